@@ -0,0 +1,53 @@
+package jaal
+
+import (
+	"context"
+	"sync"
+)
+
+// WithIsolatedBatchCaches gives every operation of a batched request its own BatchCache,
+// instead of a single cache shared by all the operations of the batch.
+func WithIsolatedBatchCaches() HandlerOption {
+	return func(h *handlerOptions) {
+		h.IsolatedBatchCaches = true
+	}
+}
+
+// BatchCache holds values, e.g. the caches of loaders, shared by the operations of a request.
+// A single request has a cache of its own, while the operations of a batched request share one
+// cache, unless WithIsolatedBatchCaches is used. The operations of a batch are executed one
+// after another, so a value loaded by an operation is reused by the following ones, but the
+// keys of several operations are never combined into a single fetch.
+type BatchCache struct {
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+func newBatchCache() *BatchCache {
+	return &BatchCache{values: make(map[interface{}]interface{})}
+}
+
+// Load returns the value stored for key, calling create to store it the first time the key is
+// loaded in the cache.
+func (c *BatchCache) Load(key interface{}, create func() interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.values[key]
+	if !ok {
+		value = create()
+		c.values[key] = value
+	}
+	return value
+}
+
+// BatchCacheFromContext returns the BatchCache of the request being executed, or nil if ctx
+// does not come from the HTTPHandler.
+func BatchCacheFromContext(ctx context.Context) *BatchCache {
+	cache, _ := ctx.Value(batchCacheKey).(*BatchCache)
+	return cache
+}
+
+func addBatchCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, batchCacheKey, newBatchCache())
+}
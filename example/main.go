@@ -2,12 +2,8 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
-	"reflect"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,26 +12,6 @@ import (
 	"go.appointy.com/jaal/schemabuilder"
 )
 
-func init() {
-	var typ = reflect.TypeOf(time.Time{})
-	_ = schemabuilder.RegisterScalar(typ, "DateTime", func(value interface{}, dest reflect.Value) error {
-		v, ok := value.(string)
-		if !ok {
-			return errors.New("invalid type expected string")
-		}
-
-		t, err := time.Parse(time.RFC3339, v)
-		if err != nil {
-			return err
-		}
-
-		dest.Set(reflect.ValueOf(t))
-
-		return nil
-	})
-
-}
-
 type Server struct {
 	Characters []*Character
 }
@@ -78,13 +54,8 @@ func RegisterPayload(schema *schemabuilder.Schema) {
 	payload.FieldFunc("dateOfBirth", func(ctx context.Context, in *Character) time.Time {
 		return in.DateOfBirth
 	})
-	payload.FieldFunc("metadata", func(ctx context.Context, in *Character) (*schemabuilder.Map, error) {
-		data, err := json.Marshal(in.Metadata)
-		if err != nil {
-			return nil, err
-		}
-
-		return &schemabuilder.Map{Value: string(data)}, nil
+	payload.FieldFunc("metadata", func(ctx context.Context, in *Character) map[string]string {
+		return in.Metadata
 	})
 }
 
@@ -99,31 +70,14 @@ func RegisterInput(schema *schemabuilder.Schema) {
 	input.FieldFunc("dateOfBirth", func(target *CreateCharacterRequest, source time.Time) {
 		target.DateOfBirth = source
 	})
-	input.FieldFunc("metadata", func(target *CreateCharacterRequest, source schemabuilder.Map) error {
-		v := source.Value
-
-		decodedValue, err := base64.StdEncoding.DecodeString(v)
-		if err != nil {
-			return err
-		}
-
-		data := make(map[string]string)
-		if err := json.Unmarshal(decodedValue, &data); err != nil {
-			return err
-		}
-
-		target.Metadata = data
-		return nil
+	// metadata is a StringMap, passed as an object such as {house: "Gryffindor"}.
+	input.FieldFunc("metadata", func(target *CreateCharacterRequest, source map[string]string) {
+		target.Metadata = source
 	})
 }
 
 func RegisterEnum(schema *schemabuilder.Schema) {
-	schema.Enum(Type(0), map[string]interface{}{
-		"WIZARD":    Type(0),
-		"MUGGLE":    Type(1),
-		"GOBLIN":    Type(2),
-		"HOUSE_ELF": Type(3),
-	})
+	schema.EnumAuto(Type(0), []string{"WIZARD", "MUGGLE", "GOBLIN", "HOUSE_ELF"})
 }
 
 func (s *Server) RegisterOperations(schema *schemabuilder.Schema) {
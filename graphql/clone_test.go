@@ -151,3 +151,26 @@ func TestClone(t *testing.T) {
 
 	assert.Equal(t, string(result1), string(result2))
 }
+
+func TestSchemaTypes(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type UserInput struct {
+		Name string
+	}
+	type Role int32
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{})
+	schema.InputObject("UserInput", UserInput{})
+	schema.Enum(Role(0), map[string]interface{}{"ADMIN": Role(0)}, schemabuilder.WithName("UserRole"))
+	schema.Query().FieldFunc("user", func() *User { return nil })
+
+	types := schema.Types()
+	assert.Equal(t, []string{"Query", "User"}, types.Objects)
+	assert.Equal(t, []string{"UserInput"}, types.InputObjects)
+	assert.Equal(t, []string{"UserRole"}, types.Enums)
+	assert.Contains(t, types.Scalars, "String")
+	assert.Contains(t, types.Scalars, "Int")
+}
@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultListFactor is the factor by which ComplexityOfSelectionSet multiplies the complexity of
+// list fields, estimating the number of elements of a list.
+const DefaultListFactor = 10
+
+// ComplexityOfSelectionSet estimates the cost of executing selectionSet against typ. Every field
+// costs 1 plus the cost of its selections, and list fields cost DefaultListFactor times as much.
+// Fields and fragments excluded by @skip or @include are not counted.
+func ComplexityOfSelectionSet(typ Type, selectionSet *SelectionSet) (int, error) {
+	return ComplexityWithListFactor(typ, selectionSet, DefaultListFactor)
+}
+
+// ComplexityWithListFactor is ComplexityOfSelectionSet with the given factor for list fields.
+func ComplexityWithListFactor(typ Type, selectionSet *SelectionSet, listFactor int) (int, error) {
+	c := &complexity{
+		listFactor: listFactor,
+		cache:      make(map[complexityKey]int),
+	}
+	return c.selectionSet(typ, selectionSet)
+}
+
+type complexityKey struct {
+	typ          Type
+	selectionSet *SelectionSet
+}
+
+type complexity struct {
+	listFactor int
+
+	// cache holds the complexity of selection sets already visited, which bounds the work
+	// done for fragments spread many times.
+	cache map[complexityKey]int
+}
+
+func (c *complexity) selectionSet(typ Type, selectionSet *SelectionSet) (int, error) {
+	if selectionSet == nil {
+		return 0, nil
+	}
+
+	key := complexityKey{typ: typ, selectionSet: selectionSet}
+	if cost, ok := c.cache[key]; ok {
+		return cost, nil
+	}
+
+	var fields map[string]*Field
+	var possibleTypes map[string]*Object
+	switch typ := typ.(type) {
+	case *Object:
+		fields = typ.Fields
+	case *Interface:
+		fields = typ.Fields
+		possibleTypes = typ.Types
+	case *Union:
+		possibleTypes = typ.Types
+	default:
+		return 0, fmt.Errorf("%s must have no selections", typ)
+	}
+
+	total := 0
+	for _, selection := range selectionSet.Selections {
+		if ok, err := shouldIncludeNode(selection.Directives); err != nil {
+			return 0, err
+		} else if !ok || selection.Name == "__typename" {
+			continue
+		}
+
+		field, ok := fields[selection.Name]
+		if !ok {
+			return 0, fmt.Errorf(`unknown field "%s"`, selection.Name)
+		}
+
+		cost, err := c.field(field, selection)
+		if err != nil {
+			return 0, err
+		}
+		total = saturatingAdd(total, cost)
+	}
+
+	for _, fragment := range selectionSet.Fragments {
+		if ok, err := shouldIncludeNode(fragment.Directives); err != nil {
+			return 0, err
+		} else if !ok {
+			continue
+		}
+
+		// Fragments on the possible types of an abstract type are counted against the type
+		// they are spread on.
+		fragmentTyp := typ
+		if object, ok := possibleTypes[fragment.Fragment.On]; ok {
+			fragmentTyp = object
+		}
+
+		cost, err := c.selectionSet(fragmentTyp, fragment.Fragment.SelectionSet)
+		if err != nil {
+			return 0, err
+		}
+		total = saturatingAdd(total, cost)
+	}
+
+	c.cache[key] = total
+	return total, nil
+}
+
+func (c *complexity) field(field *Field, selection *Selection) (int, error) {
+	typ := field.Type
+	isList := false
+	for {
+		if nonNull, ok := typ.(*NonNull); ok {
+			typ = nonNull.Type
+			continue
+		}
+		if list, ok := typ.(*List); ok {
+			typ = list.Type
+			isList = true
+			continue
+		}
+		break
+	}
+
+	cost, err := c.selectionSet(typ, selection.SelectionSet)
+	if err != nil {
+		return 0, err
+	}
+
+	cost = saturatingAdd(cost, 1)
+	if isList {
+		cost = saturatingMul(cost, c.listFactor)
+	}
+	return cost, nil
+}
+
+func saturatingAdd(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int) int {
+	if b != 0 && a > math.MaxInt32/b {
+		return math.MaxInt32
+	}
+	return a * b
+}
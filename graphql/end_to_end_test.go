@@ -2,14 +2,26 @@ package graphql_test
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kylelemons/godebug/pretty"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.appointy.com/jaal/graphql"
 	"go.appointy.com/jaal/internal"
+	"go.appointy.com/jaal/introspection"
+	"go.appointy.com/jaal/jerrors"
 	"go.appointy.com/jaal/schemabuilder"
 )
 
@@ -99,18 +111,18 @@ func TestInterface(t *testing.T) {
 		"inner": map[string]interface{}{
 			"interfaceType": []interface{}{
 				map[string]interface{}{
-					"id":      int64(1),
+					"id":      float64(1),
 					"name":    "a",
-					"uniqueA": int64(2),
+					"uniqueA": float64(2),
 				},
 				map[string]interface{}{
-					"id":      int64(2),
+					"id":      float64(2),
 					"name":    "b",
-					"uniqueB": int64(3),
+					"uniqueB": float64(3),
 				},
 			},
 		},
-	}, val)
+	}, internal.AsJSON(val))
 
 }
 
@@ -254,6 +266,127 @@ func TestEnum(t *testing.T) {
 
 }
 
+func TestEnumAuto(t *testing.T) {
+	type status int32
+
+	schema := schemabuilder.NewSchema()
+	schema.EnumAuto(status(0), []string{"ACTIVE", "SUSPENDED", "DELETED"})
+
+	query := schema.Query()
+	query.FieldFunc("next", func(args struct{ Status status }) status {
+		return args.Status + 1
+	})
+
+	builtSchema := schema.MustBuild()
+	q, err := graphql.Parse(`{ next(status: SUSPENDED) }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"next": "DELETED"}, internal.AsJSON(val))
+
+	assert.Panics(t, func() {
+		schemabuilder.NewSchema().EnumAuto(int8(0), make([]string, 129))
+	})
+	assert.Panics(t, func() {
+		schemabuilder.NewSchema().EnumAuto("", []string{"ACTIVE"})
+	})
+}
+
+func TestEnumVariables(t *testing.T) {
+	type role int32
+	type userInput struct {
+		Name string
+		Role role
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Enum(role(0), map[string]role{"MEMBER": 0, "ADMIN": 1}, schemabuilder.WithName("Role"))
+
+	query := schema.Query()
+	query.FieldFunc("updateUserRole", func(args struct{ NewRole role }) role {
+		return args.NewRole
+	})
+	query.FieldFunc("createUser", func(args struct{ User userInput }) string {
+		if args.User.Role == 1 {
+			return args.User.Name + " (admin)"
+		}
+		return args.User.Name
+	})
+	user := schema.InputObject("UserInput", userInput{})
+	user.FieldFunc("name", func(target *userInput, source string) { target.Name = source })
+	user.FieldFunc("role", func(target *userInput, source role) { target.Role = source })
+
+	builtSchema := schema.MustBuild()
+	e := graphql.Executor{}
+
+	for _, tt := range []struct {
+		name     string
+		query    string
+		vars     map[string]interface{}
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			name:     "variable",
+			query:    `query($role: Role!) { updateUserRole(newRole: $role) }`,
+			vars:     map[string]interface{}{"role": "ADMIN"},
+			expected: map[string]interface{}{"updateUserRole": "ADMIN"},
+		},
+		{
+			name:  "unknown literal",
+			query: `{ updateUserRole(newRole: INVALID) }`,
+			err:   `error parsing args for "updateUserRole": newRole: unknown value INVALID of enum Role`,
+		},
+		{
+			name:  "unknown variable",
+			query: `query($role: Role!) { updateUserRole(newRole: $role) }`,
+			vars:  map[string]interface{}{"role": "INVALID"},
+			err:   `error parsing args for "updateUserRole": newRole: unknown value INVALID of enum Role`,
+		},
+		{
+			name:  "non-string variable",
+			query: `query($role: Role!) { updateUserRole(newRole: $role) }`,
+			vars:  map[string]interface{}{"role": float64(1)},
+			err:   `error parsing args for "updateUserRole": newRole: not a string, expected a value of enum Role`,
+		},
+		{
+			name:     "input object variable",
+			query:    `query($user: UserInput!) { createUser(user: $user) }`,
+			vars:     map[string]interface{}{"user": map[string]interface{}{"name": "harry", "role": "ADMIN"}},
+			expected: map[string]interface{}{"createUser": "harry (admin)"},
+		},
+		{
+			name:  "unknown input object variable",
+			query: `query($user: UserInput!) { createUser(user: $user) }`,
+			vars:  map[string]interface{}{"user": map[string]interface{}{"name": "harry", "role": "INVALID"}},
+			err:   `error parsing args for "createUser": user: role : unknown value INVALID of enum Role`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, tt.vars)
+			require.NoError(t, err)
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, internal.AsJSON(val))
+		})
+	}
+}
+
 func TestSkipDirectives(t *testing.T) {
 	schema := schemabuilder.NewSchema()
 	query := schema.Query()
@@ -271,7 +404,8 @@ func TestSkipDirectives(t *testing.T) {
 		}
 
 		e := graphql.Executor{}
-		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+		result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(result), err
 	}
 
 	// Variable skip
@@ -417,4 +551,2868 @@ func TestSkipDirectives(t *testing.T) {
 	if d := pretty.Compare(result, internal.ParseJSON(`{"value": "s"}`)); d != "" {
 		t.Errorf("unexpected diff: %s", d)
 	}
+
+	// A field is selected when any of its occurrences, in the fragments or outside of them, is
+	// not skipped.
+	for _, tt := range []struct {
+		skip, include bool
+		expected      string
+	}{
+		{skip: true, include: false, expected: `{}`},
+		{skip: true, include: true, expected: `{"value": "s"}`},
+		{skip: false, include: false, expected: `{"value": "s"}`},
+	} {
+		vars := map[string]interface{}{"skip": tt.skip, "include": tt.include}
+
+		result, err = execute(`
+		query x {
+			...X @skip(if: $skip)
+			value @include(if: $include)
+		}
+		fragment X on Query {
+			value
+		}`, vars)
+		if err != nil {
+			t.Errorf("expected no err, received %s", err.Error())
+		}
+		if d := pretty.Compare(result, internal.ParseJSON(tt.expected)); d != "" {
+			t.Errorf("unexpected diff with %v: %s", vars, d)
+		}
+
+		result, err = execute(`
+		query x {
+			value @include(if: $include)
+			... @skip(if: $skip) {
+				value
+			}
+		}`, vars)
+		if err != nil {
+			t.Errorf("expected no err, received %s", err.Error())
+		}
+		if d := pretty.Compare(result, internal.ParseJSON(tt.expected)); d != "" {
+			t.Errorf("unexpected diff with %v: %s", vars, d)
+		}
+	}
+}
+
+func TestTypenameOverride(t *testing.T) {
+	type Document struct {
+		Version int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("documents", func() []*Document {
+		return []*Document{{Version: 1}, {Version: 2}}
+	})
+
+	obj := schema.Object("Document", Document{}, schemabuilder.WithTypename(func(source interface{}) string {
+		if source.(*Document).Version > 1 {
+			return "DocumentV2"
+		}
+		return "Document"
+	}))
+	obj.FieldFunc("version", func(in *Document) int64 {
+		return in.Version
+	})
+
+	builtSchema := schema.MustBuild()
+	q, err := graphql.Parse(`{ documents { __typename version } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"documents": []interface{}{
+			map[string]interface{}{"__typename": "Document", "version": float64(1)},
+			map[string]interface{}{"__typename": "DocumentV2", "version": float64(2)},
+		},
+	}, internal.AsJSON(val))
+}
+
+func TestTypename(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type UnionType struct {
+		schemabuilder.Union
+
+		*UnionPart1
+		*UnionPart2
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("me", func() *User {
+		return &User{Name: "a"}
+	})
+	query.FieldFunc("unions", func() []*UnionType {
+		return []*UnionType{{UnionPart1: &UnionPart1{}}, {UnionPart2: &UnionPart2{}}}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string {
+		return in.Name
+	})
+	schema.Object("UnionPart1", UnionPart1{})
+	schema.Object("UnionPart2", UnionPart2{})
+
+	builtSchema := schema.MustBuild()
+	q, err := graphql.Parse(`{ __typename me { __typename name } unions { __typename } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"__typename": "Query",
+		"me":         map[string]interface{}{"__typename": "User", "name": "a"},
+		"unions": []interface{}{
+			map[string]interface{}{"__typename": "UnionPart1"},
+			map[string]interface{}{"__typename": "UnionPart2"},
+		},
+	}, internal.AsJSON(val))
+}
+
+func TestDeprecationUsageHook(t *testing.T) {
+	type User struct {
+		FirstName string
+		LastName  string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(args struct {
+		Id   *string
+		Name *string `graphql:",deprecated=use id"`
+	}) *User {
+		return &User{FirstName: "Harry", LastName: "Potter"}
+	})
+
+	obj := schema.Object("User", User{})
+	obj.FieldFunc("firstName", func(in *User) string {
+		return in.FirstName
+	})
+	obj.FieldFunc("name", func(in *User) string {
+		return in.FirstName + " " + in.LastName
+	}, schemabuilder.Deprecated("use firstName"))
+
+	builtSchema := schema.MustBuild()
+
+	var used []string
+	e := graphql.Executor{
+		DeprecationUsageHook: func(ctx context.Context, typeName, fieldName string) {
+			used = append(used, typeName+"."+fieldName)
+		},
+	}
+
+	execute := func(queryString string) interface{} {
+		q, err := graphql.Parse(queryString, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return internal.AsJSON(val)
+	}
+
+	execute(`{ user { firstName } }`)
+	assert.Empty(t, used)
+
+	val := execute(`{ user { firstName name } }`)
+	assert.Equal(t, map[string]interface{}{
+		"user": map[string]interface{}{"firstName": "Harry", "name": "Harry Potter"},
+	}, val)
+	assert.Equal(t, []string{"User.name"}, used)
+
+	// The deprecated args are reported once given a value.
+	used = nil
+	execute(`{ user(id: "1") { firstName } }`)
+	assert.Empty(t, used)
+	execute(`{ user(name: "Harry") { firstName } }`)
+	assert.Equal(t, []string{"Query.user(name:)"}, used)
+
+	// So are the deprecated fields of input objects, wherever they are nested.
+	filter := &graphql.InputObject{
+		Name:             "Filter",
+		InputFields:      map[string]graphql.Type{"name": &graphql.Scalar{Type: "String"}, "legacy": &graphql.Scalar{Type: "String"}},
+		DeprecatedFields: map[string]string{"legacy": "use name"},
+	}
+	root := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"count": {
+			Type: &graphql.Scalar{Type: "Int"},
+			Args: map[string]graphql.Type{"filters": &graphql.List{Type: &graphql.NonNull{Type: filter}}},
+			Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				return int64(0), nil
+			},
+			ParseArguments: func(json interface{}) (interface{}, error) { return json, nil },
+		},
+	}}
+	q, err := graphql.Parse(`{ count(filters: [{name: "Harry"}, {legacy: "Potter"}]) }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), root, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	used = nil
+	if _, err := e.Execute(context.Background(), root, nil, q); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"Filter.legacy"}, used)
+}
+
+func TestRequiredInputFields(t *testing.T) {
+	type Address struct {
+		City string
+		Zip  *string
+	}
+	type Contact struct {
+		Email   string
+		Address *Address
+	}
+	type User struct {
+		Name    string
+		Contact Contact
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+
+	address := schema.InputObject("AddressInput", Address{})
+	address.FieldFunc("city", func(target *Address, source string) { target.City = source })
+	address.FieldFunc("zip", func(target *Address, source *string) { target.Zip = source })
+
+	contact := schema.InputObject("ContactInput", Contact{})
+	contact.FieldFunc("email", func(target *Contact, source string) { target.Email = source })
+	contact.FieldFunc("address", func(target *Contact, source *Address) { target.Address = source })
+
+	user := schema.InputObject("UserInput", User{})
+	user.FieldFunc("name", func(target *User, source string) { target.Name = source })
+	user.FieldFunc("contact", func(target *User, source Contact) { target.Contact = source })
+
+	schema.Mutation().FieldFunc("createUser", func(args struct{ Input *User }) bool { return true })
+	builtSchema := schema.MustBuild()
+
+	tests := []struct {
+		name      string
+		input     string
+		variables string
+		err       string
+	}{
+		{name: "optional fields", input: `{name: "Harry", contact: {email: "harry@hogwarts.edu"}}`},
+		{name: "missing field", input: `{contact: {email: "harry@hogwarts.edu"}}`, err: `field "name" of type String! is required`},
+		{name: "missing object", input: `{name: "Harry"}`, err: `field "contact" of type ContactInput! is required`},
+		{name: "missing nested field", input: `{name: "Harry", contact: {email: "harry@hogwarts.edu", address: {zip: "4"}}}`, err: `contact : address : field "city" of type String! is required`},
+		// Null values can only be given through variables.
+		{name: "null field", variables: `{"name": "Harry", "contact": {"email": null}}`, err: `contact : field "email" of type String! must not be null`},
+		{name: "null object", variables: `{"name": "Harry", "contact": null}`, err: `field "contact" of type ContactInput! must not be null`},
+		{name: "null optional field", variables: `{"name": "Harry", "contact": {"email": "harry@hogwarts.edu", "address": null}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, variables := `mutation { createUser(input: `+tt.input+`) }`, map[string]interface{}(nil)
+			if tt.variables != "" {
+				query = `mutation ($input: UserInput) { createUser(input: $input) }`
+				variables = map[string]interface{}{"input": internal.ParseJSON(tt.variables)}
+			}
+			q, err := graphql.Parse(query, variables)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Mutation, q.SelectionSet)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, received %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestInputValidators(t *testing.T) {
+	type Contact struct {
+		Email string
+	}
+	type User struct {
+		Age     int64
+		Contact *Contact
+	}
+
+	email := regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+
+	contact := schema.InputObject("ContactInput", Contact{})
+	contact.FieldFunc("email", func(target *Contact, source string) { target.Email = source },
+		schemabuilder.WithValidator(func(value interface{}) error {
+			if !email.MatchString(value.(string)) {
+				return fmt.Errorf("%q is not an email", value)
+			}
+			return nil
+		}))
+
+	user := schema.InputObject("UserInput", User{})
+	user.FieldFunc("age", func(target *User, source int64) { target.Age = source },
+		schemabuilder.WithValidator(func(value interface{}) error {
+			if value.(int64) < 0 {
+				return errors.New("must not be negative")
+			}
+			return nil
+		}))
+	user.FieldFunc("contact", func(target *User, source *Contact) { target.Contact = source })
+
+	schema.Mutation().FieldFunc("createUser", func(args struct{ Input *User }) bool { return true })
+	builtSchema := schema.MustBuild()
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "valid", input: `{age: 17, contact: {email: "harry@hogwarts.edu"}}`},
+		{name: "out of range", input: `{age: -1}`, err: `error parsing args for "createUser": input: age : must not be negative`},
+		{name: "nested format", input: `{age: 17, contact: {email: "harry"}}`, err: `error parsing args for "createUser": input: contact : email : "harry" is not an email`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(`mutation { createUser(input: `+tt.input+`) }`, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Mutation, q.SelectionSet)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNestedInterface(t *testing.T) {
+	type Dog struct {
+		Name  string
+		Barks bool
+	}
+	type Cat struct {
+		Name  string
+		Lives int64
+	}
+	type Pet struct {
+		schemabuilder.Interface
+		*Dog
+		*Cat
+	}
+	type Person struct {
+		Name string
+		Pet  Pet
+	}
+	type Shelter struct {
+		Name string
+		Pets []Pet
+	}
+	type Owner struct {
+		schemabuilder.Interface
+		*Person
+		*Shelter
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("owners", func() []Owner {
+		return []Owner{
+			{Person: &Person{Name: "Harry", Pet: Pet{Cat: &Cat{Name: "Hedwig", Lives: 9}}}},
+			{Shelter: &Shelter{Name: "Hogwarts", Pets: []Pet{{Dog: &Dog{Name: "Fang", Barks: true}}}}},
+		}
+	})
+
+	dog := schema.Object("Dog", Dog{})
+	dog.FieldFunc("name", func(in *Dog) string { return in.Name })
+	dog.FieldFunc("barks", func(in *Dog) bool { return in.Barks })
+	cat := schema.Object("Cat", Cat{})
+	cat.FieldFunc("name", func(in *Cat) string { return in.Name })
+	cat.FieldFunc("lives", func(in *Cat) int64 { return in.Lives })
+	person := schema.Object("Person", Person{})
+	person.FieldFunc("name", func(in *Person) string { return in.Name })
+	person.FieldFunc("pets", func(in *Person) []Pet { return []Pet{in.Pet} })
+	shelter := schema.Object("Shelter", Shelter{})
+	shelter.FieldFunc("name", func(in *Shelter) string { return in.Name })
+	shelter.FieldFunc("pets", func(in *Shelter) []Pet { return in.Pets })
+
+	builtSchema := schema.MustBuild()
+	q, err := graphql.Parse(`{ owners { name pets { name ... on Cat { lives } } } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"owners": []interface{}{
+			map[string]interface{}{
+				"name": "Harry",
+				"pets": []interface{}{
+					map[string]interface{}{"name": "Hedwig", "lives": float64(9)},
+				},
+			},
+			map[string]interface{}{
+				"name": "Hogwarts",
+				"pets": []interface{}{
+					map[string]interface{}{"name": "Fang"},
+				},
+			},
+		},
+	}, internal.AsJSON(val))
+}
+
+type blob struct {
+	Value []byte
+}
+
+func TestScalarSerializer(t *testing.T) {
+	err := schemabuilder.RegisterScalar(reflect.TypeOf(blob{}), "Blob", func(value interface{}, dest reflect.Value) error {
+		v, ok := value.(string)
+		if !ok {
+			return errors.New("not a string")
+		}
+
+		var decoded []byte
+		var err error
+		if strings.HasPrefix(v, "0x") {
+			decoded, err = hex.DecodeString(strings.TrimPrefix(v, "0x"))
+		} else {
+			decoded, err = base64.StdEncoding.DecodeString(v)
+		}
+		if err != nil {
+			return err
+		}
+
+		dest.Field(0).SetBytes(decoded)
+		return nil
+	}, schemabuilder.WithSerializer(func(value interface{}) (interface{}, error) {
+		return base64.StdEncoding.EncodeToString(value.(blob).Value), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("echo", func(args struct{ Value blob }) blob {
+		return args.Value
+	})
+	query.FieldFunc("echoAll", func(args struct{ Values []blob }) []*blob {
+		var out []*blob
+		for i := range args.Values {
+			out = append(out, &args.Values[i])
+		}
+		return out
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		hex: echo(value: "0x6a61616c")
+		base64: echo(value: "amFhbA==")
+		echoAll(values: ["0x6a61616c"])
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, graphql.ResponseObject{
+		{Key: "hex", Value: "amFhbA=="},
+		{Key: "base64", Value: "amFhbA=="},
+		{Key: "echoAll", Value: []interface{}{"amFhbA=="}},
+	}, val)
+}
+
+type (
+	rfc3339Time     time.Time
+	unixSecondsTime time.Time
+	unixMillisTime  time.Time
+)
+
+func TestTimeScalarFormats(t *testing.T) {
+	for typ, format := range map[reflect.Type]schemabuilder.TimeFormat{
+		reflect.TypeOf(rfc3339Time{}):     schemabuilder.RFC3339,
+		reflect.TypeOf(unixSecondsTime{}): schemabuilder.UnixSeconds,
+		reflect.TypeOf(unixMillisTime{}):  schemabuilder.UnixMillis,
+	} {
+		if err := schemabuilder.RegisterTimeScalar(typ, typ.Name(), format); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("rfc3339", func(args struct{ Value rfc3339Time }) rfc3339Time {
+		return rfc3339Time(time.Time(args.Value).Add(time.Hour))
+	})
+	query.FieldFunc("unixSeconds", func(args struct{ Value unixSecondsTime }) unixSecondsTime {
+		return unixSecondsTime(time.Time(args.Value).Add(time.Hour))
+	})
+	query.FieldFunc("unixMillis", func(args struct{ Value unixMillisTime }) unixMillisTime {
+		return unixMillisTime(time.Time(args.Value).Add(time.Hour))
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		rfc3339(value: "2020-01-02T03:04:05Z")
+		unixSeconds(value: 1577934245)
+		unixMillis(value: 1577934245123)
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, graphql.ResponseObject{
+		{Key: "rfc3339", Value: "2020-01-02T04:04:05Z"},
+		{Key: "unixSeconds", Value: int64(1577937845)},
+		{Key: "unixMillis", Value: int64(1577937845123)},
+	}, val)
+
+	assert.Error(t, schemabuilder.RegisterTimeScalar(reflect.TypeOf(blob{}), "Blob", schemabuilder.RFC3339))
+}
+
+func TestDefaultTimeScalar(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("later", func(args struct{ Value time.Time }) time.Time {
+		return args.Value.Add(time.Hour)
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ later(value: "2020-01-02T03:04:05Z") }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, graphql.ResponseObject{{Key: "later", Value: "2020-01-02T04:04:05Z"}}, val)
+}
+
+func TestIntArgRange(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("int32", func(args struct{ Value int32 }) int32 {
+		return args.Value
+	})
+	query.FieldFunc("int", func(args struct{ Value int }) int {
+		return args.Value
+	})
+	builtSchema := schema.MustBuild()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{name: "in range int32", query: `{ int32(value: 42) }`},
+		{name: "max int32", query: `{ int32(value: 2147483647) }`},
+		{name: "min int32", query: `{ int32(value: -2147483648) }`},
+		{name: "overflow int32", query: `{ int32(value: 2147483648) }`, wantErr: `error parsing args for "int32": value: 2147483648 is out of range, expected a value between -2147483648 and 2147483647`},
+		{name: "underflow int32", query: `{ int32(value: -2147483649) }`, wantErr: `error parsing args for "int32": value: -2147483649 is out of range, expected a value between -2147483648 and 2147483647`},
+		{name: "max int", query: `{ int(value: 2147483647) }`},
+		{name: "overflow int", query: `{ int(value: 3000000000) }`, wantErr: `error parsing args for "int": value: 3000000000 is out of range, expected a value between -2147483648 and 2147483647`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			converted := jerrors.ConvertError(err)
+			assert.Equal(t, tt.wantErr, converted.Message)
+			assert.Equal(t, jerrors.CodeBadUserInput, converted.Extensions.Code)
+		})
+	}
+}
+
+func TestListInputCoercion(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("ids", func(args struct{ Ids []string }) string {
+		return fmt.Sprintf("%#v", args.Ids)
+	})
+	query.FieldFunc("matrix", func(args struct{ Matrix [][]int64 }) string {
+		return fmt.Sprintf("%#v", args.Matrix)
+	})
+	builtSchema := schema.MustBuild()
+
+	tests := []struct {
+		name    string
+		query   string
+		vars    map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{name: "list", query: `{ ids(ids: ["u1", "u2"]) }`, want: `[]string{"u1", "u2"}`},
+		{name: "single value", query: `{ ids(ids: "u1") }`, want: `[]string{"u1"}`},
+		{name: "null", query: `query($ids: [String]) { ids(ids: $ids) }`, vars: map[string]interface{}{"ids": nil}, want: `[]string(nil)`},
+		{name: "omitted", query: `{ ids }`, want: `[]string(nil)`},
+		{name: "nested list", query: `{ matrix(matrix: [[1, 2], [3]]) }`, want: `[][]int64{[]int64{1, 2}, []int64{3}}`},
+		{name: "nested single value", query: `{ matrix(matrix: 1) }`, want: `[][]int64{[]int64{1}}`},
+		{
+			name:  "nested null item",
+			query: `query($matrix: [[Int]]) { matrix(matrix: $matrix) }`,
+			vars:  map[string]interface{}{"matrix": []interface{}{[]interface{}{float64(1)}, nil}},
+			want:  `[][]int64{[]int64{1}, []int64(nil)}`,
+		},
+		{name: "nested list of single values", query: `{ matrix(matrix: [1, 2]) }`, wantErr: `error parsing args for "matrix": matrix: not a list`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, tt.vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			if tt.wantErr != "" {
+				assert.Equal(t, tt.wantErr, jerrors.ConvertError(err).Message)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e := graphql.Executor{}
+			val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			field := q.SelectionSet.Selections[0].Name
+			assert.Equal(t, graphql.ResponseObject{{Key: field, Value: tt.want}}, val)
+		})
+	}
+}
+
+func TestExecutionOrder(t *testing.T) {
+	var order []string
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		name := name
+		query.FieldFunc(name, func() string {
+			order = append(order, name)
+			return name
+		})
+	}
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ c a d b x: a }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		order = nil
+		e := graphql.Executor{}
+		if _, err := e.Execute(context.Background(), builtSchema.Query, nil, q); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"c", "a", "d", "b", "a"}, order)
+	}
+}
+
+// TestMapBackedFieldOrder tests that the fields of an object resolved from a map are encoded in
+// selection order, however the maps of its fields and of its source are iterated.
+func TestMapBackedFieldOrder(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	object := &graphql.Object{Name: "Dynamic", Fields: make(map[string]*graphql.Field)}
+	for _, name := range names {
+		name := name
+		object.Fields[name] = &graphql.Field{
+			Type: &graphql.Scalar{Type: "String"},
+			Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				return source.(map[string]interface{})[name], nil
+			},
+			ParseArguments: func(json interface{}) (interface{}, error) { return nil, nil },
+		}
+	}
+	source := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		source[name] = strings.ToUpper(name)
+	}
+
+	q, err := graphql.Parse(`{ h c x: a g b f e d }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), object, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 20; i++ {
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), object, source, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, `{"h":"H","c":"C","x":"A","g":"G","b":"B","f":"F","e":"E","d":"D"}`, internal.MarshalJSON(val))
+	}
+}
+
+func TestResponseFieldOrder(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		query.FieldFunc(name, func() string { return name })
+	}
+	query.FieldFunc("user", func() *User { return &User{Name: "harry"} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.FieldFunc("email", func(in *User) string { return in.Name + "@hogwarts.edu" })
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ b a c user { email name } first: a __typename }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fields are encoded in the order they were selected, by their aliases.
+	assert.Equal(t, `{"b":"b","a":"a","c":"c","user":{"email":"harry@hogwarts.edu","name":"harry"},"first":"a","__typename":"Query"}`, internal.MarshalJSON(val))
+
+	first, ok := val.(graphql.ResponseObject).Get("first")
+	assert.True(t, ok)
+	assert.Equal(t, "a", first)
+}
+
+func TestListNullPolicy(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+
+	items := func() []*Item {
+		return []*Item{{Name: "a"}, nil, {Name: "b"}}
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("kept", items)
+	query.FieldFunc("dropped", items, schemabuilder.WithListNullPolicy(schemabuilder.DropNulls))
+	item := schema.Object("Item", Item{})
+	item.FieldFunc("name", func(in *Item) string {
+		return in.Name
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ kept { name } dropped { name } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, internal.ParseJSON(`{
+		"kept": [{"name": "a"}, null, {"name": "b"}],
+		"dropped": [{"name": "a"}, {"name": "b"}]
+	}`), internal.AsJSON(val))
+
+	schema.Query().FieldFunc("notList", func() *Item { return nil }, schemabuilder.WithListNullPolicy(schemabuilder.DropNulls))
+	_, err = schema.Build()
+	assert.Error(t, err)
+}
+
+func TestPresentResults(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("nickname", func(args struct{ Name string }) (string, bool) {
+		return args.Name, args.Name != ""
+	})
+	query.FieldFunc("age", func(args struct{ Present, Fail bool }) (int64, bool, error) {
+		if args.Fail {
+			return 0, args.Present, errors.New("age unavailable")
+		}
+		return 0, args.Present, nil
+	})
+	builtSchema := schema.MustBuild()
+
+	fields := builtSchema.Query.(*graphql.Object).Fields
+	assert.Equal(t, "String", fields["nickname"].Type.String())
+	assert.Equal(t, "Int", fields["age"].Type.String())
+
+	for _, tt := range []struct {
+		name     string
+		query    string
+		expected string
+		err      string
+	}{
+		{
+			name:     "present",
+			query:    `{ nickname(name: "harry") age(present: true, fail: false) }`,
+			expected: `{"nickname": "harry", "age": 0}`,
+		},
+		{
+			name:     "absent",
+			query:    `{ nickname(name: "") age(present: false, fail: false) }`,
+			expected: `{"nickname": null, "age": null}`,
+		},
+		{
+			name:  "present with error",
+			query: `{ age(present: true, fail: true) }`,
+			err:   "age unavailable",
+		},
+		{
+			name:  "absent with error",
+			query: `{ age(present: false, fail: true) }`,
+			err:   "age unavailable",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+				t.Fatal(err)
+			}
+
+			e := graphql.Executor{}
+			val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error %q, but received %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, internal.ParseJSON(tt.expected), internal.AsJSON(val))
+		})
+	}
+
+	schema.Query().FieldFunc("required", func() (string, bool) { return "", false }, schemabuilder.NonNull())
+	_, err := schema.Build()
+	assert.Error(t, err)
+}
+
+func TestArgsMap(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("proxy", func(args map[string]interface{}) string {
+		return fmt.Sprintf("%v:%v", args["service"], args["id"])
+	}, schemabuilder.WithArgs(struct {
+		Service string
+		Id      *int64
+	}{}))
+	query.FieldFunc("untyped", func(args map[string]interface{}) int64 {
+		return int64(len(args))
+	})
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(`{ proxy(service: "users", id: 7) untyped(a: 1, b: "2") }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, graphql.ResponseObject{{Key: "proxy", Value: "users:7"}, {Key: "untyped", Value: int64(2)}}, val)
+
+	_, err = execute(`{ proxy(service: 1) }`)
+	assert.EqualError(t, err, `error parsing args for "proxy": service: not a string`)
+
+	_, err = execute(`{ proxy(service: "users", other: 1) }`)
+	assert.EqualError(t, err, `error parsing args for "proxy": unknown arg other`)
+
+	assert.Equal(t, []string{"id", "service"}, sortedKeys(builtSchema.Query.(*graphql.Object).Fields["proxy"].Args))
+}
+
+func sortedKeys(m map[string]graphql.Type) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestDirectiveLocations(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("name", func() string { return "jaal" })
+	builtSchema := schema.MustBuild()
+
+	_, err := graphql.Parse(`query Q @skip(if: true) { name }`, nil)
+	assert.EqualError(t, err, `directive "@skip" may not be used on QUERY`)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{name: "skip on field", query: `{ name @skip(if: false) }`},
+		{name: "defer on inline fragment", query: `{ ... @defer { name } }`},
+		{name: "defer on field", query: `{ name @defer }`, wantErr: `directive "@defer" may not be used on FIELD`},
+		{name: "oneOf on fragment spread", query: `{ ...F @oneOf } fragment F on Query { name }`, wantErr: `directive "@oneOf" may not be used on FRAGMENT_SPREAD`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComplexityOfSelectionSet(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func() []*User { return nil })
+	schema.Query().FieldFunc("me", func() *User { return nil })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.FieldFunc("friends", func(in *User) []*User { return nil })
+	builtSchema := schema.MustBuild()
+
+	tests := []struct {
+		name       string
+		query      string
+		complexity int
+	}{
+		{name: "object", query: `{ me { name } }`, complexity: 2},
+		{name: "nested lists", query: `{ users { name friends { name } } }`, complexity: 220},
+		{name: "skipped field", query: `{ users { name friends @skip(if: true) { name } } }`, complexity: 20},
+		{name: "fragment", query: `{ me { ...F } } fragment F on User { name friends { name } }`, complexity: 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			complexity, err := graphql.ComplexityOfSelectionSet(builtSchema.Query, q.SelectionSet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.complexity, complexity)
+		})
+	}
+}
+
+func TestCheckDepth(t *testing.T) {
+	type User struct{}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("me", func() *User { return &User{} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return "" })
+	user.FieldFunc("friend", func(in *User) *User { return in })
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ me { friend { ...F } } } fragment F on User { best: friend { name } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, graphql.CheckDepth(q.SelectionSet, 4))
+	assert.EqualError(t, graphql.CheckDepth(q.SelectionSet, 3), `field "me.friend.best.name" exceeds the maximum query depth of 3`)
+
+	// Fragment cycles must not prevent the check from terminating.
+	fragment := &graphql.FragmentDefinition{Name: "F", On: "User", SelectionSet: &graphql.SelectionSet{}}
+	fragment.SelectionSet.Fragments = []*graphql.FragmentSpread{{Fragment: fragment}}
+	assert.NoError(t, graphql.CheckDepth(&graphql.SelectionSet{Fragments: []*graphql.FragmentSpread{{Fragment: fragment}}}, 1))
+}
+
+func TestPanicRecovery(t *testing.T) {
+	type Item struct {
+		Value int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("ok", func() string { return "ok" })
+	query.FieldFunc("panic", func() string {
+		var m map[string]string
+		m["nil"] = "map"
+		return ""
+	})
+	query.FieldFunc("items", func() []*Item { return []*Item{{Value: 0}, {Value: 1}} })
+	item := schema.Object("Item", Item{})
+	item.FieldFunc("value", func(in *Item) int64 {
+		return []int64{1}[in.Value]
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ ok panic items { value } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	var recovered []interface{}
+	e := graphql.Executor{
+		PanicHandler: func(ctx context.Context, value interface{}, stack []byte) {
+			assert.NotEmpty(t, stack)
+			recovered = append(recovered, graphql.FieldPathFromContext(ctx))
+		},
+	}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+
+	assert.Equal(t, map[string]interface{}{
+		"ok":    "ok",
+		"panic": nil,
+		"items": []interface{}{
+			map[string]interface{}{"value": float64(1)},
+			map[string]interface{}{"value": nil},
+		},
+	}, internal.AsJSON(val))
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "internal server error", Extensions: &jerrors.Extension{Code: jerrors.CodeInternal}, Paths: []string{"panic"}},
+		{Message: "internal server error", Extensions: &jerrors.Extension{Code: jerrors.CodeInternal}, Paths: []string{"items", "1", "value"}},
+	}}, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{"panic"},
+		[]interface{}{"items", 1, "value"},
+	}, recovered)
+}
+
+type role int32
+
+func TestCustomDirectives(t *testing.T) {
+	type roleKey struct{}
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Enum(role(0), map[string]interface{}{
+		"USER":  role(0),
+		"ADMIN": role(1),
+	})
+	query := schema.Query()
+	query.FieldFunc("name", func() string { return "gopher" })
+	query.FieldFunc("secret", func() string { return "hunter2" })
+	query.FieldFunc("me", func() *User { return &User{Name: "gopher"} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	schema.Directive("upper", []graphql.DirectiveLocation{"FIELD"},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			value, err := next(ctx)
+			if s, ok := value.(string); ok {
+				return strings.ToUpper(s), err
+			}
+			return value, err
+		})
+	schema.Directive("auth", []graphql.DirectiveLocation{"FIELD"},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			if ctx.Value(roleKey{}) != args.(struct{ Role role }).Role {
+				return nil, errors.New("forbidden")
+			}
+			return next(ctx)
+		},
+		schemabuilder.WithDirectiveArgs(struct{ Role role }{}))
+	builtSchema := schema.MustBuild()
+
+	execute := func(ctx context.Context, query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateDirectives(builtSchema.Directives, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{Directives: builtSchema.Directives}
+		return e.Execute(ctx, builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(context.WithValue(context.Background(), roleKey{}, role(1)), `{ name @upper secret @auth(role: ADMIN) @upper }`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "GOPHER", "secret": "HUNTER2"}, internal.AsJSON(val))
+
+	_, err = execute(context.WithValue(context.Background(), roleKey{}, role(0)), `{ secret @auth(role: ADMIN) }`)
+	assert.Equal(t, &jerrors.Error{Message: "forbidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"secret"}}, err)
+
+	_, err = execute(context.Background(), `{ secret @auth(role: ROOT) }`)
+	assert.Error(t, err)
+
+	// The directives of every occurrence of a repeated field apply to it.
+	_, err = execute(context.WithValue(context.Background(), roleKey{}, role(0)), `{ secret secret @auth(role: ADMIN) }`)
+	assert.Equal(t, &jerrors.Error{Message: "forbidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"secret"}}, err)
+
+	val, err = execute(context.WithValue(context.Background(), roleKey{}, role(0)), `{ me @auth(role: ADMIN) { name } me { name } }`)
+	assert.Equal(t, map[string]interface{}{"me": nil}, internal.AsJSON(val))
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "forbidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"me"}},
+	}}, err)
+
+	val, err = execute(context.WithValue(context.Background(), roleKey{}, role(1)), `{ me { name @upper } me @auth(role: ADMIN) { name } }`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"me": map[string]interface{}{"name": "GOPHER"}}, internal.AsJSON(val))
+
+	_, err = execute(context.Background(), `{ ... @upper { name } }`)
+	assert.EqualError(t, err, `directive "@upper" may not be used on INLINE_FRAGMENT`)
+}
+
+func TestConnection(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	users := []*User{{Name: "Harry"}, {Name: "Ron"}, {Name: "Hermione"}}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{}).FieldFunc("name", func(in *User) string { return in.Name })
+	usersConnection := schema.Connection("User", &User{})
+	schema.Query().FieldFunc("users", func(args struct {
+		First int64
+		After *string
+	}) (*schemabuilder.Page, error) {
+		var offset int64
+		if args.After != nil {
+			index, err := schemabuilder.DecodeCursor(*args.After)
+			if err != nil {
+				return nil, err
+			}
+			offset = index + 1
+		}
+		end := offset + args.First
+		if end > int64(len(users)) {
+			end = int64(len(users))
+		}
+		return &schemabuilder.Page{
+			Items:           users[offset:end],
+			Offset:          offset,
+			HasNextPage:     end < int64(len(users)),
+			HasPreviousPage: offset > 0,
+			TotalCount:      int64(len(users)),
+		}, nil
+	}, usersConnection)
+	schema.Query().FieldFunc("friends", func() schemabuilder.Page {
+		return schemabuilder.Page{
+			Items:  users[:1],
+			Cursor: func(item interface{}) string { return item.(*User).Name },
+		}
+	}, usersConnection)
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		users(first: 2, after: "MA==") {
+			totalCount
+			edges { cursor node { name } }
+			pageInfo { hasNextPage hasPreviousPage startCursor endCursor }
+		}
+		friends { edges { cursor } pageInfo { hasNextPage } }
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, internal.ParseJSON(`{
+		"users": {
+			"totalCount": 3,
+			"edges": [
+				{"cursor": "MQ==", "node": {"name": "Ron"}},
+				{"cursor": "Mg==", "node": {"name": "Hermione"}}
+			],
+			"pageInfo": {"hasNextPage": false, "hasPreviousPage": true, "startCursor": "MQ==", "endCursor": "Mg=="}
+		},
+		"friends": {
+			"edges": [{"cursor": "Harry"}],
+			"pageInfo": {"hasNextPage": false}
+		}
+	}`), internal.AsJSON(val))
+}
+
+func TestPaginate(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	users := []*User{{Name: "Harry"}, {Name: "Ron"}, {Name: "Hermione"}, {Name: "Ginny"}, {Name: "Neville"}}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("User", User{}).FieldFunc("name", func(in *User) string { return in.Name })
+	usersConnection := schema.Connection("User", &User{})
+	schema.Query().FieldFunc("users", func(args struct{ schemabuilder.Pagination }) (*schemabuilder.Page, error) {
+		return schemabuilder.Paginate(users, args.Pagination)
+	}, usersConnection)
+	builtSchema := schema.MustBuild()
+
+	for _, tc := range []struct {
+		name     string
+		args     string
+		expected string
+		err      string
+	}{
+		{
+			name:     "all",
+			args:     `{}`,
+			expected: `{"names": ["Harry", "Ron", "Hermione", "Ginny", "Neville"], "hasNextPage": false, "hasPreviousPage": false}`,
+		},
+		{
+			name:     "first",
+			args:     `{"first": 2}`,
+			expected: `{"names": ["Harry", "Ron"], "hasNextPage": true, "hasPreviousPage": false}`,
+		},
+		{
+			name:     "after",
+			args:     `{"first": 2, "after": "MQ=="}`,
+			expected: `{"names": ["Hermione", "Ginny"], "hasNextPage": true, "hasPreviousPage": true}`,
+		},
+		{
+			name:     "last page",
+			args:     `{"first": 2, "after": "Mw=="}`,
+			expected: `{"names": ["Neville"], "hasNextPage": false, "hasPreviousPage": true}`,
+		},
+		{
+			name:     "after the last item",
+			args:     `{"first": 2, "after": "NA=="}`,
+			expected: `{"names": [], "hasNextPage": false, "hasPreviousPage": true}`,
+		},
+		{
+			name:     "first zero",
+			args:     `{"first": 0}`,
+			expected: `{"names": [], "hasNextPage": true, "hasPreviousPage": false}`,
+		},
+		{
+			name:     "offset and limit",
+			args:     `{"offset": 3, "limit": 5}`,
+			expected: `{"names": ["Ginny", "Neville"], "hasNextPage": false, "hasPreviousPage": true}`,
+		},
+		{
+			name:     "offset past the end",
+			args:     `{"offset": 10}`,
+			expected: `{"names": [], "hasNextPage": false, "hasPreviousPage": true}`,
+		},
+		{
+			name: "negative first",
+			args: `{"first": -1}`,
+			err:  "first must be non-negative, received -1",
+		},
+		{
+			name: "cursor out of range",
+			args: `{"after": "NQ=="}`,
+			err:  `invalid cursor "NQ=="`,
+		},
+		{
+			name: "invalid cursor",
+			args: `{"after": "user"}`,
+			err:  `invalid cursor "user"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := graphql.Parse(`query($first: Int, $after: String, $offset: Int, $limit: Int) {
+				users(first: $first, after: $after, offset: $offset, limit: $limit) {
+					edges { node { name } }
+					pageInfo { hasNextPage hasPreviousPage }
+				}
+			}`, internal.ParseJSON(tc.args).(map[string]interface{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+				t.Fatal(err)
+			}
+
+			e := graphql.Executor{}
+			val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("expected error %q, but received %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := internal.AsJSON(val).(map[string]interface{})["users"].(map[string]interface{})
+			names := []interface{}{}
+			for _, edge := range result["edges"].([]interface{}) {
+				names = append(names, edge.(map[string]interface{})["node"].(map[string]interface{})["name"])
+			}
+			pageInfo := result["pageInfo"].(map[string]interface{})
+			assert.Equal(t, internal.ParseJSON(tc.expected), map[string]interface{}{
+				"names":           names,
+				"hasNextPage":     pageInfo["hasNextPage"],
+				"hasPreviousPage": pageInfo["hasPreviousPage"],
+			})
+		})
+	}
+}
+
+func TestBatchFieldFunc(t *testing.T) {
+	type User struct {
+		ID int64
+	}
+	type Post struct {
+		Title string
+	}
+
+	var calls [][]int64
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("allUsers", func() []*User {
+		return []*User{{ID: 1}, nil, {ID: 2}, {ID: 3}}
+	})
+	schema.Query().FieldFunc("me", func() *User { return &User{ID: 4} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("id", func(in *User) int64 { return in.ID })
+	user.BatchFieldFunc("posts", func(ctx context.Context, users []*User, args struct{ First int64 }) ([][]*Post, error) {
+		var ids []int64
+		posts := make([][]*Post, 0, len(users))
+		for _, u := range users {
+			ids = append(ids, u.ID)
+
+			var userPosts []*Post
+			for i := int64(0); i < args.First; i++ {
+				userPosts = append(userPosts, &Post{Title: fmt.Sprintf("post %d of user %d", i, u.ID)})
+			}
+			posts = append(posts, userPosts)
+		}
+		calls = append(calls, ids)
+		return posts, nil
+	})
+	schema.Object("Post", Post{}).FieldFunc("title", func(in *Post) string { return in.Title })
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ allUsers { id posts(first: 1) { title } } me { posts(first: 2) { title } } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A single call resolves the posts of every user in the list.
+	assert.Equal(t, [][]int64{{1, 2, 3}, {4}}, calls)
+	assert.Equal(t, internal.ParseJSON(`{
+		"allUsers": [
+			{"id": 1, "posts": [{"title": "post 0 of user 1"}]},
+			null,
+			{"id": 2, "posts": [{"title": "post 0 of user 2"}]},
+			{"id": 3, "posts": [{"title": "post 0 of user 3"}]}
+		],
+		"me": {"posts": [{"title": "post 0 of user 4"}, {"title": "post 1 of user 4"}]}
+	}`), internal.AsJSON(val))
+}
+
+func TestFieldMiddleware(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func(args struct{ Name string }) *User { return &User{Name: args.Name} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.FieldFunc("secret", func(in *User) string { return "hunter2" })
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{ user(name: "Harry") { alias: name secret } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	var log []string
+	e := graphql.Executor{
+		FieldMiddleware: []graphql.FieldMiddleware{
+			func(ctx context.Context, info graphql.FieldInfo, next func() (interface{}, error)) (interface{}, error) {
+				log = append(log, fmt.Sprintf("outer %s.%s as %s %v", info.ParentType, info.FieldName, info.Alias, info.Args))
+				value, err := next()
+				log = append(log, "outer done")
+				return value, err
+			},
+			func(ctx context.Context, info graphql.FieldInfo, next func() (interface{}, error)) (interface{}, error) {
+				log = append(log, "inner "+info.FieldName)
+				if info.FieldName == "secret" {
+					return nil, errors.New("access denied")
+				}
+				return next()
+			},
+		},
+	}
+
+	// The error of the non-null secret propagates to the nullable user.
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	assert.Equal(t, map[string]interface{}{"user": nil}, internal.AsJSON(val))
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "access denied", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"user", "secret"}},
+	}}, err)
+	assert.Equal(t, []string{
+		"outer Query.user as user {Harry}",
+		"inner user",
+		"outer done",
+		"outer User.name as alias <nil>",
+		"inner name",
+		"outer done",
+		"outer User.secret as secret <nil>",
+		"inner secret",
+		"outer done",
+	}, log)
+}
+
+func TestJSONScalar(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("echo", func(args struct{ Metadata schemabuilder.JSON }) schemabuilder.JSON {
+		return args.Metadata
+	})
+	query.FieldFunc("optional", func(args struct{ Metadata *schemabuilder.JSON }) *schemabuilder.JSON {
+		return args.Metadata
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`query($metadata: JSON!) {
+		literal: echo(metadata: {a: 1, b: [2, 3], c: {d: "e", f: false}})
+		variable: echo(metadata: $metadata)
+		scalar: echo(metadata: "text")
+		optional
+	}`, map[string]interface{}{
+		"metadata": map[string]interface{}{"tags": []interface{}{"x", map[string]interface{}{"y": true}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, internal.ParseJSON(`{
+		"literal": {"a": 1, "b": [2, 3], "c": {"d": "e", "f": false}},
+		"variable": {"tags": ["x", {"y": true}]},
+		"scalar": "text",
+		"optional": null
+	}`), internal.AsJSON(val))
+}
+
+func TestInterfaceFieldInheritance(t *testing.T) {
+	type Droid struct {
+		Id              int64
+		Name            string
+		PrimaryFunction string
+	}
+
+	type Human struct {
+		Id   int64
+		Name string
+	}
+
+	type Character struct {
+		schemabuilder.Interface
+		*Droid
+		*Human
+	}
+
+	newSchema := func() *schemabuilder.Schema {
+		schema := schemabuilder.NewSchema()
+		schema.Query().FieldFunc("characters", func() []*Character {
+			return []*Character{
+				{Droid: &Droid{Id: 1, Name: "R2-D2", PrimaryFunction: "astromech"}},
+				{Human: &Human{Id: 2, Name: "Luke"}},
+			}
+		})
+
+		character := schema.Object("Character", Character{})
+		character.FieldFunc("id", func(c *Character) int64 {
+			if c.Droid != nil {
+				return c.Droid.Id
+			}
+			return c.Human.Id
+		})
+		character.FieldFunc("name", func(c *Character) string {
+			if c.Droid != nil {
+				return c.Droid.Name
+			}
+			return c.Human.Name
+		})
+		return schema
+	}
+
+	schema := newSchema()
+	droid := schema.Object("Droid", Droid{})
+	droid.Implements("Character")
+	droid.FieldFunc("name", func(d *Droid) string {
+		return "Droid " + d.Name
+	})
+	droid.FieldFunc("primaryFunction", func(d *Droid) string {
+		return d.PrimaryFunction
+	})
+	schema.Object("Human", Human{}).Implements("Character")
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		characters {
+			id
+			name
+			... on Droid { primaryFunction }
+		}
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"characters": []interface{}{
+			map[string]interface{}{"id": float64(1), "name": "Droid R2-D2", "primaryFunction": "astromech"},
+			map[string]interface{}{"id": float64(2), "name": "Luke"},
+		},
+	}, internal.AsJSON(val))
+
+	t.Run("missing field", func(t *testing.T) {
+		schema := newSchema()
+		schema.Object("Droid", Droid{}).Implements("Character")
+		schema.Object("Human", Human{}).FieldFunc("id", func(h *Human) int64 {
+			return h.Id
+		})
+
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad type Human: missing field name of interface Character")
+	})
+
+	t.Run("mismatched field", func(t *testing.T) {
+		schema := newSchema()
+		schema.Object("Droid", Droid{}).Implements("Character")
+		human := schema.Object("Human", Human{})
+		human.Implements("Character")
+		human.FieldFunc("id", func(h *Human) string {
+			return fmt.Sprint(h.Id)
+		})
+
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad type Human: field id is of type String!, but of type Int! on interface Character")
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		type Robot struct{}
+
+		schema := newSchema()
+		schema.Object("Droid", Droid{}).Implements("Character")
+		schema.Object("Human", Human{}).Implements("Character")
+		schema.Query().FieldFunc("robot", func() *Robot { return &Robot{} })
+		schema.Object("Robot", Robot{}).Implements("Character")
+
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad method robot on type schemabuilder.query: bad type graphql_test.Robot: implements Character but is not one of its member types")
+	})
+}
+
+// characterValue is implemented by the members of the Character interface, so that resolvers can
+// return them without wrapping them in a Character.
+type characterValue interface{ isCharacter() }
+
+func (*Droid) isCharacter() {}
+func (*Human) isCharacter() {}
+
+func TestInterfaceValues(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hero", func() characterValue {
+		return &Human{Id: "1000", Name: "Luke"}
+	})
+	schema.Object("Character", Character{})
+
+	human := schema.Object("Human", Human{})
+	human.FieldFunc("name", func(h *Human) string {
+		return h.Name
+	})
+	human.FieldFunc("friends", func(h *Human) []characterValue {
+		return []characterValue{
+			&Human{Id: "1002", Name: "Han"},
+			&Droid{Id: "2001", Name: "R2-D2", PrimaryFunction: "astromech"},
+		}
+	})
+
+	droid := schema.Object("Droid", Droid{})
+	droid.FieldFunc("name", func(d *Droid) string {
+		return d.Name
+	})
+	droid.FieldFunc("primaryFunction", func(d *Droid) string {
+		return d.PrimaryFunction
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		hero {
+			__typename
+			name
+			... on Human {
+				friends {
+					__typename
+					name
+					... on Droid { primaryFunction }
+				}
+			}
+		}
+	}`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"hero": map[string]interface{}{
+			"__typename": "Human",
+			"name":       "Luke",
+			"friends": []interface{}{
+				map[string]interface{}{"__typename": "Human", "name": "Han"},
+				map[string]interface{}{"__typename": "Droid", "name": "R2-D2", "primaryFunction": "astromech"},
+			},
+		},
+	}, internal.AsJSON(val))
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	// Every slow field waits for the others to start, which requires resolving them concurrently.
+	const slowFields = 3
+	started := make(chan struct{}, slowFields)
+	waitForSiblings := func(ctx context.Context) error {
+		started <- struct{}{}
+		for len(started) < slowFields {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return nil
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("name", func() string { return "query" })
+	query.FieldFunc("user", func(ctx context.Context, args struct{ Name string }) (*User, error) {
+		return &User{Name: args.Name}, waitForSiblings(ctx)
+	})
+	query.FieldFunc("fail", func(ctx context.Context) (string, error) {
+		if err := waitForSiblings(ctx); err != nil {
+			return "", err
+		}
+		return "", errors.New("failed")
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+
+	// The mutation fields record the order they run in, and how many of them run at once.
+	var mu sync.Mutex
+	var order []string
+	var running, maxRunning int
+	schema.Mutation().FieldFunc("append", func(ctx context.Context, args struct {
+		Value string
+		Delay int64
+	}) string {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Duration(args.Delay) * time.Millisecond)
+
+		mu.Lock()
+		running--
+		order = append(order, args.Value)
+		mu.Unlock()
+		return args.Value
+	})
+	builtSchema := schema.MustBuild()
+
+	execute := func(t *testing.T, query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := builtSchema.Query
+		if q.Kind == "mutation" {
+			root = builtSchema.Mutation
+		}
+		if err := graphql.ValidateQuery(context.Background(), root, q.SelectionSet); err != nil {
+			t.Fatal(err)
+		}
+
+		for len(started) > 0 {
+			<-started
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		e := graphql.Executor{MaxConcurrency: slowFields}
+		return e.Execute(ctx, root, nil, q)
+	}
+
+	t.Run("values", func(t *testing.T) {
+		val, err := execute(t, `{ name a: user(name: "a") { name } b: user(name: "b") { name } c: user(name: "c") { name } }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The fields resolved concurrently keep the order of the selections.
+		assert.Equal(t, graphql.ResponseObject{
+			{Key: "name", Value: "query"},
+			{Key: "a", Value: graphql.ResponseObject{{Key: "name", Value: "a"}}},
+			{Key: "b", Value: graphql.ResponseObject{{Key: "name", Value: "b"}}},
+			{Key: "c", Value: graphql.ResponseObject{{Key: "name", Value: "c"}}},
+		}, val)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := execute(t, `{ a: user(name: "a") { name } fail b: user(name: "b") { name } }`)
+		assert.Equal(t, &jerrors.Error{Message: "failed", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"fail"}}, err)
+	})
+
+	t.Run("mutation", func(t *testing.T) {
+		// The root fields of a mutation run serially, in order, even when they may run concurrently.
+		_, err := execute(t, `mutation { a: append(value: "a", delay: 20) b: append(value: "b", delay: 0) c: append(value: "c", delay: 10) }`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"a", "b", "c"}, order)
+		assert.Equal(t, 1, maxRunning)
+	})
+}
+
+func TestVariableUsage(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func(args struct {
+		Id   int64
+		Name *string
+		Tags []string
+	}) string {
+		return ""
+	})
+	builtSchema := schema.MustBuild()
+
+	for _, tt := range []struct {
+		name   string
+		query  string
+		err    string
+		column int
+	}{
+		{name: "matching", query: `query($id: Int!, $name: String, $tags: [String]) { user(id: $id, name: $name, tags: $tags) }`},
+		{name: "non-null variable", query: `query($id: Int!, $tags: [String!]!) { user(id: $id, tags: $tags) }`},
+		{
+			name:   "mismatched scalar",
+			query:  `query($id: String) { user(id: $id) }`,
+			err:    `variable "$id" of type "String" cannot be used for argument "id" of type "Int!" of field "user"`,
+			column: 22,
+		},
+		{
+			name:   "list into scalar",
+			query:  `query($name: [String]) { user(id: 1, name: $name) }`,
+			err:    `variable "$name" of type "[String]" cannot be used for argument "name" of type "String" of field "user"`,
+			column: 26,
+		},
+		{name: "nullable variable with default", query: `query($id: Int = 1) { user(id: $id) }`},
+		{
+			name:   "nullable variable into non-null arg",
+			query:  `query($id: Int) { user(id: $id) }`,
+			err:    `variable "$id" of type "Int" cannot be used for argument "id" of type "Int!" of field "user"`,
+			column: 19,
+		},
+		{
+			name:   "missing non-null arg",
+			query:  `{ user }`,
+			err:    `argument "id" of type "Int!" of field "user" is required`,
+			column: 3,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, map[string]interface{}{"id": float64(1), "tags": []interface{}{"admin"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, &jerrors.Error{
+				Message:    tt.err,
+				Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput},
+				Paths:      []string{},
+				Locations:  []jerrors.Location{{Line: 1, Column: tt.column}},
+			}, jerrors.ConvertError(err))
+		})
+	}
+}
+
+func TestRequiredArgs(t *testing.T) {
+	var calls []string
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func(args struct {
+		Id       schemabuilder.ID
+		Name     *string
+		Nickname string `graphql:",optional"`
+	}) string {
+		calls = append(calls, args.Id.Value)
+		return args.Id.Value
+	})
+	builtSchema := schema.MustBuild()
+
+	args := builtSchema.Query.(*graphql.Object).Fields["user"].Args
+	assert.Equal(t, "ID!", args["id"].String())
+	assert.Equal(t, "String", args["name"].String())
+	assert.Equal(t, "String", args["nickname"].String())
+
+	execute := func(query string, vars map[string]interface{}) (interface{}, error) {
+		q, err := graphql.Parse(query, vars)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	_, err := execute(`{ user(name: "Harry") }`, nil)
+	assert.Equal(t, &jerrors.Error{
+		Message:    `argument "id" of type "ID!" of field "user" is required`,
+		Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput},
+		Paths:      []string{},
+		Locations:  []jerrors.Location{{Line: 1, Column: 3}},
+	}, jerrors.ConvertError(err))
+	assert.Empty(t, calls)
+
+	val, err := execute(`query($id: ID!) { user(id: $id) }`, map[string]interface{}{"id": "u1"})
+	require.NoError(t, err)
+	assert.Equal(t, graphql.ResponseObject{{Key: "user", Value: "u1"}}, val)
+
+	val, err = execute(`{ user(id: "u2") }`, nil)
+	require.NoError(t, err)
+	assert.Equal(t, graphql.ResponseObject{{Key: "user", Value: "u2"}}, val)
+	assert.Equal(t, []string{"u1", "u2"}, calls)
+}
+
+func TestAliases(t *testing.T) {
+	type User struct {
+		Id   string
+		Name string
+	}
+
+	users := map[string]*User{"u1": {Id: "u1", Name: "Harry"}, "u2": {Id: "u2", Name: "Ron"}}
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func(args struct{ Id string }) *User {
+		return users[args.Id]
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("id", func(in *User) string { return in.Id })
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(val), err
+	}
+
+	// Every aliased selection is resolved with its own args, under its alias.
+	val, err := execute(`{ a: user(id: "u1") { name } b: user(id: "u2") { name } user(id: "u1") { id } }`)
+	require.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{
+		"a": {"name": "Harry"},
+		"b": {"name": "Ron"},
+		"user": {"id": "u1"}
+	}`), val)
+
+	// The selections with the same alias and args are merged.
+	val, err = execute(`{ a: user(id: "u1") { name } a: user(id: "u1") { id } }`)
+	require.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"a": {"name": "Harry", "id": "u1"}}`), val)
+
+	_, err = execute(`{ a: user(id: "u1") { name } a: user(id: "u2") { name } }`)
+	assert.EqualError(t, err, `fields "a" conflict because they have different args`)
+
+	_, err = execute(`{ user(id: "u1") { n: name } user(id: "u1") { n: id } }`)
+	assert.EqualError(t, err, `fields "n" conflict because "name" and "id" are different fields`)
+}
+
+func TestFieldsCanMerge(t *testing.T) {
+	type User struct {
+		Id   string
+		Name string
+	}
+	type Droid struct {
+		Name  string
+		Model string
+	}
+	type Result struct {
+		schemabuilder.Union
+
+		*User
+		*Droid
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(args struct{ Id string }) *User {
+		return &User{Id: args.Id, Name: "user " + args.Id}
+	})
+	query.FieldFunc("search", func() []*Result {
+		return []*Result{{User: &User{Id: "1", Name: "Luke"}}, {Droid: &Droid{Name: "R2-D2", Model: "astromech"}}}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("id", func(in *User) string { return in.Id })
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	droid := schema.Object("Droid", Droid{})
+	droid.FieldFunc("name", func(in *Droid) string { return in.Name })
+	droid.FieldFunc("model", func(in *Droid) string { return in.Model })
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		require.NoError(t, err)
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(val), err
+	}
+
+	_, err := execute(`{ user(id: "1") { name } user(id: "2") { name } }`)
+	assert.Equal(t, &jerrors.Error{
+		Message:    `fields "user" conflict because they have different args`,
+		Extensions: &jerrors.Extension{Code: "Unknown"},
+		Paths:      []string{},
+		Locations:  []jerrors.Location{{Line: 1, Column: 3}, {Line: 1, Column: 26}},
+	}, jerrors.ConvertError(err))
+
+	// The selections with identical args are merged.
+	val, err := execute(`{ user(id: "1") { name } user(id: "1") { id } }`)
+	require.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"user": {"name": "user 1", "id": "1"}}`), val)
+
+	// Only one of the selections on different object types applies.
+	val, err = execute(`{ search { ... on User { label: name } ... on Droid { label: model } } }`)
+	require.NoError(t, err)
+	assert.Equal(t, internal.ParseJSON(`{"search": [{"label": "Luke"}, {"label": "astromech"}]}`), val)
+
+	_, err = execute(`{ search { label: __typename ... on User { label: name } } }`)
+	assert.EqualError(t, err, `fields "label" conflict because "__typename" and "name" are different fields`)
+}
+
+func TestMergeSchemas(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type Invoice struct {
+		Amount int64
+	}
+
+	// The schemas share the resolvers of User, which is declared once so that inlining
+	// registerUser does not make copies of them.
+	userName := func(in *User) string { return in.Name }
+	registerUser := func(schema *schemabuilder.Schema) {
+		user := schema.Object("User", User{})
+		user.FieldFunc("name", userName)
+	}
+
+	users := schemabuilder.NewSchema()
+	registerUser(users)
+	users.Query().FieldFunc("me", func() *User { return &User{Name: "Harry"} })
+
+	billing := schemabuilder.NewSchema()
+	registerUser(billing)
+	invoice := billing.Object("Invoice", Invoice{})
+	invoice.FieldFunc("amount", func(in *Invoice) int64 { return in.Amount })
+	invoice.FieldFunc("customer", func(in *Invoice) *User { return &User{Name: "Ron"} })
+	billing.Query().FieldFunc("invoice", func() *Invoice { return &Invoice{Amount: 42} })
+	billing.Mutation().FieldFunc("pay", func() bool { return true })
+
+	merged, err := schemabuilder.Merge(users, billing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtSchema := merged.MustBuild()
+
+	q, err := graphql.Parse(`{ me { name } invoice { amount customer { name } } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"me": {"name": "Harry"},
+		"invoice": {"amount": 42, "customer": {"name": "Ron"}}
+	}`), internal.AsJSON(val))
+	assert.Contains(t, builtSchema.Mutation.(*graphql.Object).Fields, "pay")
+
+	t.Run("root field collision", func(t *testing.T) {
+		catalog := schemabuilder.NewSchema()
+		catalog.Query().FieldFunc("me", func() *User { return nil })
+		registerUser(catalog)
+
+		_, err := schemabuilder.Merge(users, billing, catalog)
+		assert.EqualError(t, err, "field Query.me of schema 2 is already registered by schema 0")
+	})
+
+	t.Run("conflicting object types", func(t *testing.T) {
+		catalog := schemabuilder.NewSchema()
+		catalog.Object("User", Invoice{})
+
+		_, err := schemabuilder.Merge(users, catalog)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "object User of schema 1 has type")
+	})
+
+	t.Run("conflicting shared fields", func(t *testing.T) {
+		catalog := schemabuilder.NewSchema()
+		catalog.Object("User", User{}).FieldFunc("name", func(in *User) string { return "" })
+
+		_, err := schemabuilder.Merge(users, catalog)
+		assert.EqualError(t, err, "field User.name of schema 1 is already registered by schema 0")
+	})
+}
+
+func TestSchemaStats(t *testing.T) {
+	type User struct {
+		Id    int64
+		Name  string
+		Email string
+	}
+
+	schema := schemabuilder.NewSchema()
+	user := schema.Object("User", User{})
+	user.FieldFunc("id", func(in *User) int64 { return in.Id })
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.FieldFunc("email", func(in *User) string { return in.Email })
+	schema.Query().FieldFunc("me", func() *User { return nil })
+	schema.Query().FieldFunc("user", func(args struct{ Id int64 }) *User { return nil })
+	schema.Mutation().FieldFunc("createUser", func(args struct{ Name string }) *User { return nil })
+	builtSchema := schema.MustBuild()
+
+	assert.Equal(t, []string{"Int", "Mutation", "Query", "String", "Subscription", "User"}, builtSchema.TypeNames())
+	assert.Equal(t, 6, builtSchema.FieldCount())
+
+	typ, ok := builtSchema.Type("User")
+	require.True(t, ok)
+	assert.Equal(t, builtSchema.Query.(*graphql.Object).Fields["me"].Type, typ)
+	_, ok = builtSchema.Type("Post")
+	assert.False(t, ok)
+
+	// The introspection types are reachable from the introspection fields of the query.
+	introspection.AddIntrospectionToSchema(builtSchema)
+	assert.Contains(t, builtSchema.TypeNames(), "__Schema")
+	_, ok = builtSchema.Type("__Type")
+	assert.True(t, ok)
+}
+
+func TestFederation(t *testing.T) {
+	type User struct {
+		Id   string
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	user := schema.Object("User", User{})
+	user.FieldFunc("id", func(in *User) string { return in.Id })
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.Key("id")
+	schema.Query().FieldFunc("me", func() *User { return &User{Id: "1", Name: "Harry"} })
+	schema.RegisterReferenceResolver("User", func(ctx context.Context, key map[string]interface{}) (interface{}, error) {
+		if key["id"] == "1" {
+			return User{Id: "1", Name: "Harry"}, nil
+		}
+		return (*User)(nil), nil
+	})
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string, vars map[string]interface{}) (interface{}, error) {
+		q, err := graphql.Parse(query, vars)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(`{ _service { sdl } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]interface{}{"_service": map[string]interface{}{"sdl": `type Query {
+  me: User
+}
+
+type User @key(fields: "id") {
+  id: String!
+  name: String!
+}
+`}}, internal.AsJSON(val))
+
+	query := `query($representations: [_Any!]!) { _entities(representations: $representations) { __typename ... on User { id name } } }`
+	val, err = execute(query, map[string]interface{}{"representations": []interface{}{
+		map[string]interface{}{"__typename": "User", "id": "1"},
+		map[string]interface{}{"__typename": "User", "id": "2"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, internal.ParseJSON(`{
+		"_entities": [{"__typename": "User", "id": "1", "name": "Harry"}, null]
+	}`), internal.AsJSON(val))
+
+	_, err = execute(query, map[string]interface{}{"representations": []interface{}{
+		map[string]interface{}{"__typename": "Order", "id": "1"},
+	}})
+	assert.Equal(t, `error parsing args for "_entities": representations: unknown entity "Order"`, jerrors.ConvertError(err).Message)
+
+	t.Run("entity without key", func(t *testing.T) {
+		schema := schemabuilder.NewSchema()
+		schema.Object("User", User{})
+		schema.RegisterReferenceResolver("User", func(ctx context.Context, key map[string]interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad entity User: should have a key")
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	type Tick struct {
+		Count int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("Tick", Tick{}).FieldFunc("count", func(in *Tick) int64 { return in.Count })
+	schema.Query().FieldFunc("now", func() int64 { return 0 })
+	subscription := schema.Subscription()
+	subscription.FieldFunc("ticks", func(source *schemabuilder.Subscription, args struct{ To int64 }) (func() (*Tick, error), error) {
+		var count int64
+		return func() (*Tick, error) {
+			count++
+			switch {
+			case count > args.To:
+				return nil, errors.New("done")
+			case count == 2:
+				return nil, graphql.ErrNoUpdate
+			}
+			return &Tick{Count: count}, nil
+		}, nil
+	})
+	subscription.FieldFunc("name", func() string { return "gopher" })
+	builtSchema := schema.MustBuild()
+
+	subscribe := func(ctx context.Context, query string) (<-chan *graphql.SubscriptionResult, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(ctx, builtSchema.Subscription, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{SubscriptionInterval: time.Millisecond}
+		return e.Subscribe(ctx, builtSchema.Subscription, q)
+	}
+
+	results, err := subscribe(context.Background(), `subscription { ticks(to: 3) { count } }`)
+	require.NoError(t, err)
+
+	var data []interface{}
+	var last *graphql.SubscriptionResult
+	for result := range results {
+		if result.Data != nil {
+			assert.NoError(t, result.Err)
+			data = append(data, internal.AsJSON(result.Data))
+		}
+		last = result
+	}
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"ticks": map[string]interface{}{"count": float64(1)}},
+		map[string]interface{}{"ticks": map[string]interface{}{"count": float64(3)}},
+	}, data)
+	assert.Equal(t, &jerrors.Error{Message: "done", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"ticks"}}, last.Err)
+
+	// The subscription ends when the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err = subscribe(ctx, `subscription { ticks(to: 100) { count } }`)
+	require.NoError(t, err)
+	<-results
+	cancel()
+	for range results {
+	}
+
+	_, err = subscribe(context.Background(), `subscription { name }`)
+	assert.EqualError(t, err, "name must return a function or a channel to be subscribed to")
+
+	_, err = subscribe(context.Background(), `subscription { a: name b: name }`)
+	assert.EqualError(t, err, "subscriptions must select exactly one field")
+}
+
+func TestSubscribeChannel(t *testing.T) {
+	type Tick struct {
+		Count int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Object("Tick", Tick{}).FieldFunc("count", func(in *Tick) int64 { return in.Count })
+	schema.Query().FieldFunc("now", func() int64 { return 0 })
+	schema.Subscription().FieldFunc("ticks", func(ctx context.Context, args struct{ To int64 }) (<-chan *Tick, error) {
+		if args.To < 0 {
+			return nil, errors.New("negative")
+		}
+		ticks := make(chan *Tick)
+		go func() {
+			defer close(ticks)
+			for i := int64(1); i <= args.To; i++ {
+				select {
+				case ticks <- &Tick{Count: i}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ticks, nil
+	})
+	builtSchema := schema.MustBuild()
+
+	subscribe := func(ctx context.Context, query string) (<-chan *graphql.SubscriptionResult, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(ctx, builtSchema.Subscription, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Subscribe(ctx, builtSchema.Subscription, q)
+	}
+
+	// The subscription completes once the channel is closed.
+	results, err := subscribe(context.Background(), `subscription { ticks(to: 2) { count } }`)
+	require.NoError(t, err)
+	var data []interface{}
+	for result := range results {
+		assert.NoError(t, result.Err)
+		data = append(data, internal.AsJSON(result.Data))
+	}
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"ticks": map[string]interface{}{"count": float64(1)}},
+		map[string]interface{}{"ticks": map[string]interface{}{"count": float64(2)}},
+	}, data)
+
+	// Cancelling the subscription cancels the context of the resolver.
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err = subscribe(ctx, `subscription { ticks(to: 1000000) { count } }`)
+	require.NoError(t, err)
+	<-results
+	cancel()
+	for range results {
+	}
+
+	_, err = subscribe(context.Background(), `subscription { ticks(to: -1) { count } }`)
+	assert.Equal(t, &jerrors.Error{Message: "negative", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"ticks"}}, err)
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("ticks", func() <-chan int64 { return nil })
+	_, err = schema.Build()
+	assert.Error(t, err)
+}
+
+func TestRequestedFields(t *testing.T) {
+	type User struct {
+		Requested []string
+	}
+
+	schema := schemabuilder.NewSchema()
+	user := schema.Object("User", User{})
+	user.FieldFunc("requested", func(in *User) []string { return in.Requested })
+	user.FieldFunc("name", func() string { return "Harry" })
+	user.FieldFunc("email", func() string { return "harry@hogwarts.edu" })
+	user.FieldFunc("id", func() string { return "1" })
+	query := schema.Query()
+	query.FieldFunc("user", func(ctx context.Context) *User {
+		return &User{Requested: schemabuilder.RequestedFields(ctx)}
+	})
+	query.FieldFunc("users", func(ctx context.Context, selectionSet *graphql.SelectionSet) []*User {
+		return []*User{{Requested: schemabuilder.RequestedFields(ctx)}}
+	})
+	builtSchema := schema.MustBuild()
+
+	q, err := graphql.Parse(`{
+		user { requested name mail: email ... on User { id name } __typename }
+		users { requested }
+	}`, nil)
+	require.NoError(t, err)
+	require.NoError(t, graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+	e := graphql.Executor{}
+	val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"user": map[string]interface{}{
+			"requested":  []interface{}{"requested", "name", "email", "id"},
+			"name":       "Harry",
+			"mail":       "harry@hogwarts.edu",
+			"id":         "1",
+			"__typename": "User",
+		},
+		"users": []interface{}{
+			map[string]interface{}{"requested": []interface{}{"requested"}},
+		},
+	}, internal.AsJSON(val))
+}
+
+func TestFieldDirectives(t *testing.T) {
+	type roleKey struct{}
+
+	schema := schemabuilder.NewSchema()
+	schema.Enum(role(0), map[string]interface{}{
+		"USER":  role(0),
+		"ADMIN": role(1),
+	})
+	schema.Directive("auth", []graphql.DirectiveLocation{"FIELD_DEFINITION"},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			if ctx.Value(roleKey{}) != args.(struct{ Requires role }).Requires {
+				return nil, errors.New("forbidden")
+			}
+			return next(ctx)
+		},
+		schemabuilder.WithDirectiveArgs(struct{ Requires role }{}))
+	query := schema.Query()
+	query.FieldFunc("name", func() string { return "gopher" })
+	query.FieldFunc("secret", func() string { return "hunter2" }, schemabuilder.WithDirective("auth", map[string]interface{}{"requires": "ADMIN"}))
+	query.FieldFunc("failing", func() (string, error) { return "", errors.New("failed") }, schemabuilder.WithDirective("auth", map[string]interface{}{"requires": "USER"}))
+	builtSchema := schema.MustBuild()
+
+	execute := func(ctx context.Context, query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		if err := graphql.ValidateDirectives(builtSchema.Directives, q.SelectionSet); err != nil {
+			return nil, err
+		}
+		e := graphql.Executor{}
+		return e.Execute(ctx, builtSchema.Query, nil, q)
+	}
+
+	val, err := execute(context.WithValue(context.Background(), roleKey{}, role(1)), `{ name secret }`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "gopher", "secret": "hunter2"}, internal.AsJSON(val))
+
+	// A denied field resolves to null while the other fields are resolved.
+	val, err = execute(context.WithValue(context.Background(), roleKey{}, role(0)), `{ name s: secret }`)
+	assert.Equal(t, map[string]interface{}{"name": "gopher", "s": nil}, internal.AsJSON(val))
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "forbidden", Extensions: &jerrors.Extension{Code: jerrors.CodeForbidden}, Paths: []string{"s"}},
+	}}, err)
+
+	// The errors of an allowed resolver are not mistaken for a denial.
+	_, err = execute(context.WithValue(context.Background(), roleKey{}, role(0)), `{ failing }`)
+	assert.Equal(t, &jerrors.Error{Message: "failed", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"failing"}}, err)
+
+	// Directives of the schema may not be used in queries.
+	_, err = execute(context.Background(), `{ name @auth(requires: USER) }`)
+	assert.EqualError(t, err, `directive "@auth" may not be used on FIELD`)
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("secret", func() string { return "hunter2" }, schemabuilder.WithDirective("auth", nil))
+	_, err = schema.Build()
+	assert.EqualError(t, err, "field Query.secret: directive @auth is not registered")
+}
+
+func TestFieldNameMapper(t *testing.T) {
+	type Args struct {
+		UserID        string
+		HTTPURLParser string
+		Name          string `graphql:"fullName"`
+	}
+
+	for _, tt := range []struct {
+		name   string
+		mapper func(string) string
+		query  string
+	}{
+		{name: "default", query: `{ echo(userID: "1", hTTPURLParser: "p", fullName: "n") }`},
+		{name: "camel case", mapper: schemabuilder.CamelCase, query: `{ echo(userId: "1", httpurlParser: "p", fullName: "n") }`},
+		{name: "snake case", mapper: schemabuilder.SnakeCase, query: `{ echo(user_id: "1", httpurl_parser: "p", fullName: "n") }`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := schemabuilder.NewSchema()
+			if tt.mapper != nil {
+				schema.WithFieldNameMapper(tt.mapper)
+			}
+			schema.Query().FieldFunc("echo", func(args Args) string {
+				return args.UserID + args.HTTPURLParser + args.Name
+			})
+			builtSchema := schema.MustBuild()
+
+			q, err := graphql.Parse(tt.query, nil)
+			require.NoError(t, err)
+			require.NoError(t, graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+			e := graphql.Executor{}
+			val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"echo": "1pn"}, internal.AsJSON(val))
+		})
+	}
+
+	for name, expected := range map[string][2]string{
+		"UserID":        {"userId", "user_id"},
+		"HTTPURL":       {"httpurl", "httpurl"},
+		"HTTPServerID":  {"httpServerId", "http_server_id"},
+		"OAuth2Token":   {"oAuth2Token", "o_auth2_token"},
+		"Name":          {"name", "name"},
+		"already_snake": {"alreadySnake", "already_snake"},
+	} {
+		assert.Equal(t, expected[0], schemabuilder.CamelCase(name), name)
+		assert.Equal(t, expected[1], schemabuilder.SnakeCase(name), name)
+	}
+}
+
+func TestDuplicateNames(t *testing.T) {
+	type User struct{}
+	type UserInput struct{ Name string }
+	type Args struct {
+		UserID  string
+		User_ID string
+	}
+	type status int32
+
+	t.Run("field", func(t *testing.T) {
+		schema := schemabuilder.NewSchema()
+		schema.Query().FieldFunc("me", func() User { return User{} })
+		user := schema.Object("User", User{})
+		user.FieldFunc("name", func() string { return "" })
+		user.FieldFunc("name", func() *string { return nil })
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad type graphql_test.User: duplicate field name")
+	})
+
+	t.Run("argument", func(t *testing.T) {
+		schema := schemabuilder.NewSchema()
+		schema.WithFieldNameMapper(schemabuilder.SnakeCase)
+		schema.Query().FieldFunc("user", func(args Args) string { return "" })
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad method user on type schemabuilder.query: attempted to parse Args as arguments struct, but failed: bad arg type graphql_test.Args: duplicate field user_id")
+	})
+
+	t.Run("input field", func(t *testing.T) {
+		schema := schemabuilder.NewSchema()
+		schema.Query().FieldFunc("echo", func(args struct{ User UserInput }) string { return args.User.Name })
+		input := schema.InputObject("UserInput", UserInput{})
+		input.FieldFunc("name", func(target *UserInput, source string) { target.Name = source })
+		input.FieldFunc("name", func(target *UserInput, source *string) {})
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad input type graphql_test.UserInput: duplicate field name")
+	})
+
+	t.Run("enum value", func(t *testing.T) {
+		schema := schemabuilder.NewSchema()
+		schema.EnumAuto(status(0), []string{"ACTIVE", "ACTIVE"})
+		_, err := schema.Build()
+		assert.EqualError(t, err, "bad enum graphql_test.status: duplicate value ACTIVE")
+
+		schema = schemabuilder.NewSchema()
+		schema.Enum(status(0), map[string]interface{}{"ACTIVE": status(0), "ENABLED": status(0)})
+		_, err = schema.Build()
+		assert.EqualError(t, err, "bad enum graphql_test.status: duplicate value ENABLED")
+	})
+}
+
+func TestNullPropagation(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("name", func() string { return "query" })
+	query.FieldFunc("me", func() User { return User{Name: "ron"} })
+	query.FieldFunc("viewer", func() *User { return &User{Name: "ron"} })
+	query.FieldFunc("users", func() []*User { return []*User{{Name: "harry"}, {Name: "ron"}} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	user.FieldFunc("email", func(in *User) (string, error) {
+		if in.Name == "ron" {
+			return "", errors.New("email is hidden")
+		}
+		return in.Name + "@hogwarts.edu", nil
+	})
+	user.FieldFunc("friend", func(in *User) *User { return nil }, schemabuilder.NonNull())
+	builtSchema := schema.MustBuild()
+
+	execute := func(query string) (interface{}, error) {
+		q, err := graphql.Parse(query, nil)
+		require.NoError(t, err)
+		require.NoError(t, graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(val), err
+	}
+
+	// The non-null me propagates the error of its non-null email up to the root.
+	val, err := execute(`{ name me { name email } }`)
+	assert.Nil(t, val)
+	assert.Equal(t, &jerrors.Error{Message: "email is hidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"me", "email"}}, err)
+
+	// The nullable viewer resolves to null, and its siblings are kept.
+	val, err = execute(`{ name viewer { name email } }`)
+	assert.Equal(t, map[string]interface{}{"name": "query", "viewer": nil}, val)
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "email is hidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"viewer", "email"}},
+	}}, err)
+
+	// The elements of a list are non-null, so that the error propagates to the list field.
+	val, err = execute(`{ name users { email } }`)
+	assert.Nil(t, val)
+	assert.Equal(t, &jerrors.Error{Message: "email is hidden", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"users", "1", "email"}}, err)
+
+	// A non-null field resolving to null is an error.
+	val, err = execute(`{ name viewer { friend { name } } }`)
+	assert.Equal(t, map[string]interface{}{"name": "query", "viewer": nil}, val)
+	assert.Equal(t, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "cannot return null for non-null field User.friend", Extensions: &jerrors.Extension{Code: "Unknown"}, Paths: []string{"viewer", "friend"}},
+	}}, err)
+}
+
+func TestRecursiveTypes(t *testing.T) {
+	type Category struct {
+		Name     string
+		Parent   *Category
+		Children []*Category
+	}
+	type Author struct {
+		Name  string
+		Books []string
+	}
+	type Book struct {
+		Title  string
+		Author string
+	}
+
+	root := &Category{Name: "root"}
+	for _, name := range []string{"a", "b"} {
+		child := &Category{Name: name, Parent: root}
+		child.Children = []*Category{{Name: name + "1", Parent: child}}
+		root.Children = append(root.Children, child)
+	}
+	authors := map[string]*Author{"rowling": {Name: "rowling", Books: []string{"stone", "chamber"}}}
+	books := map[string]*Book{"stone": {Title: "stone", Author: "rowling"}, "chamber": {Title: "chamber", Author: "rowling"}}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("root", func() *Category { return root })
+	query.FieldFunc("book", func(args struct{ Title string }) *Book { return books[args.Title] })
+
+	category := schema.Object("Category", Category{})
+	category.FieldFunc("name", func(in *Category) string { return in.Name })
+	category.FieldFunc("parent", func(in *Category) *Category { return in.Parent })
+	category.FieldFunc("children", func(in *Category) []*Category { return in.Children })
+
+	author := schema.Object("Author", Author{})
+	author.FieldFunc("name", func(in *Author) string { return in.Name })
+	author.FieldFunc("books", func(in *Author) []*Book {
+		var written []*Book
+		for _, title := range in.Books {
+			written = append(written, books[title])
+		}
+		return written
+	})
+	book := schema.Object("Book", Book{})
+	book.FieldFunc("title", func(in *Book) string { return in.Title })
+	book.FieldFunc("author", func(in *Book) *Author { return authors[in.Author] })
+
+	builtSchema, err := schema.Build()
+	require.NoError(t, err)
+
+	execute := func(query string) interface{} {
+		q, err := graphql.Parse(query, nil)
+		require.NoError(t, err)
+		require.NoError(t, graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet))
+		e := graphql.Executor{}
+		val, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		require.NoError(t, err)
+		return internal.AsJSON(val)
+	}
+
+	// The object type of a field referring to the object itself is built once.
+	assert.Equal(t, internal.ParseJSON(`{"root": {"name": "root", "children": [
+		{"name": "a", "parent": {"name": "root"}, "children": [{"name": "a1", "parent": {"name": "a"}}]},
+		{"name": "b", "parent": {"name": "root"}, "children": [{"name": "b1", "parent": {"name": "b"}}]}
+	]}}`), execute(`{ root { name children { name parent { name } children { name parent { name } } } } }`))
+
+	// Mutually referring object types are built once.
+	assert.Equal(t, internal.ParseJSON(`{"book": {"title": "stone", "author": {"name": "rowling", "books": [
+		{"title": "stone", "author": {"name": "rowling"}},
+		{"title": "chamber", "author": {"name": "rowling"}}
+	]}}}`), execute(`{ book(title: "stone") { title author { name books { title author { name } } } } }`))
+}
+
+func TestErrorLocations(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func() *User { return &User{Name: "harry"} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string { return in.Name })
+	builtSchema := schema.MustBuild()
+
+	for _, tt := range []struct {
+		name     string
+		query    string
+		err      string
+		location jerrors.Location
+	}{
+		{
+			name:     "misspelled field",
+			query:    "{\n  user {\n    nmae\n  }\n}",
+			err:      `unknown field "nmae". Did you mean "name"?`,
+			location: jerrors.Location{Line: 3, Column: 5},
+		},
+		{
+			name:     "missing selections",
+			query:    "{\n  user\n}",
+			err:      "object field must have selections",
+			location: jerrors.Location{Line: 2, Column: 3},
+		},
+		{
+			name:     "syntax error",
+			query:    "{\n  user {\n    name(\n  }\n}",
+			location: jerrors.Location{Line: 4, Column: 3},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := graphql.Parse(tt.query, nil)
+			if err == nil {
+				err = graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet)
+			}
+			require.Error(t, err)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+			}
+			assert.Equal(t, []jerrors.Location{tt.location}, jerrors.ConvertError(err).Locations)
+		})
+	}
+}
+
+func TestUnknownFieldSuggestions(t *testing.T) {
+	type User struct {
+		Username string
+		Email    string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func() *User { return &User{} })
+	user := schema.Object("User", User{})
+	user.FieldFunc("username", func(in *User) string { return in.Username })
+	user.FieldFunc("email", func(in *User) string { return in.Email })
+	user.FieldFunc("id", func(in *User) string { return "" })
+	builtSchema := schema.MustBuild()
+
+	for _, tt := range []struct {
+		field string
+		err   string
+	}{
+		{field: "usrname", err: `unknown field "usrname". Did you mean "username"?`},
+		{field: "emial", err: `unknown field "emial". Did you mean "email"?`},
+		{field: "ids", err: `unknown field "ids". Did you mean "id"?`},
+		// Names too far from any field get no suggestion.
+		{field: "phone", err: `unknown field "phone"`},
+		{field: "x", err: `unknown field "x"`},
+	} {
+		t.Run(tt.field, func(t *testing.T) {
+			q, err := graphql.Parse(`{ user { `+tt.field+` } }`, nil)
+			require.NoError(t, err)
+			assert.EqualError(t, graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet), tt.err)
+		})
+	}
 }
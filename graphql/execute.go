@@ -1,42 +1,143 @@
 package graphql
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"go.appointy.com/jaal/jerrors"
 )
 
 type Executor struct {
+	// DeprecationUsageHook, if set, is called whenever a deprecated field is resolved, and for
+	// every deprecated arg or input object field given a value by the resolved field. The
+	// fieldName of an arg is the field and the arg in the form users(name:), with the typeName
+	// of the field, while the typeName of an input object field is the input object.
+	DeprecationUsageHook func(ctx context.Context, typeName, fieldName string)
+
+	// Directives are the custom directives whose handlers are called for the fields they are
+	// applied to. Their args must have been parsed by ValidateDirectives.
+	Directives map[string]*DirectiveDefinition
+
+	// FieldMiddleware runs around the resolver of every field, the first one outermost.
+	FieldMiddleware []FieldMiddleware
+
+	// PanicHandler, if set, is called with the recovered value and the stack trace whenever a
+	// resolver panics, e.g. to log them. The client only receives a sanitized error.
+	PanicHandler func(ctx context.Context, recovered interface{}, stack []byte)
+
+	// MaxConcurrency, if positive, resolves the Expensive fields of an object concurrently with
+	// their siblings, running at most MaxConcurrency of them at once. Other fields, and the
+	// Expensive fields exceeding the limit, are resolved in order. The DeprecationUsageHook,
+	// PanicHandler and FieldMiddleware may then be called concurrently. The functions returned
+	// by a lazy list field are always called concurrently, within the same limit if it is set.
+	MaxConcurrency int
+
+	// SubscriptionInterval is how often Subscribe calls the function returned by the resolver
+	// of a subscription to produce the next event. It defaults to one second.
+	SubscriptionInterval time.Duration
+
 	iterate bool
+
+	// sem holds a token for every Expensive field being resolved concurrently.
+	sem chan struct{}
+
+	// fieldErrors are the errors of the fields resolving to null while the rest of the response
+	// is kept, because their resolvers panicked, the deadline of the context was exceeded, or
+	// the error of a field propagated up to them.
+	fieldErrors []*jerrors.Error
+
+	// mutation is set by Execute while executing a mutation, none of whose root fields is
+	// resolved after one of them failed, since they are resolved in order for their side effects.
+	mutation bool
+
+	// deferring is set by ExecuteIncremental to collect the fragments marked with @defer.
+	deferring bool
+	deferred  []Deferred
+}
+
+// FieldInfo describes the field being resolved for a FieldMiddleware.
+type FieldInfo struct {
+	// ParentType is the name of the object the field belongs to.
+	ParentType string
+	FieldName  string
+	Alias      string
+	// ReturnType is the type of the field.
+	ReturnType Type
+	// Args are the parsed args of the field.
+	Args interface{}
 }
 
+// FieldMiddleware runs around the resolver of a field, which it calls with next. It may inspect
+// or replace the value returned by next, or return without calling it, e.g. to deny access to
+// the field. Around a BatchResolver, it runs once for all the sources, and next returns the
+// values of the field for all of them as a []interface{}.
+type FieldMiddleware func(ctx context.Context, info FieldInfo, next func() (interface{}, error)) (interface{}, error)
+
 type computationOutput struct {
 	Function  interface{}
 	Field     *Field
 	Selection *Selection
+
+	path *pathSegment
+}
+
+// computationList holds the slice of functions returned by a lazy list field.
+type computationList struct {
+	Functions interface{}
+	Field     *Field
+	Selection *Selection
+
+	path *pathSegment
 }
 
 var ErrNoUpdate = errors.New("no update")
 
+// Execute resolves the query against typ. The query must have been validated with ValidateQuery
+// first, which parses the arguments of every selection in the query. As a result invalid input
+// anywhere in the query is rejected before any resolver is invoked.
+//
+// When the resolver of a field fails, or the field is non-null but resolves to null, the error
+// propagates up to the nearest nullable field or list element, which resolves to null. The
+// response is then returned along with a jerrors.MultiError, holding the error at the path of
+// the field which failed, such as ["allUsers", "2", "email"]. An error propagating up to the
+// root of the response is returned alone, along with a nil response.
+//
+// Objects are returned as a ResponseObject, holding their fields in the order they were selected.
 func (e *Executor) Execute(ctx context.Context, typ Type, source interface{}, query *Query) (interface{}, error) {
+	e.fieldErrors = nil
+	e.mutation = query.Kind == "mutation"
+	e.sem = nil
+	if e.MaxConcurrency > 0 {
+		e.sem = make(chan struct{}, e.MaxConcurrency)
+	}
+
 	response, err := e.execute(ctx, typ, source, query.SelectionSet)
 	if err != nil {
-		return nil, err
+		return nil, rootError(err)
 	}
 
 	for e.iterate {
 		e.iterate = false
 
 		if err := e.lateExecution(ctx, response); err != nil {
-			return nil, err
+			return nil, rootError(err)
 		}
 	}
 
+	// Fields whose resolvers panicked or timed out, and the nullable fields to which errors
+	// propagated, resolve to null while the rest of the response is kept.
+	if len(e.fieldErrors) > 0 {
+		return response, &jerrors.MultiError{Errors: e.fieldErrors}
+	}
+
 	return response, nil
 }
 
@@ -85,19 +186,20 @@ func unwrap(v interface{}) interface{} {
 
 func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
-	if value.Kind() == reflect.Ptr && value.IsNil() {
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
 		return nil, nil
 	}
 
-	fields := make(map[string]interface{})
-	for _, selection := range selectionSet.Selections {
-		if selection.Name == "__typename" {
-			fields[selection.Alias] = typ.Name
-			continue
+	if typ.ResolveType != nil {
+		member := typ.ResolveType(source)
+		if member == nil {
+			return nil, fmt.Errorf("union type %s has no member type for %T", typ.Name, source)
 		}
+		return e.executeAbstract(ctx, typ.Name, member, source, selectionSet)
 	}
 
-	// For every inline fragment spread, check if the current concrete type matches and execute that object.
+	// Resolve the selections whose type condition matches the concrete type held by the union.
+	var fields ResponseObject
 	var possibleTypes []string
 	for typString, graphqlTyp := range typ.Types {
 		inner := reflect.ValueOf(source)
@@ -111,78 +213,292 @@ func (e *Executor) executeUnion(ctx context.Context, typ *Union, source interfac
 		}
 		possibleTypes = append(possibleTypes, graphqlTyp.String())
 
-		for _, fragment := range selectionSet.Fragments {
-			if fragment.Fragment.On != typString {
-				continue
-			}
-			resolved, err := e.executeObject(ctx, graphqlTyp, inner.Interface(), fragment.Fragment.SelectionSet)
-			if err != nil {
-				if err == ErrNoUpdate {
-					return nil, err
-				}
-				return nil, jerrors.NestErrorPaths(err, typString)
-			}
-
-			for k, v := range resolved.(map[string]interface{}) {
-				fields[k] = v
-			}
+		resolved, err := e.executeAbstract(ctx, typ.Name, graphqlTyp, inner.Interface(), selectionSet)
+		if err != nil {
+			return nil, err
 		}
+		fields = resolved
 	}
 
 	if len(possibleTypes) > 1 {
 		return nil, fmt.Errorf("union type field should only return one value, but received: %s", strings.Join(possibleTypes, " "))
 	}
+	if fields == nil {
+		fields = ResponseObject{}
+	}
 	return fields, nil
 }
 
+// executeAbstract resolves selectionSet, selected on the union or interface named abstract, on
+// the concrete object typ of source. Only the fragments whose type condition is the abstract type
+// or is satisfied by typ are applied.
+func (e *Executor) executeAbstract(ctx context.Context, abstract string, typ *Object, source interface{}, selectionSet *SelectionSet) (ResponseObject, error) {
+	selections, err := flatten(selectionSet, func(on string) bool {
+		return on == abstract || typ.satisfies(on)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e.executeSelections(ctx, typ, source, selections, nil)
+}
+
 // executeObject executes an object query
 func (e *Executor) executeObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
+	return e.executeBatchedObject(ctx, typ, source, selectionSet, nil)
+}
+
+// executeBatchedObject executes an object like executeObject, except that the values of the
+// fields in batched, by response key, have already been resolved by their BatchResolver.
+func (e *Executor) executeBatchedObject(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet, batched map[string]interface{}) (interface{}, error) {
 	value := reflect.ValueOf(source)
 	if value.Kind() == reflect.Ptr && value.IsNil() {
 		return nil, nil
 	}
 
-	selections, err := Flatten(selectionSet)
+	selections, err := flatten(e.deferFragments(ctx, typ, source, selectionSet), typ.satisfies)
 	if err != nil {
 		return nil, err
 	}
+	return e.executeSelections(ctx, typ, source, selections, batched)
+}
+
+// executeSelections resolves the flattened selections on the object typ, using the values of
+// the fields in batched, by response key, instead of resolving them.
+func (e *Executor) executeSelections(ctx context.Context, typ *Object, source interface{}, selections []*Selection, batched map[string]interface{}) (ResponseObject, error) {
+	fields := make(ResponseObject, 0, len(selections))
+
+	// The fields resolved concurrently are stored once all of them are resolved.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	var concurrent []*concurrentField
 
-	fields := make(map[string]interface{})
+	// The errors of the non-null fields are returned once all the fields are resolved, so that
+	// the errors of all the failing fields are reported.
+	failed := &nullField{}
+
+	// The root fields of a mutation are resolved serially, in order.
+	serial := e.mutation && pathFromContext(ctx) == nil
 
 	// for every selection, resolve the value and store it in the output object
 	for _, selection := range selections {
-		if ok, err := shouldIncludeNode(selection.Directives); err != nil {
-			if err == ErrNoUpdate {
-				return nil, err
-			}
-			return nil, jerrors.NestErrorPaths(err, selection.Alias)
-		} else if !ok {
-			continue
-		}
-
 		if selection.Name == "__typename" {
-			fields[selection.Alias] = typ.Name
+			fields = append(fields, ResponseField{Key: selection.Alias, Value: typ.typename(source)})
 			continue
 		}
 
 		field := typ.Fields[selection.Name]
-		resolved, err := e.resolveAndExecute(ctx, field, source, selection)
+		e.trackDeprecation(ctx, typ.Name, field, selection)
+
+		var resolved interface{}
+		var err error
+		if value, ok := batched[selection.Alias]; ok {
+			fieldCtx := withPathSegment(ctx, selection.Alias)
+			if lazy, ok := e.deferLazy(fieldCtx, field, selection, value); ok {
+				resolved = lazy
+			} else {
+				resolved, err = e.execute(fieldCtx, field.Type, value, selection.SelectionSet)
+			}
+		} else if field.Expensive && !serial && e.acquire() {
+			c := &concurrentField{alias: selection.Alias, index: len(fields), field: field, executor: e.forkExecution()}
+			concurrent = append(concurrent, c)
+			fields = append(fields, ResponseField{Key: selection.Alias})
+
+			wg.Add(1)
+			go func(field *Field, selection *Selection) {
+				defer wg.Done()
+				defer e.release()
+				c.value, c.err = c.executor.resolveAndExecute(ctx, typ.Name, field, source, selection)
+			}(field, selection)
+			continue
+		} else {
+			resolved, err = e.resolveAndExecute(ctx, typ.Name, field, source, selection)
+		}
 		if err != nil {
 			if err == ErrNoUpdate {
 				return nil, err
 			}
-			return nil, jerrors.NestErrorPaths(err, selection.Alias)
+			if !e.recoverNullField(withPathSegment(ctx, selection.Alias), field.Type, err) {
+				if err := nestErrorPath(err, selection.Alias); !failed.add(err) {
+					return nil, err
+				}
+				if serial {
+					return nil, failed
+				}
+				continue
+			}
 		}
-		fields[selection.Alias] = resolved
+		fields = append(fields, ResponseField{Key: selection.Alias, Value: resolved})
 	}
 
+	wg.Wait()
+	for _, c := range concurrent {
+		e.join(c.executor)
+		if c.err != nil {
+			if c.err == ErrNoUpdate {
+				return nil, c.err
+			}
+			if !e.recoverNullField(withPathSegment(ctx, c.alias), c.field.Type, c.err) {
+				if err := nestErrorPath(c.err, c.alias); !failed.add(err) {
+					return nil, err
+				}
+				continue
+			}
+		}
+		fields[c.index].Value = c.value
+	}
+
+	if len(failed.errs) > 0 {
+		return nil, failed
+	}
 	return fields, nil
 }
 
-func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source interface{}, selection *Selection) (interface{}, error) {
-	value, err := safeExecuteResolver(ctx, field, source, selection.Args, selection.SelectionSet)
-	if err != nil {
-		return nil, err
+// concurrentField is a field resolved concurrently with its siblings by its own executor. Its
+// value is stored at index in the fields of the object, keeping the order of the selections.
+type concurrentField struct {
+	alias    string
+	index    int
+	field    *Field
+	executor *Executor
+
+	value interface{}
+	err   error
+}
+
+// ResponseField is a field of an object in a response, by its response key.
+type ResponseField struct {
+	Key   string
+	Value interface{}
+}
+
+// ResponseObject is an object in a response, holding its fields in the order they were selected.
+// It is encoded to a JSON object with its keys in that order.
+type ResponseObject []ResponseField
+
+// Get returns the value of the field with the response key key.
+func (o ResponseObject) Get(key string) (interface{}, bool) {
+	for _, field := range o {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set stores value as the field with the response key key, appending the field unless it is
+// already present.
+func (o ResponseObject) set(key string, value interface{}) ResponseObject {
+	for i := range o {
+		if o[i].Key == key {
+			o[i].Value = value
+			return o
+		}
+	}
+	return append(o, ResponseField{Key: key, Value: value})
+}
+
+// MarshalJSON encodes the object with its keys in the order of its fields. A nil object, such as
+// the value of an object which failed to resolve, is encoded as null.
+func (o ResponseObject) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// acquire reports whether an Expensive field can be resolved concurrently, in which case
+// release must be called once it is resolved.
+func (e *Executor) acquire() bool {
+	select {
+	case e.sem <- struct{}{}:
+		return true
+	default:
+		// The field is resolved in order when the limit is reached or concurrency is disabled.
+		return false
+	}
+}
+
+func (e *Executor) release() {
+	<-e.sem
+}
+
+// forkExecution returns an executor continuing the execution of e with its own state, which
+// is merged into e with join.
+func (e *Executor) forkExecution() *Executor {
+	inner := e.fork()
+	inner.deferring = e.deferring
+	return inner
+}
+
+// join merges the execution state of inner, returned by forkExecution, into e.
+func (e *Executor) join(inner *Executor) {
+	e.iterate = e.iterate || inner.iterate
+	e.fieldErrors = append(e.fieldErrors, inner.fieldErrors...)
+	e.deferred = append(e.deferred, inner.deferred...)
+}
+
+// fork returns an executor with the configuration of e, and its own execution state.
+func (e *Executor) fork() *Executor {
+	return &Executor{
+		DeprecationUsageHook: e.DeprecationUsageHook,
+		Directives:           e.Directives,
+		FieldMiddleware:      e.FieldMiddleware,
+		PanicHandler:         e.PanicHandler,
+		MaxConcurrency:       e.MaxConcurrency,
+		SubscriptionInterval: e.SubscriptionInterval,
+		sem:                  e.sem,
+	}
+}
+
+// trackDeprecation reports the usage of a deprecated field of the object typeName, and of the
+// deprecated args and input object fields given a value by selection, to the DeprecationUsageHook.
+func (e *Executor) trackDeprecation(ctx context.Context, typeName string, field *Field, selection *Selection) {
+	if e.DeprecationUsageHook == nil {
+		return
+	}
+	if field.IsDeprecated {
+		e.DeprecationUsageHook(ctx, typeName, selection.Name)
+	}
+	for _, arg := range selection.deprecatedArgs {
+		e.DeprecationUsageHook(ctx, typeName, selection.Name+"("+arg+":)")
+	}
+	for _, inputField := range selection.deprecatedInputFields {
+		e.DeprecationUsageHook(ctx, inputField.typeName, inputField.fieldName)
+	}
+}
+
+// deferLazy returns the computation of value, which is resolved in a later iteration of the
+// executor, if field is lazy.
+func (e *Executor) deferLazy(ctx context.Context, field *Field, selection *Selection, value interface{}) (interface{}, bool) {
+	// If a field returns a list of functions, then resolve all of them later at once
+	if field.LazyListExecution {
+		e.iterate = true
+		return &computationList{
+			Functions: value,
+			Field:     field,
+			Selection: selection,
+			path:      pathFromContext(ctx),
+		}, true
 	}
 
 	// If a field returns function, then do not execute the function at the moment
@@ -192,22 +508,292 @@ func (e *Executor) resolveAndExecute(ctx context.Context, field *Field, source i
 			Function:  value,
 			Field:     field,
 			Selection: selection,
-		}, nil
+			path:      pathFromContext(ctx),
+		}, true
 	}
 
-	return e.execute(ctx, field.Type, value, selection.SelectionSet)
+	return nil, false
 }
 
-func safeExecuteResolver(ctx context.Context, field *Field, source, args interface{}, selectionSet *SelectionSet) (result interface{}, err error) {
+func (e *Executor) resolveAndExecute(ctx context.Context, typeName string, field *Field, source interface{}, selection *Selection) (interface{}, error) {
+	ctx = withPathSegment(ctx, selection.Alias)
+	resolve := e.applyFieldMiddleware(typeName, field, selection, e.applyDirectives(field, source, selection))
+	value, err := safeExecuteResolver(ctx, resolve)
+	if err != nil {
+		if e.recoverField(ctx, err) {
+			return nil, nil
+		}
+		if err == ErrNoUpdate {
+			return nil, err
+		}
+		return nil, newNullField(err)
+	}
+
+	if lazy, ok := e.deferLazy(ctx, field, selection, value); ok {
+		return lazy, nil
+	}
+
+	resolved, err := e.execute(ctx, field.Type, value, selection.SelectionSet)
+	if err == context.DeadlineExceeded && e.recoverField(ctx, err) {
+		// The deadline was exceeded before the value of the field was completed.
+		return nil, nil
+	}
+	if _, ok := field.Type.(*NonNull); ok && err == nil && resolved == nil {
+		return nil, newNullField(fmt.Errorf("cannot return null for non-null field %s.%s", typeName, selection.Name))
+	}
+	return resolved, err
+}
+
+// applyDirectives returns a function resolving selection with the resolver of field, through
+// the handlers of the custom directives applied to selection. The first directive is the
+// outermost handler.
+func (e *Executor) applyDirectives(field *Field, source interface{}, selection *Selection) func(ctx context.Context) (interface{}, error) {
+	resolve := func(ctx context.Context) (interface{}, error) {
+		return field.Resolve(ctx, source, selection.Args, selection.SelectionSet)
+	}
+
+	for i := len(selection.Directives) - 1; i >= 0; i-- {
+		directive := selection.Directives[i]
+		definition, ok := e.Directives[directive.Name]
+		if !ok || definition.Handler == nil {
+			continue
+		}
+
+		next := resolve
+		resolve = func(ctx context.Context) (interface{}, error) {
+			return definition.Handler(ctx, directive.Args, next)
+		}
+	}
+
+	// The directives of the schema run outermost, so that they may deny the field before any
+	// directive of the query.
+	for i := len(field.Directives) - 1; i >= 0; i-- {
+		directive := field.Directives[i]
+
+		next := resolve
+		resolve = func(ctx context.Context) (interface{}, error) {
+			var nextErr error
+			value, err := directive.Handler(ctx, directive.Args, func(ctx context.Context) (interface{}, error) {
+				value, err := next(ctx)
+				nextErr = err
+				return value, err
+			})
+			if err != nil && err != nextErr {
+				return nil, &deniedField{err: err}
+			}
+			return value, err
+		}
+	}
+
+	return resolve
+}
+
+// deniedField is the error returned by the handler of a directive applied to a field by the
+// schema, which denied the field.
+type deniedField struct {
+	err error
+}
+
+func (d *deniedField) Error() string {
+	return d.err.Error()
+}
+
+func (d *deniedField) Unwrap() error {
+	return d.err
+}
+
+// applyFieldMiddleware wraps resolve, which resolves selection of field on an object of the type
+// named typeName, with the FieldMiddleware of the executor.
+func (e *Executor) applyFieldMiddleware(typeName string, field *Field, selection *Selection, resolve func(ctx context.Context) (interface{}, error)) func(ctx context.Context) (interface{}, error) {
+	if len(e.FieldMiddleware) == 0 {
+		return resolve
+	}
+
+	info := FieldInfo{
+		ParentType: typeName,
+		FieldName:  selection.Name,
+		Alias:      selection.Alias,
+		ReturnType: field.Type,
+		Args:       selection.Args,
+	}
+	return func(ctx context.Context) (interface{}, error) {
+		next := func() (interface{}, error) {
+			return resolve(ctx)
+		}
+		for i := len(e.FieldMiddleware) - 1; i >= 0; i-- {
+			middleware, inner := e.FieldMiddleware[i], next
+			next = func() (interface{}, error) {
+				return middleware(ctx, info, inner)
+			}
+		}
+		return next()
+	}
+}
+
+func safeExecuteResolver(ctx context.Context, resolve func(ctx context.Context) (interface{}, error)) (result interface{}, err error) {
 	defer func() {
 		if panicErr := recover(); panicErr != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			result, err = nil, fmt.Errorf("graphql: panic: %v\n%s", panicErr, buf)
+			result, err = nil, newResolverPanic(panicErr)
 		}
 	}()
-	return field.Resolve(ctx, source, args, selectionSet)
+	return resolve(ctx)
+}
+
+// resolverPanic is the error of a resolver which panicked.
+type resolverPanic struct {
+	recovered interface{}
+	stack     []byte
+}
+
+func newResolverPanic(recovered interface{}) *resolverPanic {
+	const size = 64 << 10
+	buf := make([]byte, size)
+	buf = buf[:runtime.Stack(buf, false)]
+	return &resolverPanic{recovered: recovered, stack: buf}
+}
+
+func (p *resolverPanic) Error() string {
+	return fmt.Sprintf("graphql: panic: %v\n%s", p.recovered, p.stack)
+}
+
+// recoverField reports whether err is the panic of the resolver of the field at the path in
+// ctx, an error caused by the deadline of ctx being exceeded, or the error of a directive of the
+// schema denying the field. If so, an error is recorded for the field, which resolves to null,
+// and a panic is passed to the PanicHandler.
+func (e *Executor) recoverField(ctx context.Context, err error) bool {
+	paths := errorPaths(ctx)
+
+	if denied, ok := err.(*deniedField); ok {
+		// The error of the directive is reported as forbidden, unless it has its own code.
+		fieldErr := &jerrors.Error{
+			Message:    denied.err.Error(),
+			Extensions: &jerrors.Extension{Code: jerrors.CodeForbidden},
+		}
+		var withCode *jerrors.Error
+		if errors.As(denied.err, &withCode) && withCode != nil && withCode.Extensions != nil {
+			fieldErr.Extensions = &jerrors.Extension{Code: withCode.Extensions.Code}
+		}
+		fieldErr.Paths = paths
+		e.fieldErrors = append(e.fieldErrors, fieldErr)
+		return true
+	}
+
+	if p, ok := err.(*resolverPanic); ok {
+		if e.PanicHandler != nil {
+			e.PanicHandler(ctx, p.recovered, p.stack)
+		}
+
+		e.fieldErrors = append(e.fieldErrors, &jerrors.Error{
+			Message:    "internal server error",
+			Extensions: &jerrors.Extension{Code: jerrors.CodeInternal},
+			Paths:      paths,
+		})
+		return true
+	}
+
+	if ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	e.fieldErrors = append(e.fieldErrors, &jerrors.Error{
+		Message:    "execution timed out",
+		Extensions: &jerrors.Extension{Code: jerrors.CodeTimeout},
+		Paths:      paths,
+	})
+	return true
+}
+
+// nullField is the error of a field whose resolver failed, or which is non-null but resolved to
+// null. It propagates up to the nearest nullable field or list element, which resolves to null
+// instead, and its errors are then recorded with the paths of the fields which failed.
+type nullField struct {
+	// errs have paths relative to the value being executed.
+	errs []*jerrors.Error
+}
+
+func newNullField(err error) *nullField {
+	if multi := jerrors.ConvertMultiError(err); multi != nil {
+		return &nullField{errs: multi.Errors}
+	}
+	return &nullField{errs: []*jerrors.Error{jerrors.ConvertError(err)}}
+}
+
+func (n *nullField) Error() string {
+	return (&jerrors.MultiError{Errors: n.errs}).Error()
+}
+
+func (n *nullField) Errors() []error {
+	errs := make([]error, 0, len(n.errs))
+	for _, err := range n.errs {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// add adds the errors of err to n if it is a nullField, reporting whether it is.
+func (n *nullField) add(err error) bool {
+	null, ok := err.(*nullField)
+	if ok {
+		n.errs = append(n.errs, null.errs...)
+	}
+	return ok
+}
+
+// nestErrorPath nests the paths of err, returned for the value at the path segment, in segment.
+func nestErrorPath(err error, segment string) error {
+	null, ok := err.(*nullField)
+	if !ok {
+		return jerrors.NestErrorPaths(err, segment)
+	}
+
+	nested := &nullField{errs: make([]*jerrors.Error, 0, len(null.errs))}
+	for _, err := range null.errs {
+		nested.errs = append(nested.errs, jerrors.NestErrorPaths(err, segment).(*jerrors.Error))
+	}
+	return nested
+}
+
+// recoverNullField reports whether err, returned for the value of type typ at the path in ctx,
+// is a nullField stopping at the value because typ is nullable. If so, its errors are recorded
+// and the value resolves to null.
+func (e *Executor) recoverNullField(ctx context.Context, typ Type, err error) bool {
+	null, ok := err.(*nullField)
+	if !ok {
+		return false
+	}
+	if _, ok := typ.(*NonNull); ok {
+		return false
+	}
+
+	paths := errorPaths(ctx)
+	for _, fieldErr := range null.errs {
+		for i := len(paths) - 1; i >= 0; i-- {
+			fieldErr = jerrors.NestErrorPaths(fieldErr, paths[i]).(*jerrors.Error)
+		}
+		e.fieldErrors = append(e.fieldErrors, fieldErr)
+	}
+	return true
+}
+
+// rootError returns err, which failed the whole response, as the error of the response.
+func rootError(err error) error {
+	null, ok := err.(*nullField)
+	if !ok {
+		return err
+	}
+	if len(null.errs) == 1 {
+		return null.errs[0]
+	}
+	return &jerrors.MultiError{Errors: null.errs}
+}
+
+// errorPaths returns the path of the field in ctx as the paths of an error.
+func errorPaths(ctx context.Context) []string {
+	path := FieldPathFromContext(ctx)
+	paths := make([]string, 0, len(path))
+	for _, segment := range path {
+		paths = append(paths, fmt.Sprint(segment))
+	}
+	return paths
 }
 
 var emptyList = []interface{}{}
@@ -220,32 +806,130 @@ func (e *Executor) executeList(ctx context.Context, typ *List, source interface{
 
 	// iterate over arbitrary slice types using reflect
 	slice := reflect.ValueOf(source)
-	items := make([]interface{}, slice.Len())
+	items := make([]interface{}, 0, slice.Len())
+
+	// resolve the fields with a BatchResolver once for all elements
+	elemTyp := typ.Type
+	if nonNull, ok := elemTyp.(*NonNull); ok {
+		elemTyp = nonNull.Type
+	}
+	object, _ := elemTyp.(*Object)
+	var batches []map[string]interface{}
+	if object != nil {
+		var err error
+		if batches, err = e.resolveBatches(ctx, object, slice, selectionSet); err != nil {
+			return nil, err
+		}
+	}
 
-	// resolve every element in the slice
+	// resolve every element in the slice, reporting the errors of all the failing elements
+	failed := &nullField{}
 	for i := 0; i < slice.Len(); i++ {
 		value := slice.Index(i)
-		resolved, err := e.execute(ctx, typ.Type, value.Interface(), selectionSet)
+
+		var resolved interface{}
+		var err error
+		if batches != nil {
+			resolved, err = e.executeBatchedObject(withPathSegment(ctx, i), object, value.Interface(), selectionSet, batches[i])
+		} else {
+			resolved, err = e.execute(withPathSegment(ctx, i), typ.Type, value.Interface(), selectionSet)
+		}
 		if err != nil {
 			if err == ErrNoUpdate {
 				return nil, err
 			}
-			return nil, jerrors.NestErrorPaths(err, fmt.Sprint(i))
+			if !e.recoverNullField(withPathSegment(ctx, i), typ.Type, err) {
+				if err := nestErrorPath(err, fmt.Sprint(i)); !failed.add(err) {
+					return nil, err
+				}
+				continue
+			}
 		}
-		items[i] = resolved
+		if resolved == nil && typ.DropNulls {
+			continue
+		}
+		items = append(items, resolved)
 	}
 
+	if len(failed.errs) > 0 {
+		return nil, failed
+	}
 	return items, nil
 }
 
+// resolveBatches calls the BatchResolver of every field of typ selected in selectionSet once,
+// with the non-nil elements of slice as sources. It returns the values of those fields for every
+// element, by response key, or nil if no such field is selected.
+func (e *Executor) resolveBatches(ctx context.Context, typ *Object, slice reflect.Value, selectionSet *SelectionSet) ([]map[string]interface{}, error) {
+	selections, err := flatten(selectionSet, typ.satisfies)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []interface{}
+	var indices []int
+	for i := 0; i < slice.Len(); i++ {
+		value := slice.Index(i)
+		if (value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface) && value.IsNil() {
+			continue
+		}
+		sources = append(sources, value.Interface())
+		indices = append(indices, i)
+	}
+
+	var batches []map[string]interface{}
+	for _, selection := range selections {
+		field, ok := typ.Fields[selection.Name]
+		if !ok || field.BatchResolver == nil || len(sources) == 0 {
+			continue
+		}
+
+		fieldCtx := withPathSegment(ctx, selection.Alias)
+		result, err := safeExecuteResolver(fieldCtx, e.applyFieldMiddleware(typ.Name, field, selection, func(ctx context.Context) (interface{}, error) {
+			return field.BatchResolver(ctx, sources, selection.Args, selection.SelectionSet)
+		}))
+
+		values, _ := result.([]interface{})
+		if err != nil {
+			if !e.recoverField(fieldCtx, err) {
+				return nil, jerrors.NestErrorPaths(err, selection.Alias)
+			}
+			values = make([]interface{}, len(sources))
+		} else if len(values) != len(sources) {
+			return nil, fmt.Errorf("batch resolver of %s returned %d values for %d sources", selection.Name, len(values), len(sources))
+		}
+
+		if batches == nil {
+			batches = make([]map[string]interface{}, slice.Len())
+		}
+		for i, index := range indices {
+			if batches[index] == nil {
+				batches[index] = make(map[string]interface{})
+			}
+			batches[index][selection.Alias] = values[i]
+		}
+	}
+
+	return batches, nil
+}
+
 // executeInterface resolves an interface query
 func (e *Executor) executeInterface(ctx context.Context, typ *Interface, source interface{}, selectionSet *SelectionSet) (interface{}, error) {
 	value := reflect.ValueOf(source)
-	if value.Kind() == reflect.Ptr && value.IsNil() {
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
 		return nil, nil
 	}
-	fields := make(map[string]interface{})
-	var possibleTypes []string
+
+	if typ.ResolveType != nil {
+		if member := typ.ResolveType(source); member != nil {
+			return e.executeAbstract(ctx, typ.Name, member, source, selectionSet)
+		}
+	}
+	if reflect.Indirect(value).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("interface type %s has no member type for %T", typ.Name, source)
+	}
+
+	fields := ResponseObject{}
 	for typString, graphqlTyp := range typ.Types {
 		inner := reflect.ValueOf(source)
 		if inner.Kind() == reflect.Ptr && inner.Elem().Kind() == reflect.Struct {
@@ -255,43 +939,14 @@ func (e *Executor) executeInterface(ctx context.Context, typ *Interface, source
 		if inner.IsNil() {
 			continue
 		}
-		possibleTypes = append(possibleTypes, graphqlTyp.String())
 
-		// modifiedSelectionSet selection set contains fragments on typString
-		modifiedSelectionSet := &SelectionSet{
-			Selections: selectionSet.Selections,
-			Fragments:  []*FragmentSpread{},
-		}
-		for _, f := range selectionSet.Fragments {
-			if f.Fragment.On == typString {
-				modifiedSelectionSet.Fragments = append(modifiedSelectionSet.Fragments, f)
-			}
-		}
-
-		selections, err := Flatten(modifiedSelectionSet)
+		// Resolve the fields against the concrete value held by the interface.
+		resolved, err := e.executeAbstract(ctx, typ.Name, graphqlTyp, inner.Interface(), selectionSet)
 		if err != nil {
 			return nil, err
 		}
-		// for every selection, resolve the value and store it in the output object
-		for _, selection := range selections {
-			if selection.Name == "__typename" {
-				fields[selection.Alias] = graphqlTyp.Name
-				continue
-			}
-			field, ok := graphqlTyp.Fields[selection.Name]
-			if !ok {
-				continue
-			}
-			value := reflect.ValueOf(source).Elem()
-			value = value.FieldByName(typString)
-			resolved, err := e.resolveAndExecute(ctx, field, value.Interface(), selection)
-			if err != nil {
-				if err == ErrNoUpdate {
-					return nil, err
-				}
-				return nil, jerrors.NestErrorPaths(err, selection.Alias)
-			}
-			fields[selection.Alias] = resolved
+		for _, field := range resolved {
+			fields = fields.set(field.Key, field.Value)
 		}
 	}
 
@@ -350,12 +1005,23 @@ func (e *Executor) lateExecution(ctx context.Context, response interface{}) erro
 		}
 	}
 
-	data, ok := response.(map[string]interface{})
+	data, ok := response.(ResponseObject)
 	if !ok {
 		return nil
 	}
 
-	for key, value := range data {
+	for i, field := range data {
+		value := field.Value
+		if list, ok := value.(*computationList); ok {
+			resolved, err := e.resolveAndExecuteFunctionList(ctx, list)
+			if err != nil {
+				return err
+			}
+
+			data[i].Value = resolved
+			continue
+		}
+
 		output, ok := value.(*computationOutput)
 		if !ok {
 			if err := e.lateExecution(ctx, value); err != nil {
@@ -369,17 +1035,135 @@ func (e *Executor) lateExecution(ctx context.Context, response interface{}) erro
 			return err
 		}
 
-		data[key] = resolved
+		data[i].Value = resolved
 	}
 
 	return nil
 }
 
 func (e *Executor) resolveAndExecuteFunction(ctx context.Context, output *computationOutput) (interface{}, error) {
-	value, err := output.Field.LazyResolver(ctx, output.Function)
+	ctx = context.WithValue(ctx, fieldPathKey, output.path)
+	value, err := safeExecuteLazyResolver(ctx, output.Field, output.Function)
 	if err != nil {
+		if e.recoverField(ctx, err) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
-	return e.execute(ctx, output.Field.Type, value, output.Selection.SelectionSet)
+	resolved, err := e.execute(ctx, output.Field.Type, value, output.Selection.SelectionSet)
+	if err != nil && e.recoverNullField(ctx, output.Field.Type, err) {
+		return nil, nil
+	}
+	return resolved, err
+}
+
+// resolveAndExecuteFunctionList calls the functions of a lazy list concurrently and executes
+// the resulting values in order.
+func (e *Executor) resolveAndExecuteFunctionList(ctx context.Context, list *computationList) (interface{}, error) {
+	functions := reflect.ValueOf(list.Functions)
+	if !functions.IsValid() || functions.IsNil() {
+		return emptyList, nil
+	}
+	ctx = context.WithValue(ctx, fieldPathKey, list.path)
+
+	typ := list.Field.Type
+	if nonNull, ok := typ.(*NonNull); ok {
+		typ = nonNull.Type
+	}
+	listTyp := typ.(*List)
+
+	values := make([]interface{}, functions.Len())
+	errs := make([]error, functions.Len())
+
+	// The functions are called concurrently, within the limit of MaxConcurrency if it is set,
+	// and in order once the limit is reached.
+	bounded := e.sem != nil
+	var wg sync.WaitGroup
+	for i := 0; i < functions.Len(); i++ {
+		if bounded && !e.acquire() {
+			values[i], errs[i] = safeExecuteLazyResolver(withPathSegment(ctx, i), list.Field, functions.Index(i).Interface())
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if bounded {
+				defer e.release()
+			}
+			values[i], errs[i] = safeExecuteLazyResolver(withPathSegment(ctx, i), list.Field, functions.Index(i).Interface())
+		}(i)
+	}
+	wg.Wait()
+
+	items := make([]interface{}, 0, len(values))
+	for i, value := range values {
+		if errs[i] != nil {
+			if e.recoverField(withPathSegment(ctx, i), errs[i]) {
+				items = append(items, nil)
+				continue
+			}
+			return nil, jerrors.NestErrorPaths(errs[i], fmt.Sprint(i))
+		}
+
+		resolved, err := e.execute(withPathSegment(ctx, i), listTyp.Type, value, list.Selection.SelectionSet)
+		if err != nil {
+			if err == ErrNoUpdate {
+				return nil, err
+			}
+			if !e.recoverNullField(withPathSegment(ctx, i), listTyp.Type, err) {
+				return nil, nestErrorPath(err, fmt.Sprint(i))
+			}
+		}
+		if resolved == nil && listTyp.DropNulls {
+			continue
+		}
+		items = append(items, resolved)
+	}
+
+	return items, nil
+}
+
+func safeExecuteLazyResolver(ctx context.Context, field *Field, fun interface{}) (result interface{}, err error) {
+	defer func() {
+		if panicErr := recover(); panicErr != nil {
+			result, err = nil, newResolverPanic(panicErr)
+		}
+	}()
+	return field.LazyResolver(ctx, fun)
+}
+
+type fieldPathKeyType int
+
+const fieldPathKey fieldPathKeyType = 0
+
+// pathSegment is a single response key or list index in the path of the field being
+// resolved. Segments are linked to their parent so that descending is cheap.
+type pathSegment struct {
+	parent *pathSegment
+	key    interface{}
+}
+
+func withPathSegment(ctx context.Context, key interface{}) context.Context {
+	return context.WithValue(ctx, fieldPathKey, &pathSegment{parent: pathFromContext(ctx), key: key})
+}
+
+func pathFromContext(ctx context.Context) *pathSegment {
+	segment, _ := ctx.Value(fieldPathKey).(*pathSegment)
+	return segment
+}
+
+// FieldPathFromContext returns the path to the field being resolved, made up of the
+// response keys of fields (string) and the indices of list elements (int).
+func FieldPathFromContext(ctx context.Context) []interface{} {
+	var path []interface{}
+	for segment := pathFromContext(ctx); segment != nil; segment = segment.parent {
+		path = append(path, segment.key)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
 }
@@ -217,11 +217,17 @@ func TestError(t *testing.T) {
 		t.Error(err)
 	}
 
+	// The error field is nullable, so that it resolves to null along with its error.
 	e := graphql.Executor{}
-	if _, err := e.Execute(context.Background(), query, nil, q); err == nil ||
-		!reflect.DeepEqual(err, &jerrors.Error{Message: "test error", Paths: []string{"error"}, Extensions: &jerrors.Extension{Code: codes.Unknown.String()}}) {
+	val, err := e.Execute(context.Background(), query, nil, q)
+	if !reflect.DeepEqual(err, &jerrors.MultiError{Errors: []*jerrors.Error{
+		{Message: "test error", Paths: []string{"error"}, Extensions: &jerrors.Extension{Code: codes.Unknown.String()}},
+	}}) {
 		t.Error("expected test error")
 	}
+	if !reflect.DeepEqual(internal.AsJSON(val), map[string]interface{}{"error": nil}) {
+		t.Errorf("expected null error field, received %v", val)
+	}
 }
 
 func TestErrorCases(t *testing.T) {
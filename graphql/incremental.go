@@ -0,0 +1,112 @@
+package graphql
+
+import (
+	"context"
+
+	"go.appointy.com/jaal/jerrors"
+)
+
+// Patch is the result of a fragment marked with @defer, delivered after the initial response.
+type Patch struct {
+	// Path is the path to the object the fragment was spread on.
+	Path  []interface{}
+	Label string
+	Data  interface{}
+	Err   error
+}
+
+// Deferred executes a fragment marked with @defer. It is called once the initial response
+// has been delivered.
+type Deferred func(ctx context.Context) *Patch
+
+// ExecuteIncremental executes the query like Execute, except that the fragments spread on
+// objects with the @defer directive are left out of the returned response. Instead, each of
+// them is returned as a Deferred executing the fragment. Fragments marked with @defer within
+// a deferred fragment are executed with it.
+func (e *Executor) ExecuteIncremental(ctx context.Context, typ Type, source interface{}, query *Query) (interface{}, []Deferred, error) {
+	inc := e.fork()
+	inc.deferring = true
+
+	response, err := inc.Execute(ctx, typ, source, query)
+	if response == nil {
+		return nil, nil, err
+	}
+
+	return response, inc.deferred, err
+}
+
+// deferFragments removes the fragments marked with @defer from selectionSet, and records
+// them to be executed on source later.
+func (e *Executor) deferFragments(ctx context.Context, typ *Object, source interface{}, selectionSet *SelectionSet) *SelectionSet {
+	if !e.deferring || selectionSet == nil {
+		return selectionSet
+	}
+
+	var fragments []*FragmentSpread
+	for _, fragment := range selectionSet.Fragments {
+		if !typ.satisfies(fragment.Fragment.On) {
+			continue
+		}
+		directive := findDirectiveWithName(fragment.Directives, "defer")
+		if directive == nil || !shouldDefer(directive) {
+			fragments = append(fragments, fragment)
+			continue
+		}
+		if ok, err := shouldIncludeNode(fragment.Directives); err == nil && !ok {
+			continue
+		}
+
+		e.deferred = append(e.deferred, e.deferFragment(ctx, typ, source, fragment, directive))
+	}
+
+	if len(fragments) == len(selectionSet.Fragments) {
+		return selectionSet
+	}
+	return &SelectionSet{
+		Selections: selectionSet.Selections,
+		Fragments:  fragments,
+	}
+}
+
+func (e *Executor) deferFragment(ctx context.Context, typ *Object, source interface{}, fragment *FragmentSpread, directive *Directive) Deferred {
+	path := pathFromContext(ctx)
+	label, _ := directive.Args.(map[string]interface{})["label"].(string)
+
+	return func(ctx context.Context) *Patch {
+		ctx = context.WithValue(ctx, fieldPathKey, path)
+		patch := &Patch{
+			Path:  FieldPathFromContext(ctx),
+			Label: label,
+		}
+
+		inner := e.fork()
+		data, err := inner.executeObject(ctx, typ, source, fragment.Fragment.SelectionSet)
+		for err == nil && inner.iterate {
+			inner.iterate = false
+			err = inner.lateExecution(ctx, data)
+		}
+
+		if err != nil {
+			patch.Err = rootError(err)
+			return patch
+		}
+
+		patch.Data = data
+		if len(inner.fieldErrors) > 0 {
+			patch.Err = &jerrors.MultiError{Errors: inner.fieldErrors}
+		}
+		return patch
+	}
+}
+
+// shouldDefer evaluates the optional if argument of a @defer directive.
+func shouldDefer(directive *Directive) bool {
+	args, _ := directive.Args.(map[string]interface{})
+	v, ok := args["if"]
+	if !ok {
+		return true
+	}
+
+	b, ok := v.(bool)
+	return !ok || b
+}
@@ -3,10 +3,15 @@ package graphql_test
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/internal"
 	"go.appointy.com/jaal/jerrors"
 	"go.appointy.com/jaal/schemabuilder"
 )
@@ -35,7 +40,8 @@ func TestLazyExecution(t *testing.T) {
 		}
 
 		e := graphql.Executor{}
-		return e.Execute(context.Background(), builtSchema.Query, nil, q)
+		result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(result), err
 	}
 
 	t.Run("Lazy execution of wand", func(t *testing.T) {
@@ -277,6 +283,289 @@ func TestLazyExecution(t *testing.T) {
 	})
 }
 
+func TestLazyListExecution(t *testing.T) {
+	s := getServer()
+	sb := schemabuilder.NewSchema()
+
+	registerWand(sb)
+	registerWizard(sb)
+
+	// The thunks record the wands and owners they computed, and how many of them run at once.
+	var mu sync.Mutex
+	var computed []string
+	var running, maxRunning int
+	reset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		computed, maxRunning = nil, 0
+	}
+	sb.Query().FieldFunc("wands", func(ctx context.Context) []func() (*wand, error) {
+		thunks := make([]func() (*wand, error), 0, len(s.wands))
+		for _, w := range s.wands {
+			w := w
+			thunks = append(thunks, func() (*wand, error) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				defer mu.Unlock()
+				running--
+				computed = append(computed, w.Id)
+				return w, nil
+			})
+		}
+		return thunks
+	})
+	wandObject, err := sb.GetObject("Wand", wand{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The owners of the wands are resolved once for all the wands of a list, as lazy lists.
+	wandObject.BatchFieldFunc("owners", func(ctx context.Context, wands []*wand) [][]func() (*wizard, error) {
+		ids := make([]string, 0, len(wands))
+		owners := make([][]func() (*wizard, error), 0, len(wands))
+		for _, in := range wands {
+			in := in
+			ids = append(ids, in.Id)
+
+			var thunks []func() (*wizard, error)
+			for _, w := range s.wizards {
+				if w.WandId != in.Id {
+					continue
+				}
+				w := w
+				thunks = append(thunks, func() (*wizard, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					computed = append(computed, in.Id+" owner "+w.Id)
+					return w, nil
+				})
+			}
+			owners = append(owners, thunks)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		computed = append(computed, "owners of "+strings.Join(ids, ","))
+		return owners
+	})
+	wandObject.BatchFieldFunc("firstOwner", func(wands []*wand) []func() *wizard {
+		owners := make([]func() *wizard, 0, len(wands))
+		for _, in := range wands {
+			in := in
+			owners = append(owners, func() *wizard {
+				mu.Lock()
+				defer mu.Unlock()
+				computed = append(computed, in.Id+" first owner")
+				for _, w := range s.wizards {
+					if w.WandId == in.Id {
+						return w
+					}
+				}
+				return nil
+			})
+		}
+		return owners
+	})
+	sb.Query().FieldFunc("allWands", func() []*wand {
+		return s.wands
+	})
+	// The thunks of concurrentWands only return once all of them have started, which they
+	// cannot do when they are called one after another.
+	sb.Query().FieldFunc("concurrentWands", func(ctx context.Context) []func() (*wand, error) {
+		var started sync.WaitGroup
+		started.Add(len(s.wands))
+		all := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(all)
+		}()
+
+		thunks := make([]func() (*wand, error), 0, len(s.wands))
+		for _, w := range s.wands {
+			w := w
+			thunks = append(thunks, func() (*wand, error) {
+				started.Done()
+				select {
+				case <-all:
+					return w, nil
+				case <-time.After(time.Second):
+					return nil, errors.New("elements were not computed concurrently")
+				}
+			})
+		}
+		return thunks
+	})
+	sb.Query().FieldFunc("brokenWands", func(ctx context.Context) []func() (*wand, error) {
+		return []func() (*wand, error){
+			func() (*wand, error) { return s.wands[0], nil },
+			func() (*wand, error) { return nil, errors.New("wand not found") },
+		}
+	})
+	sb.Query().FieldFunc("name", func() string {
+		return "wands"
+	})
+
+	builtSchema := sb.MustBuild()
+	executeWith := func(e *graphql.Executor, queryString string, vars map[string]interface{}) (interface{}, error) {
+		q, err := graphql.Parse(queryString, vars)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := graphql.ValidateQuery(context.Background(), builtSchema.Query, q.SelectionSet); err != nil {
+			return nil, err
+		}
+
+		result, err := e.Execute(context.Background(), builtSchema.Query, nil, q)
+		return internal.AsJSON(result), err
+	}
+	execute := func(queryString string, vars map[string]interface{}) (interface{}, error) {
+		return executeWith(&graphql.Executor{}, queryString, vars)
+	}
+	computedThunks := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		thunks := append([]string(nil), computed...)
+		sort.Strings(thunks)
+		return thunks
+	}
+
+	t.Run("Lazy list elements are resolved", func(t *testing.T) {
+		reset()
+		result, err := execute(`{ wands { core } }`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"wands": []interface{}{
+				map[string]interface{}{"core": "dragon"},
+				map[string]interface{}{"core": "phoenix"},
+				map[string]interface{}{"core": "serpent"},
+				map[string]interface{}{"core": "unicorn"},
+			},
+		}, result)
+		assert.Equal(t, []string{"wand1", "wand2", "wand3", "wand4"}, computedThunks())
+	})
+	t.Run("Lazy list elements are computed concurrently", func(t *testing.T) {
+		result, err := execute(`{ concurrentWands { id } }`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, result.(map[string]interface{})["concurrentWands"], 4)
+	})
+	t.Run("Only the selected branches of lazy list elements are computed", func(t *testing.T) {
+		const query = `query x { wands { core owners @skip(if: $skip) { name } } }`
+
+		reset()
+		result, err := execute(query, map[string]interface{}{"skip": false})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"wands": []interface{}{
+				map[string]interface{}{"core": "dragon", "owners": []interface{}{map[string]interface{}{"name": "Hermoine Granger"}}},
+				map[string]interface{}{"core": "phoenix", "owners": []interface{}{map[string]interface{}{"name": "Harry Potter"}}},
+				map[string]interface{}{"core": "serpent", "owners": []interface{}{map[string]interface{}{"name": "Draco Malfoy"}}},
+				map[string]interface{}{"core": "unicorn", "owners": []interface{}{map[string]interface{}{"name": "Ronald Weasley"}}},
+			},
+		}, result)
+		assert.Equal(t, []string{
+			"owners of wand1", "owners of wand2", "owners of wand3", "owners of wand4",
+			"wand1", "wand1 owner w3",
+			"wand2", "wand2 owner w1",
+			"wand3", "wand3 owner w2",
+			"wand4", "wand4 owner w4",
+		}, computedThunks())
+
+		// The owners of the wands are never computed when they are skipped, while the wands
+		// still are.
+		reset()
+		result, err = execute(query, map[string]interface{}{"skip": true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"wands": []interface{}{
+				map[string]interface{}{"core": "dragon"},
+				map[string]interface{}{"core": "phoenix"},
+				map[string]interface{}{"core": "serpent"},
+				map[string]interface{}{"core": "unicorn"},
+			},
+		}, result)
+		assert.Equal(t, []string{"wand1", "wand2", "wand3", "wand4"}, computedThunks())
+	})
+	t.Run("Only the selected lazy results of batch fields are computed", func(t *testing.T) {
+		const query = `query x { allWands { core ... @include(if: $owners) { owners { name } firstOwner { name } } } }`
+
+		reset()
+		result, err := execute(query, map[string]interface{}{"owners": true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"allWands": []interface{}{
+				map[string]interface{}{"core": "dragon", "owners": []interface{}{map[string]interface{}{"name": "Hermoine Granger"}}, "firstOwner": map[string]interface{}{"name": "Hermoine Granger"}},
+				map[string]interface{}{"core": "phoenix", "owners": []interface{}{map[string]interface{}{"name": "Harry Potter"}}, "firstOwner": map[string]interface{}{"name": "Harry Potter"}},
+				map[string]interface{}{"core": "serpent", "owners": []interface{}{map[string]interface{}{"name": "Draco Malfoy"}}, "firstOwner": map[string]interface{}{"name": "Draco Malfoy"}},
+				map[string]interface{}{"core": "unicorn", "owners": []interface{}{map[string]interface{}{"name": "Ronald Weasley"}}, "firstOwner": map[string]interface{}{"name": "Ronald Weasley"}},
+			},
+		}, result)
+		assert.Equal(t, []string{
+			"owners of wand1,wand2,wand3,wand4",
+			"wand1 first owner", "wand1 owner w3",
+			"wand2 first owner", "wand2 owner w1",
+			"wand3 first owner", "wand3 owner w2",
+			"wand4 first owner", "wand4 owner w4",
+		}, computedThunks())
+
+		reset()
+		result, err = execute(query, map[string]interface{}{"owners": false})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Len(t, result.(map[string]interface{})["allWands"], 4)
+		assert.Empty(t, computedThunks())
+	})
+	t.Run("Lazy list elements are computed within MaxConcurrency", func(t *testing.T) {
+		reset()
+		_, err := executeWith(&graphql.Executor{MaxConcurrency: 2}, `{ wands { core } }`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, []string{"wand1", "wand2", "wand3", "wand4"}, computedThunks())
+
+		// Two elements are computed concurrently, and the executor computes the next one itself
+		// while the limit is reached.
+		mu.Lock()
+		defer mu.Unlock()
+		assert.True(t, maxRunning <= 3, "expected at most 3 elements computed at once, but %d were", maxRunning)
+	})
+	t.Run("Error in lazy list element", func(t *testing.T) {
+		_, err := execute(`{ brokenWands { core } }`, nil)
+		assert.Equal(t, &jerrors.Error{
+			Message: "wand not found",
+			Paths:   []string{"1"},
+			Extensions: &jerrors.Extension{
+				Code: "Unknown",
+			},
+		}, err)
+	})
+}
+
 type wand struct {
 	Id   string
 	Core string
@@ -4,32 +4,51 @@ package graphql
 // and stores its output value in a more convenient format.
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/graphql-go/graphql/gqlerrors"
 	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
 	"github.com/graphql-go/graphql/language/parser"
 	"go.appointy.com/jaal/jerrors"
 )
 
 type Query struct {
-	Name string
-	Kind string
+	Name       string
+	Kind       string
+	Directives []*Directive
+
+	// Variables are the values of the variables of the operation, including the default
+	// values of the variables omitted from the request.
+	Variables map[string]interface{}
+	// DeclaredVariables are the names of the variables defined by the operation, in order.
+	DeclaredVariables []string
 	*SelectionSet
 }
 
 // Parse parses an input GraphQL string into a *Query
 //
-// Parse validates that the query looks syntactically correct and contains no cycles or unused fragments or immediate conflicts.
-// However, it does not validate that the query is legal under a given schema, which instead is done by ValidateQuery.
+// Parse validates that the query looks syntactically correct and contains no cycles or unused fragments.
+// However, it does not validate that the query is legal under a given schema, which instead is done by ValidateQuery,
+// including that the fields with the same alias can be merged.
 func Parse(source string, vars map[string]interface{}) (*Query, error) {
+	return ParseOperation(source, "", vars)
+}
+
+// ParseOperation parses the operation named operationName of an input GraphQL string into a *Query,
+// like Parse. The operation name must be provided when the string defines multiple operations, and
+// must match the name of the operation otherwise, if provided.
+func ParseOperation(source, operationName string, vars map[string]interface{}) (*Query, error) {
 	document, err := parser.Parse(parser.ParseParams{Source: source})
 	if err != nil {
-		return nil, err
+		return nil, syntaxError(err)
 	}
 
-	var queryDefinition *ast.OperationDefinition
+	var operations []*ast.OperationDefinition
 	fragmentDefinitions := make(map[string]*ast.FragmentDefinition)
 
 	for _, definition := range document.Definitions {
@@ -45,18 +64,16 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 			if definition.Operation != "query" && definition.Operation != "mutation" && definition.Operation != "subscription" {
 				return nil, fmt.Errorf("only supports queries, mutations and subscriptions")
 			}
-			if queryDefinition != nil {
-				return nil, fmt.Errorf("only support a single query")
-			}
-			queryDefinition = definition
+			operations = append(operations, definition)
 
 		default:
 			return nil, fmt.Errorf("unsupported definition")
 		}
 	}
 
-	if queryDefinition == nil {
-		return nil, fmt.Errorf("must have a single query")
+	queryDefinition, err := selectOperation(operations, operationName)
+	if err != nil {
+		return nil, err
 	}
 
 	kind := queryDefinition.Operation
@@ -73,8 +90,11 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 
 	// Parse variable definitions, default values, etc.
 	var defaultedVars map[string]interface{}
+	definitions := make(map[string]*ast.VariableDefinition)
 	for _, variableDefinition := range queryDefinition.VariableDefinitions {
 		name := variableDefinition.Variable.Name.Value
+		definitions[name] = variableDefinition
+		rv.DeclaredVariables = append(rv.DeclaredVariables, name)
 
 		if _, ok := variableDefinition.Type.(*ast.NonNull); ok {
 			if variableDefinition.DefaultValue != nil {
@@ -85,8 +105,8 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 		}
 
 		if variableDefinition.DefaultValue != nil {
-			// Ignore default if the value exists.
-			if vars[name] != nil {
+			// Ignore default if the value exists, even if it is an explicit null.
+			if _, ok := vars[name]; ok {
 				continue
 			}
 
@@ -110,6 +130,20 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	if defaultedVars != nil {
 		vars = defaultedVars
 	}
+	rv.Variables = vars
+
+	// The locations of directives in selection sets are checked by ValidateQuery, which
+	// does not see the operation itself.
+	directives, err := parseDirectives(queryDefinition.Directives, vars)
+	if err != nil {
+		return rv, err
+	}
+	if err := validateDirectives(directives, DirectiveLocation(strings.ToUpper(kind))); err != nil {
+		return rv, err
+	}
+	if len(directives) > 0 {
+		rv.Directives = directives
+	}
 
 	globalFragments := make(map[string]*FragmentDefinition)
 	for name, fragment := range fragmentDefinitions {
@@ -120,23 +154,20 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	}
 
 	for name, fragment := range fragmentDefinitions {
-		selectionSet, err := parseSelectionSet(fragment.SelectionSet, globalFragments, vars)
+		selectionSet, err := parseSelectionSet(fragment.SelectionSet, globalFragments, vars, definitions)
 		if err != nil {
 			return rv, err
 		}
 		globalFragments[name].SelectionSet = selectionSet
 	}
 
-	selectionSet, err := parseSelectionSet(queryDefinition.SelectionSet, globalFragments, vars)
+	selectionSet, err := parseSelectionSet(queryDefinition.SelectionSet, globalFragments, vars, definitions)
 	if err != nil {
 		return rv, err
 	}
 
-	if err := detectCyclesAndUnusedFragments(selectionSet, globalFragments); err != nil {
-		return rv, err
-	}
-
-	if err := detectConflicts(selectionSet); err != nil {
+	// The fragments of a document with multiple operations may only be used by the others.
+	if err := detectCyclesAndUnusedFragments(selectionSet, globalFragments, len(operations) == 1); err != nil {
 		return rv, err
 	}
 
@@ -145,6 +176,47 @@ func Parse(source string, vars map[string]interface{}) (*Query, error) {
 	return rv, nil
 }
 
+// selectOperation returns the operation named operationName among operations, or the only
+// operation if there is a single one and operationName is empty or its name.
+func selectOperation(operations []*ast.OperationDefinition, operationName string) (*ast.OperationDefinition, error) {
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("must have a single query")
+	}
+
+	if len(operations) == 1 {
+		var name string
+		if operations[0].Name != nil {
+			name = operations[0].Name.Value
+		}
+		if operationName != "" && operationName != name {
+			return nil, fmt.Errorf("unknown operation named %q", operationName)
+		}
+		return operations[0], nil
+	}
+
+	names := make(map[string]bool)
+	for _, operation := range operations {
+		// An anonymous operation must be the only operation of the document.
+		if operation.Name == nil {
+			return nil, fmt.Errorf("only support a single query")
+		}
+		if names[operation.Name.Value] {
+			return nil, fmt.Errorf("duplicate operation named %q", operation.Name.Value)
+		}
+		names[operation.Name.Value] = true
+	}
+
+	if operationName == "" {
+		return nil, fmt.Errorf("must provide operation name")
+	}
+	for _, operation := range operations {
+		if operation.Name.Value == operationName {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation named %q", operationName)
+}
+
 // valueToJson takes a graphql-go ast value and converts it to a value like those generated by json.Unmarshal
 func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, error) {
 	switch value := value.(type) {
@@ -203,7 +275,7 @@ func valueToJson(value ast.Value, vars map[string]interface{}) (interface{}, err
 }
 
 // parseSelectionSet takes a grapqhl-go selection set and converts it to a simplified *SelectionSet, bindings vars
-func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*FragmentDefinition, vars map[string]interface{}) (*SelectionSet, error) {
+func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*FragmentDefinition, vars map[string]interface{}, definitions map[string]*ast.VariableDefinition) (*SelectionSet, error) {
 	if input == nil {
 		return nil, nil
 	}
@@ -228,7 +300,7 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				return nil, err
 			}
 
-			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars)
+			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars, definitions)
 			if err != nil {
 				return nil, err
 			}
@@ -239,6 +311,8 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 				Args:         args,
 				SelectionSet: selectionSet,
 				Directives:   directives,
+				variables:    variableUsages(selection.Arguments, definitions),
+				location:     sourceLocation(selection.Loc),
 			})
 
 		case *ast.FragmentSpread:
@@ -262,14 +336,17 @@ func parseSelectionSet(input *ast.SelectionSet, globalFragments map[string]*Frag
 			fragments = append(fragments, fragmentSpread)
 
 		case *ast.InlineFragment:
-			on := selection.TypeCondition.Name.Value
+			var on string
+			if selection.TypeCondition != nil {
+				on = selection.TypeCondition.Name.Value
+			}
 
 			directives, err := parseDirectives(selection.Directives, vars)
 			if err != nil {
 				return nil, err
 			}
 
-			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars)
+			selectionSet, err := parseSelectionSet(selection.SelectionSet, globalFragments, vars, definitions)
 			if err != nil {
 				return nil, err
 			}
@@ -308,6 +385,79 @@ func argsToJson(input []*ast.Argument, vars map[string]interface{}) (interface{}
 	return args, nil
 }
 
+// sourceLocation returns the line and column of the start of loc in the query, or the zero
+// location if it is unknown.
+func sourceLocation(loc *ast.Location) jerrors.Location {
+	if loc == nil || loc.Source == nil {
+		return jerrors.Location{}
+	}
+	l := location.GetLocation(loc.Source, loc.Start)
+	return jerrors.Location{Line: l.Line, Column: l.Column}
+}
+
+// syntaxError reports the syntax error err of the parser at its locations in the query.
+func syntaxError(err error) error {
+	gqlErr, ok := err.(*gqlerrors.Error)
+	if !ok || len(gqlErr.Locations) == 0 {
+		return err
+	}
+	located := &locatedError{err: err}
+	for _, l := range gqlErr.Locations {
+		located.locations = append(located.locations, jerrors.Location{Line: l.Line, Column: l.Column})
+	}
+	return located
+}
+
+// locatedError is an error reported at locations in the query.
+type locatedError struct {
+	err       error
+	locations []jerrors.Location
+}
+
+func (e *locatedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *locatedError) Unwrap() error {
+	return e.err
+}
+
+// Locations returns the locations of the error, read by jerrors.ConvertError.
+func (e *locatedError) Locations() []jerrors.Location {
+	return e.locations
+}
+
+// locate reports err, returned by the validation of selection, at the location of selection,
+// unless it already has a location within selection.
+func locate(err error, selection *Selection) error {
+	var located *locatedError
+	if err == nil || selection.location == (jerrors.Location{}) || errors.As(err, &located) {
+		return err
+	}
+	return &locatedError{err: err, locations: []jerrors.Location{selection.location}}
+}
+
+// variableUsages returns the declared variables passed as arguments in input, by argument name.
+func variableUsages(input []*ast.Argument, definitions map[string]*ast.VariableDefinition) map[string]*ast.VariableDefinition {
+	var usages map[string]*ast.VariableDefinition
+	for _, arg := range input {
+		variable, ok := arg.Value.(*ast.Variable)
+		if !ok {
+			continue
+		}
+		definition, ok := definitions[variable.Name.Value]
+		if !ok {
+			continue
+		}
+
+		if usages == nil {
+			usages = make(map[string]*ast.VariableDefinition)
+		}
+		usages[arg.Name.Value] = definition
+	}
+	return usages
+}
+
 type visitState int
 
 const (
@@ -332,8 +482,9 @@ func parseDirectives(directives []*ast.Directive, vars map[string]interface{}) (
 	return d, nil
 }
 
-// detectCyclesAndUnusedFragments finds cycles in fragments that include eachother as well as fragments that don't appear anywhere
-func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments map[string]*FragmentDefinition) error {
+// detectCyclesAndUnusedFragments finds cycles in fragments that include eachother as well as, if checkUnused
+// is set, fragments that don't appear anywhere
+func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments map[string]*FragmentDefinition, checkUnused bool) error {
 	state := make(map[*FragmentDefinition]visitState)
 
 	var visitFragment func(spread *FragmentSpread) error
@@ -379,6 +530,9 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 	if err := visitSelectionSet(selectionSet); err != nil {
 		return err
 	}
+	if !checkUnused {
+		return nil
+	}
 
 	for _, fragment := range globalFragments {
 		if state[fragment] != visited {
@@ -388,61 +542,6 @@ func detectCyclesAndUnusedFragments(selectionSet *SelectionSet, globalFragments
 	return nil
 }
 
-// detectConflicts finds conflicts
-//
-// Conflicts are selections that can not be merged, for example
-//
-//     foo: bar(id: 123)
-//     foo: baz(id: 456)
-//
-// A query cannot contain both selections, because they have the same alias
-// with different source names, and they also have different arguments.
-func detectConflicts(selectionSet *SelectionSet) error {
-	state := make(map[*SelectionSet]visitState)
-
-	var visitChild func(*SelectionSet) error
-	visitChild = func(selectionSet *SelectionSet) error {
-		if state[selectionSet] == visited {
-			return nil
-		}
-		state[selectionSet] = visited
-
-		selections := make(map[string]*Selection)
-
-		var visitSibling func(*SelectionSet) error
-		visitSibling = func(selectionSet *SelectionSet) error {
-			for _, selection := range selectionSet.Selections {
-				if other, found := selections[selection.Alias]; found {
-					if other.Name != selection.Name {
-						return fmt.Errorf("same alias with different name")
-					}
-					if !reflect.DeepEqual(other.Args, selection.Args) {
-						return fmt.Errorf("same alias with different args")
-					}
-				} else {
-					selections[selection.Alias] = selection
-				}
-			}
-
-			for _, fragment := range selectionSet.Fragments {
-				if err := visitSibling(fragment.Fragment.SelectionSet); err != nil {
-					return err
-				}
-			}
-
-			return nil
-		}
-
-		if err := visitSibling(selectionSet); err != nil {
-			return err
-		}
-
-		return nil
-	}
-
-	return visitChild(selectionSet)
-}
-
 // Flatten takes a SelectionSet and flattens it into an array of selections
 // with unique aliases
 //
@@ -462,8 +561,19 @@ func detectConflicts(selectionSet *SelectionSet) error {
 //
 // Flatten does _not_ flatten out the inner queries, so the name above does not
 // get flattened out yet.
+//
+// The flattened selections are ordered by the first occurrence of their alias
+// in the selection set, so they are executed in the order they were requested.
+// The fields and fragments excluded by @skip or @include are left out.
 func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
+	return flatten(selectionSet, func(string) bool { return true })
+}
+
+// flatten flattens selectionSet like Flatten, skipping the fragments whose type condition
+// doesn't satisfy applies.
+func flatten(selectionSet *SelectionSet, applies func(on string) bool) ([]*Selection, error) {
 	grouped := make(map[string][]*Selection)
+	var aliases []string
 
 	state := make(map[*SelectionSet]visitState)
 	var visit func(*SelectionSet) error
@@ -473,9 +583,22 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 		}
 
 		for _, selection := range selectionSet.Selections {
+			// A field is only selected by its occurrences which are not skipped, so the
+			// directives are applied before the occurrences are merged.
+			if ok, err := shouldIncludeNode(selection.Directives); err != nil {
+				return jerrors.NestErrorPaths(err, selection.Alias)
+			} else if !ok {
+				continue
+			}
+			if _, ok := grouped[selection.Alias]; !ok {
+				aliases = append(aliases, selection.Alias)
+			}
 			grouped[selection.Alias] = append(grouped[selection.Alias], selection)
 		}
 		for _, fragment := range selectionSet.Fragments {
+			if !applies(fragment.Fragment.On) {
+				continue
+			}
 			if ok, err := shouldIncludeNode(fragment.Directives); err != nil {
 				return jerrors.NestErrorPaths(err, fragment.Fragment.Name)
 
@@ -497,25 +620,52 @@ func Flatten(selectionSet *SelectionSet) ([]*Selection, error) {
 	}
 
 	var flattened []*Selection
-	for _, selections := range grouped {
-		if len(selections) == 1 || selections[0].SelectionSet == nil {
+	for _, alias := range aliases {
+		selections := grouped[alias]
+		if len(selections) == 1 {
 			flattened = append(flattened, selections[0])
 			continue
 		}
 
-		merged := &SelectionSet{}
-		for _, selection := range selections {
-			merged.Selections = append(merged.Selections, selection.SelectionSet.Selections...)
-			merged.Fragments = append(merged.Fragments, selection.SelectionSet.Fragments...)
+		// The directives of every occurrence apply to the merged field, so that repeating a
+		// field cannot drop the directives of another of its occurrences.
+		merged := &Selection{
+			Name:       selections[0].Name,
+			Alias:      selections[0].Alias,
+			Args:       selections[0].Args,
+			Directives: mergeDirectives(selections),
+
+			deprecatedArgs:        selections[0].deprecatedArgs,
+			deprecatedInputFields: selections[0].deprecatedInputFields,
+		}
+		if selections[0].SelectionSet != nil {
+			merged.SelectionSet = &SelectionSet{}
+			for _, selection := range selections {
+				merged.SelectionSet.Selections = append(merged.SelectionSet.Selections, selection.SelectionSet.Selections...)
+				merged.SelectionSet.Fragments = append(merged.SelectionSet.Fragments, selection.SelectionSet.Fragments...)
+			}
 		}
 
-		flattened = append(flattened, &Selection{
-			Name:         selections[0].Name,
-			Alias:        selections[0].Alias,
-			Args:         selections[0].Args,
-			SelectionSet: merged,
-		})
+		flattened = append(flattened, merged)
 	}
 
 	return flattened, nil
 }
+
+// mergeDirectives returns the directives of selections in order, leaving out the directives
+// repeated with the same args by several of them.
+func mergeDirectives(selections []*Selection) []*Directive {
+	var directives []*Directive
+	for _, selection := range selections {
+	directive:
+		for _, directive := range selection.Directives {
+			for _, other := range directives {
+				if directive.Name == other.Name && reflect.DeepEqual(directive.Args, other.Args) {
+					continue directive
+				}
+			}
+			directives = append(directives, directive)
+		}
+	}
+	return directives
+}
@@ -36,8 +36,9 @@ fragment Bar on Foo {
 	}
 
 	expected := &Query{
-		Name: "",
-		Kind: "query",
+		Name:      "",
+		Kind:      "query",
+		Variables: map[string]interface{}{"var": "var value!!"},
 		SelectionSet: &SelectionSet{
 			Selections: []*Selection{
 				{
@@ -164,8 +165,10 @@ mutation foo($var: bar) {
 	}
 
 	expected = &Query{
-		Name: "foo",
-		Kind: "mutation",
+		Name:              "foo",
+		Kind:              "mutation",
+		Variables:         map[string]interface{}{"var": "var value!!"},
+		DeclaredVariables: []string{"var"},
 		SelectionSet: &SelectionSet{
 			Selections: []*Selection{
 				{
@@ -177,7 +180,11 @@ mutation foo($var: bar) {
 			},
 		},
 	}
-	if !reflect.DeepEqual(query, expected) {
+	// The selections are compared as JSON, leaving out their locations in the query.
+	got, _ = json.Marshal(query)
+	exp, _ = json.Marshal(expected)
+	if !reflect.DeepEqual(string(got), string(exp)) {
+		t.Logf("\ngot : %v \n\n expected %v\n\n", string(got), string(exp))
 		t.Error("unexpected parse")
 	}
 }
@@ -200,35 +207,6 @@ func TestParseUnsupported(t *testing.T) {
 		t.Error("expected multiple queries to fail", err)
 	}
 
-	_, err = Parse(`
-{
-	b(a: 1)
-	b(a: 2)
-}`, map[string]interface{}{})
-	if err == nil || err.Error() != "same alias with different args" {
-		t.Error("expected different args to fail", err)
-	}
-
-	_, err = Parse(`
-{
-	a: a
-	a: b
-}`, map[string]interface{}{})
-	if err == nil || err.Error() != "same alias with different name" {
-		t.Error("expected different names to fail", err)
-	}
-
-	_, err = Parse(`
-{
-	a: a
-	... on Foo {
-		a: b
-	}
-}`, map[string]interface{}{})
-	if err == nil || err.Error() != "same alias with different name" {
-		t.Error("expected different names in fragment to fail", err)
-	}
-
 	_, err = Parse(`
 {
 	a(x: 1, x: 1)
@@ -260,6 +238,49 @@ fragment foo on Foo {
 	}
 }
 
+func TestParseOperation(t *testing.T) {
+	source := `
+query A {
+	...frag
+}
+
+query B {
+	baz
+}
+
+fragment frag on Query {
+	bar
+}`
+
+	query, err := ParseOperation(source, "B", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Name != "B" || len(query.SelectionSet.Selections) != 1 || query.SelectionSet.Selections[0].Name != "baz" {
+		t.Errorf("expected operation B to be selected, but received %v", query)
+	}
+
+	_, err = ParseOperation(source, "", map[string]interface{}{})
+	if err == nil || err.Error() != "must provide operation name" {
+		t.Error("expected missing operation name to fail", err)
+	}
+
+	_, err = ParseOperation(source, "C", map[string]interface{}{})
+	if err == nil || err.Error() != `unknown operation named "C"` {
+		t.Error("expected unknown operation name to fail", err)
+	}
+
+	_, err = ParseOperation(`{ bar }`, "A", map[string]interface{}{})
+	if err == nil || err.Error() != `unknown operation named "A"` {
+		t.Error("expected mismatched operation name to fail", err)
+	}
+
+	query, err = ParseOperation(`query A { bar }`, "A", map[string]interface{}{})
+	if err != nil || query.Name != "A" {
+		t.Error("expected matching operation name to succeed", err)
+	}
+}
+
 func TestParseRequiredVariableDefinitionWithDefaultValue(t *testing.T) {
 	// Expect required variables to be provided.
 	_, err := Parse(`
@@ -294,6 +315,24 @@ query Operation($x: int64 = 2) {
 	}
 }
 
+func TestParseExplicitNullOverridesDefaultValue(t *testing.T) {
+	query, err := Parse(`
+query Operation($x: Int = 2) {
+	field(x: $x)
+}	`, map[string]interface{}{"x": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := query.SelectionSet.Selections[0].Args.(map[string]interface{})
+	if val, ok := args["x"]; !ok || val != nil {
+		t.Errorf("expected explicit null, received %v", val)
+	}
+	if val, ok := query.Variables["x"]; !ok || val != nil {
+		t.Errorf("expected explicit null variable, received %v", val)
+	}
+}
+
 func TestSkippedFragment(t *testing.T) {
 	_, err := Parse(`query Test($something: bool) {
 		something @skip(if: $something) {
@@ -306,3 +345,33 @@ func TestSkippedFragment(t *testing.T) {
 		t.Errorf("expected no error, received %s", err.Error())
 	}
 }
+
+func TestFlattenOrder(t *testing.T) {
+	query, err := Parse(`{
+		b
+		a: c
+		... on Query { d b }
+		c
+		... Frag
+	}
+	fragment Frag on Query { e a: c }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		selections, err := Flatten(query.SelectionSet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var aliases []string
+		for _, selection := range selections {
+			aliases = append(aliases, selection.Alias)
+		}
+
+		if expected := []string{"b", "a", "c", "d", "e"}; !reflect.DeepEqual(aliases, expected) {
+			t.Fatalf("expected %v, received %v", expected, aliases)
+		}
+	}
+}
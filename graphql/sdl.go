@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CollectTypes adds typ, and the types reachable from its fields, arguments and members, to
+// types, by name.
+func CollectTypes(typ Type, types map[string]Type) {
+	switch typ := typ.(type) {
+	case *Object:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+
+		for _, field := range typ.Fields {
+			CollectTypes(field.Type, types)
+
+			for _, arg := range field.Args {
+				CollectTypes(arg, types)
+			}
+		}
+
+	case *Union:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+		for _, graphqlTyp := range typ.Types {
+			CollectTypes(graphqlTyp, types)
+		}
+
+	case *Interface:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+
+		for _, field := range typ.Fields {
+			CollectTypes(field.Type, types)
+
+			for _, arg := range field.Args {
+				CollectTypes(arg, types)
+			}
+		}
+		for _, object := range typ.Types {
+			CollectTypes(object, types)
+		}
+
+	case *List:
+		CollectTypes(typ.Type, types)
+
+	case *Scalar:
+		if _, ok := types[typ.Type]; ok {
+			return
+		}
+		types[typ.Type] = typ
+
+	case *Enum:
+		if _, ok := types[typ.Type]; ok {
+			return
+		}
+		types[typ.Type] = typ
+
+	case *InputObject:
+		if _, ok := types[typ.Name]; ok {
+			return
+		}
+		types[typ.Name] = typ
+
+		for _, field := range typ.InputFields {
+			CollectTypes(field, types)
+		}
+
+	case *NonNull:
+		CollectTypes(typ.Type, types)
+	}
+}
+
+// builtinScalars are the scalars defined by the spec, which are left out of the SDL of a schema.
+var builtinScalars = map[string]bool{
+	"Int":     true,
+	"Float":   true,
+	"String":  true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+// PrintSchema prints the SDL of the custom directives of schema and of the types reachable from
+// its roots. The directives, types, fields, arguments and values are sorted by name, so that the
+// SDL of a schema is stable and can be diffed.
+func PrintSchema(schema *Schema) string {
+	types := schema.types()
+
+	names := make([]string, 0, len(schema.Directives))
+	for name := range schema.Directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var definitions []string
+	for _, name := range names {
+		definitions = append(definitions, printDirective(schema.Directives[name]))
+	}
+	if sdl := PrintTypes(types); sdl != "" {
+		definitions = append(definitions, sdl)
+	}
+	return strings.Join(definitions, "\n")
+}
+
+// PrintTypes prints the SDL of types, sorted by name. The scalars defined by the spec, the
+// introspection types and fields, and the objects without fields, such as a Mutation without
+// any mutation, are left out.
+func PrintTypes(types map[string]Type) string {
+	var definitions []string
+	for _, name := range sortedKeys(types) {
+		if builtinScalars[name] || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		typ := types[name]
+		if object, ok := typ.(*Object); ok {
+			fields := make(map[string]*Field, len(object.Fields))
+			for fieldName, field := range object.Fields {
+				if !strings.HasPrefix(fieldName, "__") {
+					fields[fieldName] = field
+				}
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			copied := *object
+			copied.Fields = fields
+			typ = &copied
+		}
+
+		definitions = append(definitions, PrintType(typ))
+	}
+	return strings.Join(definitions, "\n")
+}
+
+// printDirective prints the definition of a custom directive.
+func printDirective(directive *DirectiveDefinition) string {
+	var b strings.Builder
+	b.WriteString("directive @" + directive.Name)
+	if len(directive.Args) > 0 {
+		var args []string
+		for _, arg := range sortedKeys(directive.Args) {
+			args = append(args, fmt.Sprintf("%s: %s", arg, directive.Args[arg]))
+		}
+		fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+	}
+
+	locations := make([]string, 0, len(directive.Locations))
+	for _, location := range directive.Locations {
+		locations = append(locations, string(location))
+	}
+	if directive.Repeatable {
+		b.WriteString(" repeatable")
+	}
+	fmt.Fprintf(&b, " on %s\n", strings.Join(locations, " | "))
+	return b.String()
+}
+
+// PrintType prints the definition of typ in a normalized, SDL-like form, sorting its fields,
+// arguments and values.
+func PrintType(typ Type) string {
+	var b strings.Builder
+
+	switch typ := typ.(type) {
+	case *Scalar:
+		fmt.Fprintf(&b, "scalar %s", typ.Type)
+		if typ.SpecifiedByURL != "" {
+			fmt.Fprintf(&b, " @specifiedBy(url: %q)", typ.SpecifiedByURL)
+		}
+		b.WriteString("\n")
+
+	case *Enum:
+		values := append([]string(nil), typ.Values...)
+		sort.Strings(values)
+		fmt.Fprintf(&b, "enum %s {\n", typ.Type)
+		for _, value := range values {
+			fmt.Fprintf(&b, "  %s\n", value)
+		}
+		b.WriteString("}\n")
+
+	case *Object:
+		fmt.Fprintf(&b, "type %s", typ.Name)
+		if len(typ.Interfaces) > 0 {
+			fmt.Fprintf(&b, " implements %s", strings.Join(sortedKeys(typ.Interfaces), " & "))
+		}
+		if typ.FederationKey != "" {
+			fmt.Fprintf(&b, " @key(fields: %q)", typ.FederationKey)
+		}
+		b.WriteString(" {\n")
+		printFields(&b, typ.Fields)
+		b.WriteString("}\n")
+
+	case *Interface:
+		fmt.Fprintf(&b, "interface %s {\n", typ.Name)
+		printFields(&b, typ.Fields)
+		b.WriteString("}\n")
+
+	case *Union:
+		fmt.Fprintf(&b, "union %s = %s\n", typ.Name, strings.Join(sortedKeys(typ.Types), " | "))
+
+	case *InputObject:
+		fmt.Fprintf(&b, "input %s {\n", typ.Name)
+		for _, name := range sortedKeys(typ.InputFields) {
+			fmt.Fprintf(&b, "  %s: %s", name, typ.InputFields[name])
+			if reason, ok := typ.DeprecatedFields[name]; ok {
+				fmt.Fprintf(&b, " @deprecated(reason: %q)", reason)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func printFields(b *strings.Builder, fields map[string]*Field) {
+	for _, name := range sortedKeys(fields) {
+		field := fields[name]
+		b.WriteString("  " + name)
+
+		if len(field.Args) > 0 {
+			var args []string
+			for _, arg := range sortedKeys(field.Args) {
+				def := fmt.Sprintf("%s: %s", arg, field.Args[arg])
+				if reason, ok := field.DeprecatedArgs[arg]; ok {
+					def += fmt.Sprintf(" @deprecated(reason: %q)", reason)
+				}
+				args = append(args, def)
+			}
+			fmt.Fprintf(b, "(%s)", strings.Join(args, ", "))
+		}
+
+		fmt.Fprintf(b, ": %s", field.Type)
+		if field.IsDeprecated {
+			fmt.Fprintf(b, " @deprecated(reason: %q)", field.DeprecationReason)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// sortedKeys returns the sorted keys of a map keyed by name.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*Field:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]Type:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*Object:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*Interface:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]interface{}:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,357 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// ParseSchema parses the SDL of a schema, such as the output of PrintSchema, into the types of
+// its Query, Mutation and Subscription and its directives. The roots are the types named in the
+// schema definition, or the types named Query, Mutation and Subscription when there is none.
+//
+// The parsed schema describes the types of a schema but cannot execute queries, since none of its
+// fields has a resolver. It is meant to compare the schema built from code to an expected SDL:
+//   expected, err := graphql.ParseSchema(sdl)
+//   ...
+//   if graphql.PrintSchema(built) != graphql.PrintSchema(expected) {
+//     ...
+//   }
+func ParseSchema(sdl string) (*Schema, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: sdl})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &sdlParser{
+		types:       make(map[string]Type),
+		definitions: make(map[string]ast.Node),
+	}
+	schema := &Schema{Directives: make(map[string]*DirectiveDefinition)}
+	roots := map[string]string{
+		"query":        "Query",
+		"mutation":     "Mutation",
+		"subscription": "Subscription",
+	}
+	explicit := make(map[string]bool)
+
+	// The named types are created first, so that the fields can refer to types defined later in
+	// the document.
+	var directives []*ast.DirectiveDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.SchemaDefinition:
+			for _, operation := range definition.OperationTypes {
+				roots[operation.Operation] = operation.Type.Name.Value
+				explicit[operation.Operation] = true
+			}
+		case *ast.DirectiveDefinition:
+			if _, ok := schema.Directives[definition.Name.Value]; ok {
+				return nil, fmt.Errorf("duplicate directive @%s", definition.Name.Value)
+			}
+			schema.Directives[definition.Name.Value] = nil
+			directives = append(directives, definition)
+		case *ast.ScalarDefinition:
+			err = p.define(definition.Name.Value, definition, &Scalar{
+				Type:           definition.Name.Value,
+				Description:    description(definition.Description),
+				SpecifiedByURL: directiveArg(definition.Directives, "specifiedBy", "url"),
+			})
+		case *ast.EnumDefinition:
+			enum := &Enum{Type: definition.Name.Value}
+			for _, value := range definition.Values {
+				enum.Values = append(enum.Values, value.Name.Value)
+			}
+			err = p.define(definition.Name.Value, definition, enum)
+		case *ast.ObjectDefinition:
+			err = p.define(definition.Name.Value, definition, &Object{
+				Name:          definition.Name.Value,
+				Description:   description(definition.Description),
+				Fields:        make(map[string]*Field),
+				Interfaces:    make(map[string]*Interface),
+				FederationKey: directiveArg(definition.Directives, "key", "fields"),
+			})
+		case *ast.InterfaceDefinition:
+			err = p.define(definition.Name.Value, definition, &Interface{
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Types:       make(map[string]*Object),
+				Fields:      make(map[string]*Field),
+			})
+		case *ast.UnionDefinition:
+			err = p.define(definition.Name.Value, definition, &Union{
+				Name:        definition.Name.Value,
+				Description: description(definition.Description),
+				Types:       make(map[string]*Object),
+			})
+		case *ast.InputObjectDefinition:
+			err = p.define(definition.Name.Value, definition, &InputObject{
+				Name:             definition.Name.Value,
+				InputFields:      make(map[string]Type),
+				DeprecatedFields: make(map[string]string),
+			})
+		default:
+			return nil, fmt.Errorf("unsupported definition %s", definition.GetKind())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, definition := range p.definitions {
+		if err := p.complete(p.types[name], definition); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, definition := range directives {
+		args, _, err := p.inputValues(definition.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("directive @%s: %s", definition.Name.Value, err)
+		}
+		directive := &DirectiveDefinition{
+			Name:        definition.Name.Value,
+			Description: description(definition.Description),
+			Args:        args,
+		}
+		for _, location := range definition.Locations {
+			directive.Locations = append(directive.Locations, DirectiveLocation(location.Value))
+		}
+		schema.Directives[directive.Name] = directive
+	}
+
+	for operation, name := range roots {
+		typ, ok := p.types[name]
+		if !ok {
+			if explicit[operation] {
+				return nil, fmt.Errorf("%s type %s is not defined", operation, name)
+			}
+			continue
+		}
+		object, ok := typ.(*Object)
+		if !ok {
+			return nil, fmt.Errorf("%s type %s should be an object", operation, name)
+		}
+		switch operation {
+		case "query":
+			schema.Query = object
+		case "mutation":
+			schema.Mutation = object
+		case "subscription":
+			schema.Subscription = object
+		}
+	}
+	if schema.Query == nil {
+		return nil, fmt.Errorf("schema should have a query type")
+	}
+
+	return schema, nil
+}
+
+// sdlParser holds the named types of the SDL being parsed, along with their definitions.
+type sdlParser struct {
+	types       map[string]Type
+	definitions map[string]ast.Node
+}
+
+// define adds the named type typ, defined by definition.
+func (p *sdlParser) define(name string, definition ast.Node, typ Type) error {
+	if _, ok := p.types[name]; ok || builtinScalars[name] {
+		return fmt.Errorf("duplicate type %s", name)
+	}
+	p.types[name] = typ
+	p.definitions[name] = definition
+	return nil
+}
+
+// complete adds the fields, interfaces and members of typ, which refer to other named types.
+func (p *sdlParser) complete(typ Type, definition ast.Node) error {
+	switch definition := definition.(type) {
+	case *ast.ObjectDefinition:
+		object := typ.(*Object)
+		for _, named := range definition.Interfaces {
+			iface, ok := p.types[named.Name.Value].(*Interface)
+			if !ok {
+				return fmt.Errorf("type %s: %s is not an interface", object.Name, named.Name.Value)
+			}
+			object.Interfaces[iface.Name] = iface
+			iface.Types[object.Name] = object
+		}
+		fields, err := p.fields(definition.Fields)
+		if err != nil {
+			return fmt.Errorf("type %s: %s", object.Name, err)
+		}
+		object.Fields = fields
+
+	case *ast.InterfaceDefinition:
+		iface := typ.(*Interface)
+		fields, err := p.fields(definition.Fields)
+		if err != nil {
+			return fmt.Errorf("interface %s: %s", iface.Name, err)
+		}
+		iface.Fields = fields
+
+	case *ast.UnionDefinition:
+		union := typ.(*Union)
+		for _, named := range definition.Types {
+			object, ok := p.types[named.Name.Value].(*Object)
+			if !ok {
+				return fmt.Errorf("union %s: %s is not an object", union.Name, named.Name.Value)
+			}
+			union.Types[object.Name] = object
+		}
+
+	case *ast.InputObjectDefinition:
+		inputObject := typ.(*InputObject)
+		fields, deprecated, err := p.inputValues(definition.Fields)
+		if err != nil {
+			return fmt.Errorf("input %s: %s", inputObject.Name, err)
+		}
+		inputObject.InputFields = fields
+		inputObject.DeprecatedFields = deprecated
+	}
+
+	return nil
+}
+
+// fields parses the field definitions of an object or an interface.
+func (p *sdlParser) fields(definitions []*ast.FieldDefinition) (map[string]*Field, error) {
+	fields := make(map[string]*Field, len(definitions))
+	for _, definition := range definitions {
+		name := definition.Name.Value
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("duplicate field %s", name)
+		}
+
+		typ, err := p.typeRef(definition.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+		args, deprecated, err := p.inputValues(definition.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+
+		field := &Field{Type: typ, Args: args}
+		if len(deprecated) > 0 {
+			field.DeprecatedArgs = deprecated
+		}
+		if reason, ok := deprecationReason(definition.Directives); ok {
+			field.IsDeprecated = true
+			field.DeprecationReason = reason
+		}
+		fields[name] = field
+	}
+	return fields, nil
+}
+
+// inputValues parses the definitions of arguments or input fields, returning their types along
+// with the deprecation reasons of the deprecated ones.
+func (p *sdlParser) inputValues(definitions []*ast.InputValueDefinition) (map[string]Type, map[string]string, error) {
+	types := make(map[string]Type, len(definitions))
+	deprecated := make(map[string]string)
+	for _, definition := range definitions {
+		name := definition.Name.Value
+		if _, ok := types[name]; ok {
+			return nil, nil, fmt.Errorf("duplicate argument %s", name)
+		}
+
+		typ, err := p.typeRef(definition.Type)
+		if err != nil {
+			return nil, nil, fmt.Errorf("argument %s: %s", name, err)
+		}
+		switch typ := unwrapType(typ).(type) {
+		case *Scalar, *Enum, *InputObject:
+		default:
+			return nil, nil, fmt.Errorf("argument %s: %s is not an input type", name, typ)
+		}
+
+		types[name] = typ
+		if reason, ok := deprecationReason(definition.Directives); ok {
+			deprecated[name] = reason
+		}
+	}
+	return types, deprecated, nil
+}
+
+// typeRef resolves a reference to a type, wrapped in lists and non-nulls.
+func (p *sdlParser) typeRef(ref ast.Type) (Type, error) {
+	switch ref := ref.(type) {
+	case *ast.NonNull:
+		typ, err := p.typeRef(ref.Type)
+		if err != nil {
+			return nil, err
+		}
+		return &NonNull{Type: typ}, nil
+	case *ast.List:
+		typ, err := p.typeRef(ref.Type)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Type: typ}, nil
+	case *ast.Named:
+		name := ref.Name.Value
+		if typ, ok := p.types[name]; ok {
+			return typ, nil
+		}
+		if builtinScalars[name] {
+			typ := &Scalar{Type: name}
+			p.types[name] = typ
+			return typ, nil
+		}
+		return nil, fmt.Errorf("unknown type %s", name)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", ref)
+	}
+}
+
+// unwrapType returns the named type wrapped by typ.
+func unwrapType(typ Type) Type {
+	for {
+		switch wrapper := typ.(type) {
+		case *NonNull:
+			typ = wrapper.Type
+		case *List:
+			typ = wrapper.Type
+		default:
+			return typ
+		}
+	}
+}
+
+// deprecationReason returns the reason of the @deprecated directive among directives, if any.
+func deprecationReason(directives []*ast.Directive) (string, bool) {
+	for _, directive := range directives {
+		if directive.Name.Value == "deprecated" {
+			if reason := directiveArg(directives, "deprecated", "reason"); reason != "" {
+				return reason, true
+			}
+			return "No longer supported", true
+		}
+	}
+	return "", false
+}
+
+// directiveArg returns the string argument arg of the directive name among directives, or the
+// empty string.
+func directiveArg(directives []*ast.Directive, name, arg string) string {
+	for _, directive := range directives {
+		if directive.Name.Value != name {
+			continue
+		}
+		for _, argument := range directive.Arguments {
+			if value, ok := argument.Value.(*ast.StringValue); ok && argument.Name.Value == arg {
+				return value.Value
+			}
+		}
+	}
+	return ""
+}
+
+func description(value *ast.StringValue) string {
+	if value == nil {
+		return ""
+	}
+	return value.Value
+}
@@ -0,0 +1,182 @@
+package graphql_test
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the SDL printer")
+
+type Episode int32
+
+type Droid struct {
+	Id              string
+	Name            string
+	PrimaryFunction string
+}
+
+type Human struct {
+	Id     string
+	Name   string
+	Height float64
+	Mass   float64
+}
+
+type Character struct {
+	schemabuilder.Interface
+	*Droid
+	*Human
+}
+
+type SearchResult struct {
+	schemabuilder.Union
+	*Droid
+	*Human
+}
+
+type Review struct {
+	Stars      int32
+	Commentary *string
+	CreatedAt  time.Time
+}
+
+type ReviewInput struct {
+	Stars      int32
+	Commentary *string
+}
+
+func makeStarWarsSchema() *schemabuilder.Schema {
+	schema := schemabuilder.NewSchema()
+	schema.EnumAuto(Episode(0), []string{"NEWHOPE", "EMPIRE", "JEDI"})
+
+	droid := schema.Object("Droid", Droid{})
+	droid.FieldFunc("id", func(d *Droid) string { return d.Id })
+	droid.FieldFunc("name", func(d *Droid) string { return d.Name })
+	droid.FieldFunc("primaryFunction", func(d *Droid) string { return d.PrimaryFunction })
+
+	human := schema.Object("Human", Human{})
+	human.FieldFunc("id", func(h *Human) string { return h.Id })
+	human.FieldFunc("name", func(h *Human) string { return h.Name })
+	human.FieldFunc("height", func(h *Human) float64 { return h.Height })
+	human.FieldFunc("mass", func(h *Human) float64 { return h.Mass }, schemabuilder.Deprecated("No longer supported."))
+
+	review := schema.Object("Review", Review{})
+	review.FieldFunc("stars", func(r *Review) int32 { return r.Stars })
+	review.FieldFunc("commentary", func(r *Review) *string { return r.Commentary })
+	review.FieldFunc("createdAt", func(r *Review) time.Time { return r.CreatedAt })
+
+	input := schema.InputObject("ReviewInput", ReviewInput{})
+	input.FieldFunc("stars", func(target *ReviewInput, source int32) { target.Stars = source })
+	input.FieldFunc("commentary", func(target *ReviewInput, source *string) { target.Commentary = source })
+
+	query := schema.Query()
+	query.FieldFunc("droid", func(args struct{ Id string }) *Droid { return nil })
+	query.FieldFunc("hero", func(args struct {
+		Episode *Episode
+		Legacy  *bool `graphql:",deprecated=Use episode."`
+	}) *Character {
+		return nil
+	})
+	query.FieldFunc("search", func(args struct{ Text string }) []*SearchResult { return nil })
+
+	mutation := schema.Mutation()
+	mutation.FieldFunc("createReview", func(args struct {
+		Episode Episode
+		Review  ReviewInput
+	}) *Review {
+		return nil
+	})
+
+	schema.Directive("upper", []graphql.DirectiveLocation{"FIELD"},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			return next(ctx)
+		})
+
+	return schema
+}
+
+func TestPrintSchema(t *testing.T) {
+	built, err := makeStarWarsSchema().Build()
+	require.NoError(t, err)
+
+	sdl := graphql.PrintSchema(built)
+
+	golden := filepath.Join("testdata", "starwars.graphql")
+	if *update {
+		require.NoError(t, ioutil.WriteFile(golden, []byte(sdl), 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), sdl)
+}
+
+func TestParseSchema(t *testing.T) {
+	sdl, err := ioutil.ReadFile(filepath.Join("testdata", "starwars.graphql"))
+	require.NoError(t, err)
+
+	expected, err := graphql.ParseSchema(string(sdl))
+	require.NoError(t, err)
+	assert.Equal(t, string(sdl), graphql.PrintSchema(expected))
+
+	built, err := makeStarWarsSchema().Build()
+	require.NoError(t, err)
+	assert.Equal(t, graphql.PrintSchema(expected), graphql.PrintSchema(built))
+
+	// The code no longer matches an SDL expecting a field it does not register.
+	withField := strings.Replace(string(sdl), "  primaryFunction: String!\n", "  primaryFunction: String!\n  serialNumber: String!\n", 1)
+	expected, err = graphql.ParseSchema(withField)
+	require.NoError(t, err)
+	assert.NotEqual(t, graphql.PrintSchema(expected), graphql.PrintSchema(built))
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		sdl  string
+		err  string
+	}{
+		{
+			name: "unknown type",
+			sdl:  "type Query { user: User }",
+			err:  "type Query: field user: unknown type User",
+		},
+		{
+			name: "duplicate type",
+			sdl:  "type Query { a: Int } type Query { b: Int }",
+			err:  "duplicate type Query",
+		},
+		{
+			name: "output argument",
+			sdl:  "type Query { a(b: Query): Int }",
+			err:  "type Query: field a: argument b: Query is not an input type",
+		},
+		{
+			name: "missing query",
+			sdl:  "type Mutation { a: Int }",
+			err:  "schema should have a query type",
+		},
+		{
+			name: "union of scalars",
+			sdl:  "type Query { a: U } union U = Int",
+			err:  "union U: Int is not an object",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := graphql.ParseSchema(tc.sdl)
+			if assert.Error(t, err) {
+				assert.Equal(t, tc.err, err.Error())
+			}
+		})
+	}
+}
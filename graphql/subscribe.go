@@ -0,0 +1,154 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.appointy.com/jaal/jerrors"
+)
+
+const defaultSubscriptionInterval = time.Second
+
+// SubscriptionResult is the response of a subscription to an event of its source stream.
+type SubscriptionResult struct {
+	// Data is the response to the selection set of the subscription for the event, keyed by
+	// the alias of its root field. It is nil when the subscription failed, in which case Err
+	// is the last result sent.
+	Data interface{}
+	Err  error
+}
+
+// Subscribe executes the subscription query against typ, which must have been validated with
+// ValidateQuery. The root field of the subscription is resolved once, and returns the source
+// stream of the subscription: either a channel, every value received from which is an event,
+// or a function, which is called every SubscriptionInterval to produce the next event unless
+// it returns ErrNoUpdate.
+//
+// The selection set of the subscription is executed on every event, and the response is sent
+// on the returned channel. The next event is only received from the source channel once the
+// previous result has been received from the returned channel, which is unbuffered. The
+// returned channel is closed when ctx is done, when the source channel is closed, completing
+// the subscription normally, or after a result holding the error which ended the subscription.
+func (e *Executor) Subscribe(ctx context.Context, typ Type, query *Query) (<-chan *SubscriptionResult, error) {
+	selections, err := Flatten(query.SelectionSet)
+	if err != nil {
+		return nil, err
+	}
+	if len(selections) != 1 {
+		return nil, errors.New("subscriptions must select exactly one field")
+	}
+	selection := selections[0]
+
+	object, ok := typ.(*Object)
+	if !ok {
+		return nil, errors.New("subscriptions are not supported")
+	}
+	field := object.Fields[selection.Name]
+	if field == nil {
+		return nil, fmt.Errorf("unknown field %s", selection.Name)
+	}
+
+	ctx = withPathSegment(ctx, selection.Alias)
+	e.trackDeprecation(ctx, object.Name, field, selection)
+	resolve := e.applyFieldMiddleware(object.Name, field, selection, e.applyDirectives(field, nil, selection))
+	source, err := safeExecuteResolver(ctx, resolve)
+	if err != nil {
+		return nil, jerrors.NestErrorPaths(err, selection.Alias)
+	}
+
+	var next func() (interface{}, bool, error)
+	switch {
+	case field.LazyExecution:
+		next = e.pollSubscription(ctx, field, source)
+	case reflect.ValueOf(source).Kind() == reflect.Chan:
+		next = receiveSubscription(ctx, reflect.ValueOf(source))
+	default:
+		return nil, fmt.Errorf("%s must return a function or a channel to be subscribed to", selection.Name)
+	}
+
+	results := make(chan *SubscriptionResult)
+	go func() {
+		defer close(results)
+
+		for {
+			value, ok, err := next()
+			if !ok {
+				return
+			}
+
+			result := &SubscriptionResult{}
+			if err == nil {
+				result.Data, err = e.fork().Execute(ctx, field.Type, value, &Query{SelectionSet: selection.SelectionSet})
+			}
+			switch {
+			case err == ErrNoUpdate:
+				continue
+			case err != nil && result.Data == nil:
+				result.Err = jerrors.NestErrorPaths(err, selection.Alias)
+			default:
+				// Fields resolving to null on errors are reported along with the rest of the
+				// response, and the subscription goes on.
+				result.Data = ResponseObject{{Key: selection.Alias, Value: result.Data}}
+				result.Err = err
+			}
+
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+			if result.Data == nil {
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// pollSubscription returns a function producing the events of the subscription of field,
+// whose resolver returned the function source, by calling it every SubscriptionInterval. It
+// reports false once ctx is done.
+func (e *Executor) pollSubscription(ctx context.Context, field *Field, source interface{}) func() (interface{}, bool, error) {
+	interval := e.SubscriptionInterval
+	if interval <= 0 {
+		interval = defaultSubscriptionInterval
+	}
+
+	polled := false
+	return func() (interface{}, bool, error) {
+		// The function is first called right away.
+		if polled {
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, false, nil
+			case <-timer.C:
+			}
+		}
+		polled = true
+
+		value, err := safeExecuteLazyResolver(ctx, field, source)
+		return value, true, err
+	}
+}
+
+// receiveSubscription returns a function producing the events of a subscription by receiving
+// them from the channel source. It reports false once ctx is done or source is closed.
+func receiveSubscription(ctx context.Context, source reflect.Value) func() (interface{}, bool, error) {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: source},
+	}
+	return func() (interface{}, bool, error) {
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 0 || !ok {
+			return nil, false, nil
+		}
+		return value.Interface(), true, nil
+	}
+}
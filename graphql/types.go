@@ -3,6 +3,9 @@ package graphql
 import (
 	"context"
 	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"go.appointy.com/jaal/jerrors"
 )
 
 // Type represents a GraphQL type, and should be either an Object, a Scalar,
@@ -18,8 +21,10 @@ type Type interface {
 // Scalar is a leaf value.  A custom "Unwrapper" can be attached to the scalar
 // so it can have a custom unwrapping (if nil we will use the default unwrapper).
 type Scalar struct {
-	Type      string
-	Unwrapper func(interface{}) (interface{}, error)
+	Type           string
+	Description    string
+	SpecifiedByURL string
+	Unwrapper      func(interface{}) (interface{}, error)
 }
 
 func (s *Scalar) isType() {}
@@ -51,7 +56,15 @@ type Object struct {
 	Description string
 	KeyField    *Field
 	Fields      map[string]*Field
-	Interfaces  map[string]*Interface //For introspection only
+	Interfaces  map[string]*Interface // Interfaces implemented by the object, by name
+
+	// Typename, if set, reports the __typename for a given source value.
+	// Defaults to Name when nil.
+	Typename func(source interface{}) string
+
+	// FederationKey is the field set of the Apollo Federation @key of the object, if it is an
+	// entity which can be resolved through the _entities query.
+	FederationKey string
 }
 
 func (o *Object) isType() {}
@@ -60,9 +73,26 @@ func (o *Object) String() string {
 	return o.Name
 }
 
+// typename returns the value reported for __typename of the given source.
+func (o *Object) typename(source interface{}) string {
+	if o.Typename != nil {
+		return o.Typename(source)
+	}
+	return o.Name
+}
+
+// satisfies reports whether the object satisfies the type condition on of a fragment, which is
+// the case when on is empty, the name of the object or of an interface it implements.
+func (o *Object) satisfies(on string) bool {
+	return on == "" || on == o.Name || o.Interfaces[on] != nil
+}
+
 // List is a collection of other values
 type List struct {
 	Type Type
+
+	// DropNulls removes null elements from the list instead of serializing them.
+	DropNulls bool
 }
 
 func (l *List) isType() {}
@@ -75,6 +105,9 @@ func (l *List) String() string {
 type InputObject struct {
 	Name        string
 	InputFields map[string]Type
+
+	// DeprecatedFields are the deprecation reasons of the deprecated input fields, by name.
+	DeprecatedFields map[string]string
 }
 
 func (io *InputObject) isType() {}
@@ -99,6 +132,11 @@ type Union struct {
 	Name        string
 	Description string
 	Types       map[string]*Object
+
+	// ResolveType, if set, returns the member type of a value of the union, dispatching on its
+	// dynamic type, or nil if it is of no member type. Otherwise values of the union are one-hot
+	// structs, holding the value in the field named after its member type.
+	ResolveType func(source interface{}) *Object
 }
 
 func (*Union) isType() {}
@@ -113,6 +151,11 @@ type Interface struct {
 	Description string
 	Types       map[string]*Object
 	Fields      map[string]*Field
+
+	// ResolveType, if set, returns the member type of a value of the interface held directly
+	// rather than in a one-hot struct, dispatching on its dynamic type, or nil if it is of no
+	// member type.
+	ResolveType func(source interface{}) *Object
 }
 
 func (*Interface) isType() {}
@@ -146,11 +189,39 @@ type Field struct {
 	Args           map[string]Type
 	ParseArguments func(json interface{}) (interface{}, error)
 
+	// DeprecatedArgs are the deprecation reasons of the deprecated arguments, by name.
+	DeprecatedArgs map[string]string
+
 	External  bool
 	Expensive bool
 
+	IsDeprecated      bool
+	DeprecationReason string
+
 	LazyExecution bool
 	LazyResolver  func(ctx context.Context, fun interface{}) (interface{}, error)
+
+	// LazyListExecution marks a list field whose resolver returns a slice of functions.
+	// Every function is passed to LazyResolver concurrently in a later iteration of the executor.
+	LazyListExecution bool
+
+	// BatchResolver, if set, is called once with the sources of all the objects of a list
+	// selecting the field, instead of calling Resolve for every object. It must return one
+	// value for every source, in the order of the sources.
+	BatchResolver BatchResolver
+
+	// Directives are the custom directives applied to the field by the schema, the first one
+	// outermost. Their handlers run around the resolver whenever the field is resolved.
+	Directives []*FieldDirective
+}
+
+// FieldDirective is a custom directive applied to a field by the schema, such as
+// @auth(requires: ADMIN). An error returned by its Handler without calling the resolver denies
+// the field, which resolves to null with the error while the other fields are resolved.
+type FieldDirective struct {
+	Name    string
+	Args    interface{}
+	Handler DirectiveHandler
 }
 
 //Schema used to validate and resolve the queries
@@ -158,6 +229,105 @@ type Schema struct {
 	Query        Type
 	Mutation     Type
 	Subscription Type
+
+	// Directives are the custom directives registered on the schema, by name.
+	Directives map[string]*DirectiveDefinition
+}
+
+// types returns the types reachable from the roots of the schema and from the args of its
+// directives, by name.
+func (s *Schema) types() map[string]Type {
+	types := make(map[string]Type)
+	CollectTypes(s.Query, types)
+	CollectTypes(s.Mutation, types)
+	CollectTypes(s.Subscription, types)
+	for _, directive := range s.Directives {
+		for _, arg := range directive.Args {
+			CollectTypes(arg, types)
+		}
+	}
+	return types
+}
+
+// TypeNames returns the sorted names of the types of the schema, including the introspection
+// types once introspection is added to it.
+func (s *Schema) TypeNames() []string {
+	return sortedKeys(s.types())
+}
+
+// Type returns the type of the schema named name.
+func (s *Schema) Type(name string) (Type, bool) {
+	typ, ok := s.types()[name]
+	return typ, ok
+}
+
+// FieldCount returns the number of fields declared by the object and interface types of the
+// schema.
+func (s *Schema) FieldCount() int {
+	count := 0
+	for _, typ := range s.types() {
+		switch typ := typ.(type) {
+		case *Object:
+			count += len(typ.Fields)
+		case *Interface:
+			count += len(typ.Fields)
+		}
+	}
+	return count
+}
+
+// DirectiveLocation is a location in a GraphQL document where a directive may be used.
+type DirectiveLocation string
+
+// DirectiveHandler executes a custom directive applied to a field. It receives the parsed
+// arguments of the directive, and next, which calls the resolver of the field. A handler
+// may transform the value returned by next, or short-circuit execution by not calling it.
+type DirectiveHandler func(ctx context.Context, args interface{}, next func(ctx context.Context) (interface{}, error)) (interface{}, error)
+
+// DirectiveDefinition is a custom directive supported by a schema. Its Handler is called by
+// the Executor for every field the directive is applied to.
+type DirectiveDefinition struct {
+	Name           string
+	Description    string
+	Locations      []DirectiveLocation
+	Args           map[string]Type
+	Repeatable     bool
+	ParseArguments func(json interface{}) (interface{}, error)
+	Handler        DirectiveHandler
+}
+
+// BuiltinDirectives are the definitions of the directives implemented by the executor and the
+// schema builder, by name. They have no Handler, and schemas cannot redefine them.
+var BuiltinDirectives = map[string]*DirectiveDefinition{
+	"include": {
+		Name:      "include",
+		Locations: []DirectiveLocation{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:      map[string]Type{"if": &NonNull{Type: &Scalar{Type: "Boolean"}}},
+	},
+	"skip": {
+		Name:      "skip",
+		Locations: []DirectiveLocation{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:      map[string]Type{"if": &NonNull{Type: &Scalar{Type: "Boolean"}}},
+	},
+	"defer": {
+		Name:      "defer",
+		Locations: []DirectiveLocation{"FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:      map[string]Type{"if": &Scalar{Type: "Boolean"}, "label": &Scalar{Type: "String"}},
+	},
+	"deprecated": {
+		Name:      "deprecated",
+		Locations: []DirectiveLocation{"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INPUT_FIELD_DEFINITION", "ENUM_VALUE"},
+		Args:      map[string]Type{"reason": &Scalar{Type: "String"}},
+	},
+	"specifiedBy": {
+		Name:      "specifiedBy",
+		Locations: []DirectiveLocation{"SCALAR"},
+		Args:      map[string]Type{"url": &NonNull{Type: &Scalar{Type: "String"}}},
+	},
+	"oneOf": {
+		Name:      "oneOf",
+		Locations: []DirectiveLocation{"INPUT_OBJECT"},
+	},
 }
 
 // SelectionSet represents a core GraphQL query
@@ -206,13 +376,32 @@ type Selection struct {
 	// The parsed flag is used to make sure the args for this Selection are only
 	// parsed once.
 	parsed bool
+
+	// variables are the definitions of the variables passed as args, by arg name.
+	variables map[string]*ast.VariableDefinition
+
+	// location is the position of the selection in the query, reported with the errors of its
+	// validation. It is zero when unknown.
+	location jerrors.Location
+
+	// deprecatedArgs are the deprecated args given a value by the selection, and
+	// deprecatedInputFields the deprecated fields of the input objects given a value in its args.
+	// They are found while validating the selection, and reported while executing it.
+	deprecatedArgs        []string
+	deprecatedInputFields []deprecatedInputField
+}
+
+// deprecatedInputField is a deprecated field of the input object typeName.
+type deprecatedInputField struct {
+	typeName  string
+	fieldName string
 }
 
 // A FragmentDefinition represents a reusable part of a GraphQL query
 //
 // The On part of a FragmentDefinition represents the type of source object for which
-// this FragmentDefinition should be used. That is not currently implemented in this
-// package.
+// this FragmentDefinition should be used. The executor only applies the fragment when the
+// concrete type of the source object is On, or implements the interface On.
 type FragmentDefinition struct {
 	Name         string
 	On           string
@@ -229,4 +418,7 @@ type FragmentSpread struct {
 type Directive struct {
 	Name string
 	Args interface{}
+
+	// parsed is set once the args of a custom directive have been parsed.
+	parsed bool
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/kylelemons/godebug/pretty"
 	"go.appointy.com/jaal/graphql"
 	"go.appointy.com/jaal/internal"
+	"go.appointy.com/jaal/introspection"
 	"go.appointy.com/jaal/schemabuilder"
 )
 
@@ -395,3 +396,195 @@ func TestUnionStruct(t *testing.T) {
 		t.Errorf("expected did not match result: %s", d)
 	}
 }
+
+func TestUnionFragmentTypeCondition(t *testing.T) {
+	type User struct {
+		Name string
+	}
+	type DeletedUser struct {
+		Name string
+	}
+
+	type UserResult struct {
+		schemabuilder.Union
+
+		*User
+		*DeletedUser
+	}
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func() *UserResult {
+		return &UserResult{DeletedUser: &DeletedUser{Name: "gone"}}
+	})
+
+	obj := schema.Object("User", User{})
+	obj.FieldFunc("name", func(in User) string {
+		return in.Name
+	})
+
+	obj = schema.Object("DeletedUser", DeletedUser{})
+	obj.FieldFunc("name", func(in DeletedUser) string {
+		return in.Name
+	})
+
+	builtSchema := schema.MustBuild()
+	ctx := context.Background()
+
+	testCases := []struct {
+		name   string
+		query  string
+		output string
+	}{
+		{
+			name:   "inline fragments",
+			query:  `{ user { __typename ... on User { name } ... on DeletedUser { deletedName: name } } }`,
+			output: `{ "user": { "__typename": "DeletedUser", "deletedName": "gone" } }`,
+		},
+		{
+			name: "named fragments",
+			query: `
+				{ user { ...UserFields ...DeletedUserFields } }
+				fragment UserFields on User { name }
+				fragment DeletedUserFields on DeletedUser { deletedName: name }`,
+			output: `{ "user": { "deletedName": "gone" } }`,
+		},
+		{
+			name:   "only non-matching fragment",
+			query:  `{ user { ... on User { name } } }`,
+			output: `{ "user": {} }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := graphql.Parse(tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := graphql.ValidateQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+				t.Fatal(err)
+			}
+
+			e := graphql.Executor{}
+			result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(tc.output)); d != "" {
+				t.Errorf("expected did not match result: %s", d)
+			}
+		})
+	}
+}
+
+type Passenger interface{ isPassenger() }
+
+type Traveller struct{ Name string }
+
+func (*Traveller) isPassenger() {}
+
+type Android struct{ Model string }
+
+func (*Android) isPassenger() {}
+
+// TestRegisteredUnion tests a union registered with Schema.Union, whose values are returned as
+// a Go interface rather than as a one-hot struct.
+func TestRegisteredUnion(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Union("Passenger", &Traveller{}, &Android{})
+
+	query := schema.Query()
+	query.FieldFunc("passengers", func() []Passenger {
+		return []Passenger{&Traveller{Name: "Luke"}, &Android{Model: "R2-D2"}}
+	})
+	query.FieldFunc("nobody", func() Passenger {
+		return nil
+	})
+
+	obj := schema.Object("Traveller", Traveller{})
+	obj.FieldFunc("name", func(in *Traveller) string {
+		return in.Name
+	})
+	obj = schema.Object("Android", Android{})
+	obj.FieldFunc("model", func(in *Android) string {
+		return in.Model
+	})
+
+	builtSchema := schema.MustBuild()
+	introspection.AddIntrospectionToSchema(builtSchema)
+	ctx := context.Background()
+
+	testCases := []struct {
+		name   string
+		query  string
+		output string
+	}{
+		{
+			name:   "members",
+			query:  `{ passengers { __typename ... on Traveller { name } ... on Android { model } } }`,
+			output: `{ "passengers": [{ "__typename": "Traveller", "name": "Luke" }, { "__typename": "Android", "model": "R2-D2" }] }`,
+		},
+		{
+			name:   "nil",
+			query:  `{ nobody { __typename } }`,
+			output: `{ "nobody": null }`,
+		},
+		{
+			name:   "possible types",
+			query:  `{ __type(name: "Passenger") { kind possibleTypes { name } } }`,
+			output: `{ "__type": { "kind": "UNION", "possibleTypes": [{ "name": "Android" }, { "name": "Traveller" }] } }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := graphql.Parse(tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := graphql.ValidateQuery(ctx, builtSchema.Query, q.SelectionSet); err != nil {
+				t.Fatal(err)
+			}
+
+			e := graphql.Executor{}
+			result, err := e.Execute(ctx, builtSchema.Query, nil, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if d := pretty.Compare(internal.AsJSON(result), internal.ParseJSON(tc.output)); d != "" {
+				t.Errorf("expected did not match result: %s", d)
+			}
+		})
+	}
+}
+
+// TestBadRegisteredUnion tests the Go interfaces which do not denote a single registered union.
+func TestBadRegisteredUnion(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Union("Passenger", &Traveller{}, &Android{})
+	schema.Union("Machine", &Android{})
+	schema.Query().FieldFunc("machine", func() interface{} {
+		return &Android{}
+	})
+	schema.Object("Traveller", Traveller{})
+	schema.Object("Android", Android{})
+
+	_, err := schema.Build()
+	if err == nil || !strings.Contains(err.Error(), "implemented by the members of Machine, Passenger") {
+		t.Errorf("expected ambiguous union to fail, received %v", err)
+	}
+
+	schema = schemabuilder.NewSchema()
+	schema.Query().FieldFunc("passenger", func() Passenger {
+		return &Traveller{}
+	})
+	schema.Object("Traveller", Traveller{})
+
+	_, err = schema.Build()
+	if err == nil || !strings.Contains(err.Error(), "an interface implemented by the members of a union or interface") {
+		t.Errorf("expected unregistered union to fail, received %v", err)
+	}
+}
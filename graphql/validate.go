@@ -3,9 +3,43 @@ package graphql
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"go.appointy.com/jaal/jerrors"
 )
 
+type introspectionDisabledKey struct{}
+
+// WithIntrospectionDisabled returns a copy of ctx in which ValidateQuery rejects the queries
+// selecting "__schema" or "__type", regardless of whether introspection was added to the
+// schema. Selecting "__typename" is still allowed.
+func WithIntrospectionDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, introspectionDisabledKey{}, true)
+}
+
+// checkIntrospection returns an error if selection is an introspection field while
+// introspection is disabled in ctx.
+func checkIntrospection(ctx context.Context, selection *Selection) error {
+	if selection.Name != "__schema" && selection.Name != "__type" {
+		return nil
+	}
+	if disabled, _ := ctx.Value(introspectionDisabledKey{}).(bool); !disabled {
+		return nil
+	}
+	return &jerrors.Error{
+		Message:    "introspection is disabled",
+		Extensions: &jerrors.Extension{Code: jerrors.CodeIntrospectionDisabled},
+		Paths:      []string{},
+	}
+}
+
 // ValidateQuery checks that the given selectionSet matches the schema typ, and parses the args in selectionSet
+//
+// All args are parsed before any resolver is executed, so a failure while parsing args
+// anywhere in the query prevents the side effects of every resolver in it.
 func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) error {
 	switch typ := typ.(type) {
 	case *Scalar:
@@ -23,129 +57,612 @@ func ValidateQuery(ctx context.Context, typ Type, selectionSet *SelectionSet) er
 			return fmt.Errorf("object field must have selections")
 		}
 
-		for _, fragment := range selectionSet.Fragments {
-			for typString, graphqlTyp := range typ.Types {
-				if fragment.Fragment.On != typString {
-					continue
-				}
-				if err := ValidateQuery(ctx, graphqlTyp, fragment.Fragment.SelectionSet); err != nil {
-					return err
-				}
-			}
+		if err := validateSelectionSetDirectives(selectionSet); err != nil {
+			return err
+		}
+		if err := validateAbstractFragments(ctx, typ.Name, typ, typ.Types, selectionSet); err != nil {
+			return err
 		}
 		for _, selection := range selectionSet.Selections {
-			if selection.Name == "__typename" {
-				if !isNilArgs(selection.Args) {
-					return fmt.Errorf(`error parsing args for "__typename": no args expected`)
-				}
-				if selection.SelectionSet != nil {
-					return fmt.Errorf(`scalar field "__typename" must have no selection`)
-				}
-				for _, fragment := range selectionSet.Fragments {
-					fragment.Fragment.SelectionSet.Selections = append(fragment.Fragment.SelectionSet.Selections, selection)
-				}
-				continue
+			if err := validateSelection(ctx, nil, selection); err != nil {
+				return err
 			}
-			return fmt.Errorf(`unknown field "%s"`, selection.Name)
 		}
-		return nil
+		return validateFieldsCanMerge(typ, []*SelectionSet{selectionSet})
 
 	case *Interface:
 		if selectionSet == nil {
 			return fmt.Errorf("object field must have selections")
 		}
-		for _, fragment := range selectionSet.Fragments {
-			for typString, graphqlTyp := range typ.Types {
-				if fragment.Fragment.On != typString {
-					continue
-				}
-				if err := ValidateQuery(ctx, graphqlTyp, fragment.Fragment.SelectionSet); err != nil {
-					return err
-				}
+		if err := validateSelectionSetDirectives(selectionSet); err != nil {
+			return err
+		}
+		if err := validateAbstractFragments(ctx, typ.Name, typ, typ.Types, selectionSet); err != nil {
+			return err
+		}
+		for _, selection := range selectionSet.Selections {
+			if err := validateSelection(ctx, typ.Fields, selection); err != nil {
+				return err
 			}
 		}
+		return validateFieldsCanMerge(typ, []*SelectionSet{selectionSet})
+
+	case *Object:
+		if selectionSet == nil {
+			return fmt.Errorf("object field must have selections")
+		}
+		if err := validateSelectionSetDirectives(selectionSet); err != nil {
+			return err
+		}
 		for _, selection := range selectionSet.Selections {
-			if selection.Name == "__typename" {
-				if !isNilArgs(selection.Args) {
-					return fmt.Errorf(`error parsing args for "__typename": no args expected`)
-				}
-				if selection.SelectionSet != nil {
-					return fmt.Errorf(`scalar field "__typename" must have no selection`)
-				}
-				continue
+			if err := validateSelection(ctx, typ.Fields, selection); err != nil {
+				return err
 			}
-			field, ok := typ.Fields[selection.Name]
-			if !ok {
-				return fmt.Errorf(`unknown field "%s"`, selection.Name)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if err := ValidateQuery(ctx, typ, fragment.Fragment.SelectionSet); err != nil {
+				return err
 			}
+		}
+		return validateFieldsCanMerge(typ, []*SelectionSet{selectionSet})
 
-			if !selection.parsed {
-				parsed, err := field.ParseArguments(selection.Args)
-				if err != nil {
-					return fmt.Errorf(`error parsing args for "%s": %s`, selection.Name, err)
-				}
-				selection.Args = parsed
-				selection.parsed = true
+	case *List:
+		return ValidateQuery(ctx, typ.Type, selectionSet)
+
+	case *NonNull:
+		return ValidateQuery(ctx, typ.Type, selectionSet)
+
+	default:
+		panic("unknown type kind")
+	}
+}
+
+// validateSelection checks that selection matches one of fields, or __typename, and parses its
+// args. The errors are reported at the location of the selection in the query.
+func validateSelection(ctx context.Context, fields map[string]*Field, selection *Selection) error {
+	if selection.Name == "__typename" {
+		if !isNilArgs(selection.Args) {
+			return locate(fmt.Errorf(`error parsing args for "__typename": no args expected`), selection)
+		}
+		if selection.SelectionSet != nil {
+			return locate(fmt.Errorf(`scalar field "__typename" must have no selection`), selection)
+		}
+		return nil
+	}
+	if err := checkIntrospection(ctx, selection); err != nil {
+		return locate(err, selection)
+	}
+
+	field, ok := fields[selection.Name]
+	if !ok {
+		if suggestion := suggestField(selection.Name, fields); suggestion != "" {
+			return locate(fmt.Errorf(`unknown field "%s". Did you mean "%s"?`, selection.Name, suggestion), selection)
+		}
+		return locate(fmt.Errorf(`unknown field "%s"`, selection.Name), selection)
+	}
+
+	// Only parse args once for a given selection.
+	if !selection.parsed {
+		if err := validateArguments(field, selection); err != nil {
+			return locate(err, selection)
+		}
+		selection.deprecatedArgs, selection.deprecatedInputFields = deprecatedInputs(field, selection.Args)
+		parsed, err := field.ParseArguments(selection.Args)
+		if err != nil {
+			return locate(fmt.Errorf(`error parsing args for "%s": %w`, selection.Name, err), selection)
+		}
+		selection.Args = parsed
+		selection.parsed = true
+	}
+
+	// The errors of the selections of the field are reported at their own location.
+	return locate(ValidateQuery(ctx, field.Type, selection.SelectionSet), selection)
+}
+
+// deprecatedInputs returns the deprecated args of field given a value in args, along with the
+// deprecated fields of the input objects given a value in them, in order.
+func deprecatedInputs(field *Field, args interface{}) ([]string, []deprecatedInputField) {
+	values, _ := args.(map[string]interface{})
+
+	var deprecatedArgs []string
+	var inputFields []deprecatedInputField
+	for _, name := range sortedKeys(values) {
+		if _, ok := field.DeprecatedArgs[name]; ok {
+			deprecatedArgs = append(deprecatedArgs, name)
+		}
+		inputFields = appendDeprecatedInputFields(inputFields, field.Args[name], values[name])
+	}
+	return deprecatedArgs, inputFields
+}
+
+// appendDeprecatedInputFields appends the deprecated fields of the input objects given a value
+// in value, of type typ, to fields.
+func appendDeprecatedInputFields(fields []deprecatedInputField, typ Type, value interface{}) []deprecatedInputField {
+	switch typ := typ.(type) {
+	case *NonNull:
+		return appendDeprecatedInputFields(fields, typ.Type, value)
+	case *List:
+		values, _ := value.([]interface{})
+		for _, value := range values {
+			fields = appendDeprecatedInputFields(fields, typ.Type, value)
+		}
+	case *InputObject:
+		values, _ := value.(map[string]interface{})
+		for _, name := range sortedKeys(values) {
+			if _, ok := typ.DeprecatedFields[name]; ok {
+				fields = append(fields, deprecatedInputField{typeName: typ.Name, fieldName: name})
+			}
+			fields = appendDeprecatedInputFields(fields, typ.InputFields[name], values[name])
+		}
+	}
+	return fields
+}
+
+// suggestField returns the name of the field among fields closest to the unknown name, or the
+// empty string if none is close enough to be a likely misspelling of it.
+func suggestField(name string, fields map[string]*Field) string {
+	// Like graphql-js, names are suggested up to an edit distance of about 40% of the name.
+	threshold := len(name)*2/5 + 1
+
+	suggestion, best := "", threshold+1
+	for candidate := range fields {
+		distance := editDistance(name, candidate)
+		if distance < best || (distance == best && candidate < suggestion) {
+			suggestion, best = candidate, distance
+		}
+	}
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between a and b, the number of single byte
+// insertions, deletions or substitutions turning a into b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			distance := previous[j-1]
+			if a[i-1] != b[j-1] {
+				distance++
+			}
+			if previous[j]+1 < distance {
+				distance = previous[j] + 1
+			}
+			if current[j-1]+1 < distance {
+				distance = current[j-1] + 1
 			}
-			if err := ValidateQuery(ctx, field.Type, selection.SelectionSet); err != nil {
+			current[j] = distance
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// validateArguments checks that the variables passed as the args of selection have types
+// compatible with the args of field, and that the non-null args of field are provided.
+func validateArguments(field *Field, selection *Selection) error {
+	for name, definition := range selection.variables {
+		argType, ok := field.Args[name]
+		if !ok {
+			continue
+		}
+		if !variableTypeMatches(definition.Type, argType, definition.DefaultValue != nil) {
+			return &jerrors.Error{
+				Message: fmt.Sprintf(`variable "$%s" of type "%s" cannot be used for argument "%s" of type "%s" of field "%s"`,
+					definition.Variable.Name.Value, astTypeString(definition.Type), name, argType, selection.Name),
+				Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput},
+				Paths:      []string{},
+			}
+		}
+	}
+
+	args, _ := selection.Args.(map[string]interface{})
+	names := make([]string, 0, len(field.Args))
+	for name := range field.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := field.Args[name].(*NonNull); ok && args[name] == nil {
+			return &jerrors.Error{
+				Message:    fmt.Sprintf(`argument "%s" of type "%s" of field "%s" is required`, name, field.Args[name], selection.Name),
+				Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput},
+				Paths:      []string{},
+			}
+		}
+	}
+	return nil
+}
+
+// variableTypeMatches reports whether a variable of type varType can be passed as an arg of
+// type argType. A nullable variable can only be passed to a non-null arg if it has a default.
+func variableTypeMatches(varType ast.Type, argType Type, hasDefault bool) bool {
+	if nonNull, ok := argType.(*NonNull); ok {
+		if varType, ok := varType.(*ast.NonNull); ok {
+			return variableTypeMatches(varType.Type, nonNull.Type, false)
+		}
+		return hasDefault && variableTypeMatches(varType, nonNull.Type, false)
+	}
+
+	switch varType := varType.(type) {
+	case *ast.NonNull:
+		return variableTypeMatches(varType.Type, argType, false)
+	case *ast.List:
+		list, ok := argType.(*List)
+		return ok && variableTypeMatches(varType.Type, list.Type, false)
+	case *ast.Named:
+		return varType.Name.Value == argType.String()
+	default:
+		return false
+	}
+}
+
+// astTypeString returns the GraphQL notation of the type of a variable, such as [Int!].
+func astTypeString(typ ast.Type) string {
+	switch typ := typ.(type) {
+	case *ast.NonNull:
+		return astTypeString(typ.Type) + "!"
+	case *ast.List:
+		return "[" + astTypeString(typ.Type) + "]"
+	case *ast.Named:
+		return typ.Name.Value
+	default:
+		return ""
+	}
+}
+
+// validateAbstractFragments validates the fragments selected on the union or interface typ named
+// abstract against the member types satisfying their type condition. Fragments on the abstract type
+// itself are validated against it.
+func validateAbstractFragments(ctx context.Context, abstract string, typ Type, types map[string]*Object, selectionSet *SelectionSet) error {
+	for _, fragment := range selectionSet.Fragments {
+		if on := fragment.Fragment.On; on == "" || on == abstract {
+			if err := ValidateQuery(ctx, typ, fragment.Fragment.SelectionSet); err != nil {
 				return err
 			}
+			continue
 		}
 
-		return nil
+		for _, graphqlTyp := range types {
+			if !graphqlTyp.satisfies(fragment.Fragment.On) {
+				continue
+			}
+			if err := ValidateQuery(ctx, graphqlTyp, fragment.Fragment.SelectionSet); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateFieldsCanMerge checks that the selections of selectionSets on typ with the same response
+// key, which are merged into a single field of the response, can be merged. They must select the
+// same field with the same args, unless they are selected on different object types, as only one
+// of them then applies. The subselections of the merged selections are checked together in turn.
+//
+// The args are compared once parsed, so it must be called after the selections are validated.
+func validateFieldsCanMerge(typ Type, selectionSets []*SelectionSet) error {
+	// The possible types of an abstract type, by name.
+	var root string
+	objects := make(map[string]*Object)
+	switch typ := typ.(type) {
 	case *Object:
-		if selectionSet == nil {
-			return fmt.Errorf("object field must have selections")
+		root = typ.Name
+	case *Interface:
+		for _, object := range typ.Types {
+			objects[object.Name] = object
+		}
+	case *Union:
+		for _, object := range typ.Types {
+			objects[object.Name] = object
+		}
+	}
+
+	grouped := make(map[string][]fieldSelection)
+	var aliases []string
+	visited := make(map[fieldSelectionSet]bool)
+	var visit func(selectionSet *SelectionSet, on string)
+	visit = func(selectionSet *SelectionSet, on string) {
+		if visited[fieldSelectionSet{selectionSet, on}] {
+			return
 		}
+		visited[fieldSelectionSet{selectionSet, on}] = true
+
 		for _, selection := range selectionSet.Selections {
-			if selection.Name == "__typename" {
-				if !isNilArgs(selection.Args) {
-					return fmt.Errorf(`error parsing args for "__typename": no args expected`)
+			if _, ok := grouped[selection.Alias]; !ok {
+				aliases = append(aliases, selection.Alias)
+			}
+			grouped[selection.Alias] = append(grouped[selection.Alias], fieldSelection{selection, on})
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if _, ok := objects[fragment.Fragment.On]; ok && on == "" {
+				visit(fragment.Fragment.SelectionSet, fragment.Fragment.On)
+			} else {
+				visit(fragment.Fragment.SelectionSet, on)
+			}
+		}
+	}
+	for _, selectionSet := range selectionSets {
+		visit(selectionSet, root)
+	}
+
+	for _, alias := range aliases {
+		selections := grouped[alias]
+		for i, selection := range selections {
+			for _, other := range selections[:i] {
+				if selection.on != other.on && selection.on != "" && other.on != "" {
+					continue
 				}
-				if selection.SelectionSet != nil {
-					return fmt.Errorf(`scalar field "__typename" must have no selection`)
+				var err error
+				if selection.Name != other.Name {
+					err = fmt.Errorf(`fields "%s" conflict because "%s" and "%s" are different fields`, alias, other.Name, selection.Name)
+				} else if !reflect.DeepEqual(selection.Args, other.Args) {
+					err = fmt.Errorf(`fields "%s" conflict because they have different args`, alias)
+				}
+				if err != nil {
+					return &locatedError{err: err, locations: selectionLocations(other.Selection, selection.Selection)}
 				}
-				continue
 			}
+		}
 
-			field, ok := typ.Fields[selection.Name]
-			if !ok {
-				return fmt.Errorf(`unknown field "%s"`, selection.Name)
+		// The subselections of the selections of the same field are merged, unless they are
+		// selected on different object types.
+		children := make(map[fieldKey][]*SelectionSet)
+		var keys []fieldKey
+		for _, selection := range selections {
+			if selection.SelectionSet == nil {
+				continue
+			}
+			key := fieldKey{name: selection.Name, on: selection.on}
+			if _, ok := children[key]; !ok {
+				keys = append(keys, key)
+			}
+			children[key] = append(children[key], selection.SelectionSet)
+		}
+		for _, key := range keys {
+			selectionSets := children[key]
+			if key.on != "" {
+				selectionSets = append(selectionSets, children[fieldKey{name: key.name}]...)
 			}
+			field := fieldOf(typ, objects[key.on], key.name)
+			if len(selectionSets) < 2 || field == nil {
+				continue
+			}
+			if err := validateFieldsCanMerge(unwrapType(field.Type), selectionSets); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-			// Only parse args once for a given selection.
-			if !selection.parsed {
-				parsed, err := field.ParseArguments(selection.Args)
-				if err != nil {
-					return fmt.Errorf(`error parsing args for "%s": %s`, selection.Name, err)
-				}
-				selection.Args = parsed
-				selection.parsed = true
+// fieldSelection is a selection along with the object type it is selected on, which is empty when
+// it applies to any of the possible types of an abstract type.
+type fieldSelection struct {
+	*Selection
+	on string
+}
+
+// fieldSelectionSet is a selection set visited on the object type on.
+type fieldSelectionSet struct {
+	*SelectionSet
+	on string
+}
+
+// fieldKey identifies the field name selected on the object type on.
+type fieldKey struct {
+	name string
+	on   string
+}
+
+// fieldOf returns the field name of object, if it is known, or else of the object or interface typ.
+func fieldOf(typ Type, object *Object, name string) *Field {
+	if object != nil {
+		return object.Fields[name]
+	}
+	switch typ := typ.(type) {
+	case *Object:
+		return typ.Fields[name]
+	case *Interface:
+		return typ.Fields[name]
+	}
+	return nil
+}
+
+// selectionLocations returns the known locations of selections in the query.
+func selectionLocations(selections ...*Selection) []jerrors.Location {
+	var locations []jerrors.Location
+	for _, selection := range selections {
+		if selection.location != (jerrors.Location{}) {
+			locations = append(locations, selection.location)
+		}
+	}
+	return locations
+}
+
+// validateSelectionSetDirectives checks the locations of the directives applied to the fields
+// and fragments of selectionSet.
+func validateSelectionSetDirectives(selectionSet *SelectionSet) error {
+	for _, selection := range selectionSet.Selections {
+		if err := validateDirectives(selection.Directives, "FIELD"); err != nil {
+			return err
+		}
+	}
+	for _, fragment := range selectionSet.Fragments {
+		location := DirectiveLocation("FRAGMENT_SPREAD")
+		if fragment.Fragment.Name == "" {
+			location = "INLINE_FRAGMENT"
+		}
+		if err := validateDirectives(fragment.Directives, location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDirectives checks that the built-in directives among directives can be used at
+// location. The custom directives are checked by ValidateDirectives.
+func validateDirectives(directives []*Directive, location DirectiveLocation) error {
+	for _, directive := range directives {
+		if definition, ok := BuiltinDirectives[directive.Name]; ok {
+			if err := validateDirectiveLocation(definition, location); err != nil {
+				return err
 			}
+		}
+	}
+	return nil
+}
+
+// validateDirectiveLocation checks that the directive defined by definition can be used at
+// location.
+func validateDirectiveLocation(definition *DirectiveDefinition, location DirectiveLocation) error {
+	for _, l := range definition.Locations {
+		if l == location {
+			return nil
+		}
+	}
+	return fmt.Errorf(`directive "@%s" may not be used on %s`, definition.Name, location)
+}
+
+// ValidateDirectives checks that the custom directives applied to the fields and fragments of
+// selectionSet are used at one of the locations they were defined for, and parses their args.
+// Directives missing from directives are left to the executor.
+func ValidateDirectives(directives map[string]*DirectiveDefinition, selectionSet *SelectionSet) error {
+	if len(directives) == 0 {
+		return nil
+	}
 
-			if err := ValidateQuery(ctx, field.Type, selection.SelectionSet); err != nil {
+	visited := make(map[*SelectionSet]bool)
+
+	var visit func(*SelectionSet) error
+	visit = func(selectionSet *SelectionSet) error {
+		if selectionSet == nil || visited[selectionSet] {
+			return nil
+		}
+		visited[selectionSet] = true
+
+		for _, selection := range selectionSet.Selections {
+			if err := parseDirectiveArgs(directives, selection.Directives, "FIELD"); err != nil {
+				return err
+			}
+			if err := visit(selection.SelectionSet); err != nil {
 				return err
 			}
 		}
 		for _, fragment := range selectionSet.Fragments {
-			if err := ValidateQuery(ctx, typ, fragment.Fragment.SelectionSet); err != nil {
+			location := DirectiveLocation("FRAGMENT_SPREAD")
+			if fragment.Fragment.Name == "" {
+				location = "INLINE_FRAGMENT"
+			}
+			if err := parseDirectiveArgs(directives, fragment.Directives, location); err != nil {
+				return err
+			}
+			if err := visit(fragment.Fragment.SelectionSet); err != nil {
 				return err
 			}
 		}
 		return nil
+	}
 
-	case *List:
-		return ValidateQuery(ctx, typ.Type, selectionSet)
+	return visit(selectionSet)
+}
 
-	case *NonNull:
-		return ValidateQuery(ctx, typ.Type, selectionSet)
+// parseDirectiveArgs checks the location of the custom directives among directives, and parses
+// their args.
+func parseDirectiveArgs(definitions map[string]*DirectiveDefinition, directives []*Directive, location DirectiveLocation) error {
+	for _, directive := range directives {
+		definition, ok := definitions[directive.Name]
+		if !ok {
+			continue
+		}
+		if err := validateDirectiveLocation(definition, location); err != nil {
+			return err
+		}
 
-	default:
-		panic("unknown type kind")
+		// Only parse args once for a given directive.
+		if directive.parsed {
+			continue
+		}
+		parsed, err := definition.ParseArguments(directive.Args)
+		if err != nil {
+			return fmt.Errorf(`error parsing args for "@%s": %w`, directive.Name, err)
+		}
+		directive.Args = parsed
+		directive.parsed = true
 	}
+	return nil
 }
 
 func isNilArgs(args interface{}) bool {
 	m, ok := args.(map[string]interface{})
 	return args == nil || (ok && len(m) == 0)
 }
+
+// CountSelections counts every selection in selectionSet, including the selections of nested
+// selection sets and of every fragment spread, each time the fragment is spread.
+//
+// Counting stops as soon as the count exceeds limit, which bounds the work done for documents
+// spreading the same fragments many times. A limit <= 0 counts all selections.
+func CountSelections(selectionSet *SelectionSet, limit int) int {
+	count := 0
+	visiting := make(map[*SelectionSet]bool)
+
+	var visit func(*SelectionSet)
+	visit = func(selectionSet *SelectionSet) {
+		if selectionSet == nil || visiting[selectionSet] || (limit > 0 && count > limit) {
+			return
+		}
+		visiting[selectionSet] = true
+
+		for _, selection := range selectionSet.Selections {
+			count++
+			visit(selection.SelectionSet)
+		}
+		for _, fragment := range selectionSet.Fragments {
+			visit(fragment.Fragment.SelectionSet)
+		}
+
+		visiting[selectionSet] = false
+	}
+
+	visit(selectionSet)
+	return count
+}
+
+// CheckDepth returns an error if the selections of selectionSet, including those of fragment
+// spreads, are nested more than maxDepth levels deep. Fields of the root selection set are at
+// depth 1. The error names the path of the first field exceeding the limit.
+func CheckDepth(selectionSet *SelectionSet, maxDepth int) error {
+	visiting := make(map[*SelectionSet]bool)
+
+	var visit func(selectionSet *SelectionSet, path []string) error
+	visit = func(selectionSet *SelectionSet, path []string) error {
+		if selectionSet == nil || visiting[selectionSet] {
+			return nil
+		}
+		visiting[selectionSet] = true
+		defer delete(visiting, selectionSet)
+
+		for _, selection := range selectionSet.Selections {
+			fieldPath := append(path[:len(path):len(path)], selection.Alias)
+			if len(fieldPath) > maxDepth {
+				return fmt.Errorf("field %q exceeds the maximum query depth of %d", strings.Join(fieldPath, "."), maxDepth)
+			}
+			if err := visit(selection.SelectionSet, fieldPath); err != nil {
+				return err
+			}
+		}
+		for _, fragment := range selectionSet.Fragments {
+			if err := visit(fragment.Fragment.SelectionSet, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return visit(selectionSet, nil)
+}
@@ -1,10 +1,20 @@
 package jaal
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"go.appointy.com/jaal/graphql"
 	"go.appointy.com/jaal/jerrors"
@@ -13,7 +23,260 @@ import (
 type HandlerOption func(*handlerOptions)
 
 type handlerOptions struct {
-	Middlewares []MiddlewareFunc
+	Middlewares           []MiddlewareFunc
+	FieldMiddlewares      []graphql.FieldMiddleware
+	StrictRequestDecoding bool
+	StrictVariables       bool
+	DeprecationUsageHook  func(ctx context.Context, typeName, fieldName string)
+	RequestID             func(r *http.Request) string
+	ContextFunc           func(r *http.Request) context.Context
+	MaxSelectionNodes     int
+	HTTPStatusMapper      func(err *jerrors.Error) int
+	MaxVariablesBytes     int
+	SubscriptionInterval  time.Duration
+	MaxComplexity         int
+	ListComplexityFactor  int
+	MaxDepth              int
+	PanicHandler          func(ctx context.Context, recovered interface{}, stack []byte)
+	ErrorFormatter        func(ctx context.Context, err error) *jerrors.Error
+	ExecutionTimeout      time.Duration
+	MaxConcurrency        int
+	ResponseCompression   bool
+	DisablePlayground     bool
+	PlaygroundTitle       string
+	IntrospectionDisabled bool
+	Tracing               bool
+	QueryCacheSize        int
+	OperationObserver     func(OperationStats)
+	MaxBodyBytes          int64
+	KeepAlive             time.Duration
+	ConnectionInitTimeout time.Duration
+
+	IsolatedBatchCaches bool
+}
+
+// defaultMaxBodyBytes is the maximum size of request bodies, unless set with WithMaxBodyBytes.
+const defaultMaxBodyBytes = 1 << 20
+
+// WithMaxBodyBytes rejects requests whose body is larger than n bytes, with an error with the
+// code BAD_REQUEST, and the status 413 when responding with HTTP statuses. It defaults to 1 MiB,
+// and can be raised e.g. for file uploads. The limit is removed when n is not positive.
+func WithMaxBodyBytes(n int64) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxBodyBytes = n
+	}
+}
+
+// WithIntrospectionDisabled rejects the queries selecting "__schema" or "__type" with an error
+// with the code INTROSPECTION_DISABLED, e.g. in production. It is independent of whether
+// introspection was added to the schema. See graphql.WithIntrospectionDisabled.
+func WithIntrospectionDisabled() HandlerOption {
+	return func(h *handlerOptions) {
+		h.IntrospectionDisabled = true
+	}
+}
+
+// WithPlayground enables or disables the GraphQL Playground, which the handler serves to the
+// GET requests of browsers without a query. It is enabled by default. When disabled, such
+// requests are rejected like the requests of any other method than POST.
+func WithPlayground(enabled bool) HandlerOption {
+	return func(h *handlerOptions) {
+		h.DisablePlayground = !enabled
+	}
+}
+
+// WithPlaygroundTitle sets the title of the page of the GraphQL Playground.
+func WithPlaygroundTitle(title string) HandlerOption {
+	return func(h *handlerOptions) {
+		h.PlaygroundTitle = title
+	}
+}
+
+// WithResponseCompression compresses the JSON responses larger than compressionThreshold bytes
+// with gzip when the request accepts it. It is off by default so that responses served behind
+// a compressing proxy are not compressed twice.
+func WithResponseCompression() HandlerOption {
+	return func(h *handlerOptions) {
+		h.ResponseCompression = true
+	}
+}
+
+// WithMaxConcurrency resolves the fields whose resolvers accept a context concurrently with
+// their siblings, running at most n of them at once. See graphql.Executor.MaxConcurrency.
+func WithMaxConcurrency(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxConcurrency = n
+	}
+}
+
+// WithExecutionTimeout cancels the context passed to the resolvers once an operation has been
+// executing for d. The fields which are not resolved by then resolve to null with an error
+// with the code TIMEOUT, while the fields resolved before are kept. Resolvers must return
+// when their context is done for the timeout to be effective, as they are not interrupted.
+func WithExecutionTimeout(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ExecutionTimeout = d
+	}
+}
+
+// WithErrorFormatter registers a function which converts every error of a response, instead of
+// jerrors.ConvertError, e.g. to mask internal messages or to map errors to codes. It receives the
+// context of the request, and the errors of fields as *jerrors.Error holding their paths. When f
+// returns nil, the error is converted by jerrors.ConvertError.
+//
+//	jaal.WithErrorFormatter(func(ctx context.Context, err error) *jerrors.Error {
+//		if !errors.Is(err, ErrUnauthorized) {
+//			return nil
+//		}
+//		jerr := jerrors.ConvertError(err)
+//		jerr.Extensions = &jerrors.Extension{Code: "UNAUTHENTICATED"}
+//		return jerr
+//	})
+func WithErrorFormatter(f func(ctx context.Context, err error) *jerrors.Error) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ErrorFormatter = f
+	}
+}
+
+// WithPanicHandler registers a function which is called with the recovered value and the
+// stack trace whenever a resolver panics, e.g. to log them. Regardless of this option, the
+// field resolves to null and the client receives a sanitized error.
+func WithPanicHandler(f func(ctx context.Context, recovered interface{}, stack []byte)) HandlerOption {
+	return func(h *handlerOptions) {
+		h.PanicHandler = f
+	}
+}
+
+// WithMaxDepth rejects queries whose selections, including those of fragment spreads, are
+// nested more than n levels deep, before they are executed.
+func WithMaxDepth(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxDepth = n
+	}
+}
+
+// WithMaxComplexity rejects queries whose complexity, as computed by
+// graphql.ComplexityOfSelectionSet, exceeds n before they are executed.
+func WithMaxComplexity(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxComplexity = n
+	}
+}
+
+// WithListComplexityFactor sets the factor by which the complexity of list fields is
+// multiplied when checking WithMaxComplexity. It defaults to graphql.DefaultListFactor.
+func WithListComplexityFactor(factor int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ListComplexityFactor = factor
+	}
+}
+
+// WithMaxVariablesBytes rejects requests whose variables are larger than n bytes when
+// serialized as JSON, before the variables are coerced.
+func WithMaxVariablesBytes(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxVariablesBytes = n
+	}
+}
+
+// WithHTTPStatusCodes makes the handler respond with the status DefaultHTTPStatus maps the
+// error of the response to, such as 401 for an authentication error. By default the handler
+// always responds with 200.
+func WithHTTPStatusCodes() HandlerOption {
+	return WithHTTPStatusMapper(DefaultHTTPStatus)
+}
+
+// WithHTTPStatusMapper makes the handler respond with the status f maps the error of the
+// response to, or with 200 when f returns 0. The body of the response is unchanged.
+func WithHTTPStatusMapper(f func(err *jerrors.Error) int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.HTTPStatusMapper = f
+	}
+}
+
+// codeStatuses are the HTTP statuses of the well-known error codes.
+var codeStatuses = map[string]int{
+	jerrors.CodeUnauthenticated: http.StatusUnauthorized,
+	jerrors.CodeForbidden:       http.StatusForbidden,
+	jerrors.CodeBadRequest:      http.StatusBadRequest,
+}
+
+// DefaultHTTPStatus maps err to the status of the error it was converted from, if that error
+// implements interface{ HTTPStatus() int }, and otherwise to the status of its code:
+// UNAUTHENTICATED to 401, FORBIDDEN to 403 and BAD_REQUEST to 400. It returns 0 for any
+// other error.
+func DefaultHTTPStatus(err *jerrors.Error) int {
+	if status := err.HTTPStatus(); status != 0 {
+		return status
+	}
+	if err.Extensions == nil {
+		return 0
+	}
+	return codeStatuses[err.Extensions.Code]
+}
+
+// WithMaxSelectionNodes rejects queries containing more than n selections in total, counting
+// the selections of fragments every time they are spread.
+func WithMaxSelectionNodes(n int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.MaxSelectionNodes = n
+	}
+}
+
+// WithRequestID computes an ID for every request, which is added to the extensions of
+// every error in the response and is available to resolvers through RequestIDFromContext.
+func WithRequestID(f func(r *http.Request) string) HandlerOption {
+	return func(h *handlerOptions) {
+		h.RequestID = f
+	}
+}
+
+// WithContextFunc derives the context of every request from the request, e.g. to add the
+// authenticated user or the tenant read from its headers or cookies. The context is derived
+// before the middlewares of WithMiddlewares run, so they and the resolvers can read the values
+// with ctx.Value. f should derive the context from r.Context(), which is cancelled when the
+// client goes away.
+//
+//	type userKey struct{}
+//
+//	jaal.WithContextFunc(func(r *http.Request) context.Context {
+//		return context.WithValue(r.Context(), userKey{}, r.Header.Get("Authorization"))
+//	})
+//
+//	func userFromContext(ctx context.Context) (string, bool) {
+//		user, ok := ctx.Value(userKey{}).(string)
+//		return user, ok
+//	}
+func WithContextFunc(f func(r *http.Request) context.Context) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ContextFunc = f
+	}
+}
+
+// WithDeprecationUsageHook registers a function which is called every time a deprecated
+// field is resolved, or a deprecated arg or input object field is given a value, which can be
+// used to measure usage before it is removed. See graphql.Executor.DeprecationUsageHook.
+func WithDeprecationUsageHook(f func(ctx context.Context, typeName, fieldName string)) HandlerOption {
+	return func(h *handlerOptions) {
+		h.DeprecationUsageHook = f
+	}
+}
+
+// WithStrictRequestDecoding makes the handler reject request bodies containing unknown
+// fields or trailing data after the JSON object.
+func WithStrictRequestDecoding() HandlerOption {
+	return func(h *handlerOptions) {
+		h.StrictRequestDecoding = true
+	}
+}
+
+// WithStrictVariables makes the handler reject requests whose variables include any variable
+// which is not defined by the operation, e.g. a misspelled one. By default such variables are
+// ignored, since some clients send more variables than an operation uses.
+func WithStrictVariables() HandlerOption {
+	return func(h *handlerOptions) {
+		h.StrictVariables = true
+	}
 }
 
 // HTTPHandler implements the handler required for executing the graphql queries and mutations
@@ -21,14 +284,50 @@ func HTTPHandler(schema *graphql.Schema, opts ...HandlerOption) http.Handler {
 	h := &httpHandler{
 		handler: handler{
 			schema:   schema,
-			executor: &graphql.Executor{},
+			executor: &graphql.Executor{Directives: schema.Directives},
 		},
 	}
 
-	o := handlerOptions{}
+	o := handlerOptions{MaxBodyBytes: defaultMaxBodyBytes}
 	for _, opt := range opts {
 		opt(&o)
 	}
+	h.strict = o.StrictRequestDecoding
+	h.strictVariables = o.StrictVariables
+	h.maxBodyBytes = o.MaxBodyBytes
+	h.executor.DeprecationUsageHook = o.DeprecationUsageHook
+	h.executor.PanicHandler = o.PanicHandler
+	h.executor.FieldMiddleware = o.FieldMiddlewares
+	if o.Tracing {
+		h.executor.FieldMiddleware = append([]graphql.FieldMiddleware{traceField}, o.FieldMiddlewares...)
+	}
+	h.tracing = o.Tracing
+	if o.QueryCacheSize > 0 {
+		h.queryCache = newQueryCache(o.QueryCacheSize)
+	}
+	h.executor.MaxConcurrency = o.MaxConcurrency
+	h.requestID = o.RequestID
+	h.contextFunc = o.ContextFunc
+	h.errorFormatter = o.ErrorFormatter
+	h.maxSelectionNodes = o.MaxSelectionNodes
+	h.statusMapper = o.HTTPStatusMapper
+	h.maxVariablesBytes = o.MaxVariablesBytes
+	h.maxDepth = o.MaxDepth
+	h.executionTimeout = o.ExecutionTimeout
+	h.compress = o.ResponseCompression
+	h.playground = !o.DisablePlayground
+	h.introspectionDisabled = o.IntrospectionDisabled
+	h.playgroundTitle = o.PlaygroundTitle
+	h.operationObserver = o.OperationObserver
+	h.isolatedBatchCaches = o.IsolatedBatchCaches
+	if h.playgroundTitle == "" {
+		h.playgroundTitle = defaultPlaygroundTitle
+	}
+	h.maxComplexity = o.MaxComplexity
+	h.listComplexityFactor = o.ListComplexityFactor
+	if h.listComplexityFactor <= 0 {
+		h.listComplexityFactor = graphql.DefaultListFactor
+	}
 
 	prev := h.execute
 	for i := range o.Middlewares {
@@ -47,84 +346,580 @@ type handler struct {
 type httpHandler struct {
 	handler
 
-	exec HandlerFunc
+	exec              HandlerFunc
+	strict            bool
+	strictVariables   bool
+	requestID         func(r *http.Request) string
+	contextFunc       func(r *http.Request) context.Context
+	errorFormatter    func(ctx context.Context, err error) *jerrors.Error
+	maxSelectionNodes int
+	statusMapper      func(err *jerrors.Error) int
+	maxVariablesBytes int
+	maxBodyBytes      int64
+	maxDepth          int
+	executionTimeout  time.Duration
+	compress          bool
+	playground        bool
+	playgroundTitle   string
+	tracing           bool
+	queryCache        *queryCache
+	operationObserver func(OperationStats)
+
+	introspectionDisabled bool
+	isolatedBatchCaches   bool
+
+	maxComplexity        int
+	listComplexityFactor int
 }
 
 type httpPostBody struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
 }
 
 type httpResponse struct {
-	Data   interface{}      `json:"data"`
-	Errors []*jerrors.Error `json:"errors"`
+	Data       interface{}            `json:"data"`
+	Errors     []*jerrors.Error       `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if h.contextFunc != nil {
+		ctx = h.contextFunc(r)
+	}
+
+	var requestID string
+	if h.requestID != nil {
+		requestID = h.requestID(r)
+		ctx = addRequestID(ctx, requestID)
+	}
+	ctx = addExtensions(ctx)
+
 	writeResponse := func(value interface{}, err error) {
-		response := httpResponse{}
-		if err != nil {
-			response.Errors = []*jerrors.Error{jerrors.ConvertError(err)}
-		} else {
-			response.Data = value
+		response, status := h.newResponse(ctx, value, err, requestID)
+		h.writeJSON(w, r, status, response)
+	}
+
+	var params httpPostBody
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("query") != "":
+		if err := decodeURLParams(r, &params); err != nil {
+			writeResponse(nil, err)
+			return
 		}
 
-		responseJSON, err := json.Marshal(response)
+	case h.playground && r.Method == http.MethodGet && acceptsHTML(r):
+		servePlayground(w, playgroundData{Title: h.playgroundTitle, Endpoint: r.URL.Path})
+		return
+
+	case r.Method != "POST":
+		writeResponse(nil, errors.New("request must be a POST"))
+		return
+
+	case r.Body == nil:
+		writeResponse(nil, errors.New("request must include a query"))
+		return
+
+	default:
+		body, err := h.readBody(w, r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeResponse(nil, err)
 			return
 		}
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", "application/json")
+
+		// The body of an application/graphql request is the query itself.
+		if isGraphQLBody(r) {
+			params.Query = string(body)
+			break
+		}
+
+		if isBatch(body) {
+			h.serveBatch(addBatchCache(ctx), w, r, body, requestID)
+			return
+		}
+
+		if err := h.decodeBody(bytes.NewReader(body), &params); err != nil {
+			writeResponse(nil, err)
+			return
 		}
-		_, _ = w.Write(responseJSON)
 	}
 
-	if r.Method != "POST" {
-		writeResponse(nil, errors.New("request must be a POST"))
-		return
+	var deferred []graphql.Deferred
+	if acceptsMultipart(r) {
+		ctx = context.WithValue(ctx, deferredKey, &deferred)
 	}
 
-	if r.Body == nil {
-		writeResponse(nil, errors.New("request must include a query"))
+	output, err := h.executeParams(addBatchCache(ctx), r, &params)
+	if err != nil || len(deferred) == 0 {
+		writeResponse(output, err)
 		return
 	}
 
-	var params httpPostBody
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		writeResponse(nil, err)
+	h.writeIncremental(ctx, w, output, deferred, requestID)
+}
+
+// serveBatch executes every operation of a batch in order, and responds with an array
+// holding the response of every operation. A failing operation does not affect the others.
+// The operations share the BatchCache of ctx, unless they are isolated.
+func (h *httpHandler) serveBatch(ctx context.Context, w http.ResponseWriter, r *http.Request, body []byte, requestID string) {
+	var batch []httpPostBody
+	if err := h.decodeBody(bytes.NewReader(body), &batch); err != nil {
+		response, status := h.newResponse(ctx, nil, err, requestID)
+		h.writeJSON(w, r, status, response)
 		return
 	}
+	if len(batch) == 0 {
+		response, status := h.newResponse(ctx, nil, errors.New("batch must contain at least one operation"), requestID)
+		h.writeJSON(w, r, status, response)
+		return
+	}
+
+	responses := make([]httpResponse, len(batch))
+	for i := range batch {
+		operationCtx := addExtensions(ctx)
+		if h.isolatedBatchCaches {
+			operationCtx = addBatchCache(operationCtx)
+		}
+		output, err := h.executeParams(operationCtx, r, &batch[i])
+		responses[i], _ = h.newResponse(operationCtx, output, err, requestID)
+	}
+	h.writeJSON(w, r, http.StatusOK, responses)
+}
+
+// executeParams parses, validates and executes the operation of a request.
+func (h *httpHandler) executeParams(ctx context.Context, r *http.Request, params *httpPostBody) (output interface{}, err error) {
+	var stats *OperationStats
+	if h.operationObserver != nil {
+		stats = &OperationStats{OperationName: params.OperationName}
+		defer func() {
+			stats.Errors = errorCount(err)
+			h.operationObserver(*stats)
+		}()
+	}
+
+	var trace *tracing
+	if h.tracing {
+		trace = &tracing{start: time.Now()}
+		ctx = addTracing(ctx, trace)
+		defer func() {
+			SetExtension(ctx, "tracing", trace.extension())
+		}()
+	}
 
-	query, err := graphql.Parse(params.Query, params.Variables)
+	if err := h.checkVariablesSize(params.Variables); err != nil {
+		return nil, err
+	}
+
+	var key string
+	if h.queryCache != nil {
+		key = queryCacheKey(params)
+	}
+	query, ok := h.queryCache.get(key)
+	if !ok {
+		if query, err = h.prepareQuery(ctx, params, trace); err != nil {
+			return nil, err
+		}
+		h.queryCache.add(key, query)
+	} else {
+		trace.parsed()
+		trace.validated()
+	}
+
+	root := h.rootType(query)
+	if stats != nil {
+		h.observe(stats, root, query)
+	}
+
+	// GET requests must be free of side effects.
+	if r.Method == http.MethodGet && query.Kind != "query" {
+		return nil, fmt.Errorf("%s operations must be sent as a POST", query.Kind)
+	}
+
+	ctx = addVariables(ctx, query.Variables)
+
+	if h.executionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.executionTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	output, err = h.exec(ctx, root, query)
+	if stats != nil {
+		stats.Duration = time.Since(start)
+	}
+	if err == context.DeadlineExceeded {
+		return nil, &jerrors.Error{
+			Message:    "execution timed out",
+			Extensions: &jerrors.Extension{Code: jerrors.CodeTimeout},
+			Paths:      []string{},
+		}
+	}
+	return output, err
+}
+
+// prepareQuery parses and validates the operation of a request.
+func (h *httpHandler) prepareQuery(ctx context.Context, params *httpPostBody, trace *tracing) (*graphql.Query, error) {
+	query, err := graphql.ParseOperation(params.Query, params.OperationName, params.Variables)
 	if err != nil {
-		writeResponse(nil, err)
-		return
+		return nil, err
+	}
+	trace.parsed()
+
+	if h.strictVariables {
+		if err := checkDeclaredVariables(query, params.Variables); err != nil {
+			return nil, err
+		}
 	}
 
-	root := h.schema.Query
+	if h.maxSelectionNodes > 0 && graphql.CountSelections(query.SelectionSet, h.maxSelectionNodes) > h.maxSelectionNodes {
+		return nil, fmt.Errorf("query exceeds the maximum of %d selection nodes", h.maxSelectionNodes)
+	}
+
+	if h.maxDepth > 0 {
+		if err := graphql.CheckDepth(query.SelectionSet, h.maxDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	root := h.rootType(query)
+	validateCtx := ctx
+	if h.introspectionDisabled {
+		validateCtx = graphql.WithIntrospectionDisabled(ctx)
+	}
+	if err := graphql.ValidateQuery(validateCtx, root, query.SelectionSet); err != nil {
+		return nil, err
+	}
+	if err := graphql.ValidateDirectives(h.schema.Directives, query.SelectionSet); err != nil {
+		return nil, err
+	}
+
+	if err := h.checkComplexity(root, query.SelectionSet); err != nil {
+		return nil, err
+	}
+
+	trace.validated()
+	return query, nil
+}
+
+// rootType returns the root type of the operation query.
+func (h *httpHandler) rootType(query *graphql.Query) graphql.Type {
 	if query.Kind == "mutation" {
-		root = h.schema.Mutation
+		return h.schema.Mutation
+	}
+	return h.schema.Query
+}
+
+// newResponse builds the response to an operation, along with its HTTP status.
+func (h *httpHandler) newResponse(ctx context.Context, value interface{}, err error, requestID string) (httpResponse, int) {
+	// The value of an operation failing partially, such as when resolvers panic, is kept
+	// alongside its errors.
+	response := httpResponse{Data: value, Extensions: extensionsFromContext(ctx)}
+	status := http.StatusOK
+	if multi := jerrors.ConvertMultiError(err); multi != nil {
+		for _, jerr := range multi.Errors {
+			response.Errors = append(response.Errors, withRequestID(h.formatError(ctx, jerr), requestID))
+		}
+	} else if err != nil {
+		jerr := h.formatError(ctx, err)
+		if h.statusMapper != nil {
+			if s := h.statusMapper(jerr); s != 0 {
+				status = s
+			}
+		}
+		response.Errors = []*jerrors.Error{withRequestID(jerr, requestID)}
+	}
+
+	return response, status
+}
+
+// formatError converts err with the function passed to WithErrorFormatter, falling back to
+// jerrors.ConvertError.
+func (h *httpHandler) formatError(ctx context.Context, err error) *jerrors.Error {
+	if h.errorFormatter != nil {
+		if jerr := h.errorFormatter(ctx, err); jerr != nil {
+			return jerr
+		}
 	}
+	return jerrors.ConvertError(err)
+}
+
+// compressionThreshold is the size in bytes from which responses are compressed when
+// WithResponseCompression is set.
+const compressionThreshold = 1024
 
-	if err := graphql.ValidateQuery(r.Context(), root, query.SelectionSet); err != nil {
-		writeResponse(nil, err)
+// writeJSON writes v as the JSON response to r, compressing it with gzip if enabled and
+// accepted by the client.
+func (h *httpHandler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	responseJSON, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if !h.compress || len(responseJSON) < compressionThreshold || !acceptsGzip(r) {
+		w.WriteHeader(status)
+		_, _ = w.Write(responseJSON)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	gz := gzip.NewWriter(w)
+	_, _ = gz.Write(responseJSON)
+	_ = gz.Close()
+}
+
+// isBatch reports whether the request body holds an array of operations.
+func isBatch(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// incrementalPayload is a part of a multipart/mixed response to a query using @defer.
+type incrementalPayload struct {
+	Data       interface{}            `json:"data"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Label      string                 `json:"label,omitempty"`
+	Errors     []*jerrors.Error       `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	HasNext    bool                   `json:"hasNext"`
+}
+
+// writeIncremental writes the initial response followed by a patch for every deferred
+// fragment, as the parts of a multipart/mixed response.
+func (h *httpHandler) writeIncremental(ctx context.Context, w http.ResponseWriter, data interface{}, deferred []graphql.Deferred, requestID string) {
+	w.Header().Set("Content-Type", `multipart/mixed; boundary="-"`)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	writePart := func(payload incrementalPayload) bool {
+		part, err := json.Marshal(payload)
+		if err != nil {
+			return false
+		}
+
+		if _, err := fmt.Fprintf(w, "\r\n---\r\nContent-Type: application/json; charset=utf-8\r\n\r\n%s", part); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	if !writePart(incrementalPayload{Data: data, Extensions: extensionsFromContext(ctx), HasNext: true}) {
+		return
+	}
+
+	for i, d := range deferred {
+		patch := d(ctx)
+		payload := incrementalPayload{
+			Data:    patch.Data,
+			Path:    patch.Path,
+			Label:   patch.Label,
+			HasNext: i < len(deferred)-1,
+		}
+		if patch.Err != nil {
+			payload.Errors = []*jerrors.Error{withRequestID(h.formatError(ctx, patch.Err), requestID)}
+		}
+		if !writePart(payload) {
+			return
+		}
+	}
+
+	_, _ = io.WriteString(w, "\r\n-----\r\n")
+}
+
+// acceptsMultipart reports whether the client accepts multipart/mixed responses to queries using @defer.
+func acceptsMultipart(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "multipart/mixed")
+}
+
+// isGraphQLBody reports whether the body of the request is a raw query, sent with the
+// application/graphql content type, rather than JSON.
+func isGraphQLBody(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/graphql"
+}
+
+// acceptsHTML reports whether the request is made by a browser which accepts HTML.
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// acceptsGzip reports whether the client accepts responses compressed with gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding = strings.TrimSpace(encoding)
+		if i := strings.Index(encoding, ";"); i >= 0 {
+			if strings.TrimSpace(encoding[i+1:]) == "q=0" {
+				continue
+			}
+			encoding = strings.TrimSpace(encoding[:i])
+		}
+		if encoding == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody decodes the request body into params. In strict mode unknown fields
+// and trailing data after the JSON value are rejected.
+func (h *httpHandler) decodeBody(body io.Reader, params interface{}) error {
+	decoder := json.NewDecoder(body)
+	if !h.strict {
+		return decoder.Decode(params)
+	}
+
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(params); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("request body must contain a single JSON object")
+	}
+	return nil
+}
+
+// checkComplexity rejects queries exceeding the complexity set with WithMaxComplexity.
+func (h *httpHandler) checkComplexity(root graphql.Type, selectionSet *graphql.SelectionSet) error {
+	if h.maxComplexity <= 0 {
+		return nil
+	}
 
-	ctx := addVariables(r.Context(), params.Variables)
+	complexity, err := graphql.ComplexityWithListFactor(root, selectionSet, h.listComplexityFactor)
+	if err != nil {
+		return err
+	}
+	if complexity > h.maxComplexity {
+		return &jerrors.Error{
+			Message:    fmt.Sprintf("query has a complexity of %d, which exceeds the maximum of %d", complexity, h.maxComplexity),
+			Extensions: &jerrors.Extension{Code: jerrors.CodeQueryTooComplex},
+			Paths:      []string{},
+		}
+	}
+	return nil
+}
+
+// readBody reads the body of r, up to the size set with WithMaxBodyBytes.
+func (h *httpHandler) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if h.maxBodyBytes <= 0 {
+		return ioutil.ReadAll(r.Body)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodyBytes))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return nil, &bodyTooLargeError{limit: h.maxBodyBytes}
+	}
+	return body, err
+}
+
+// bodyTooLargeError is the error of a request whose body exceeds the size set with
+// WithMaxBodyBytes. It has the BAD_REQUEST code, and the status 413.
+type bodyTooLargeError struct {
+	limit int64
+}
+
+func (e *bodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body exceeds the maximum of %d bytes", e.limit)
+}
+
+func (e *bodyTooLargeError) HTTPStatus() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+func (e *bodyTooLargeError) Unwrap() error {
+	return &jerrors.Error{
+		Message:    e.Error(),
+		Extensions: &jerrors.Extension{Code: jerrors.CodeBadRequest},
+		Paths:      []string{},
+	}
+}
+
+// decodeURLParams reads the query, the JSON encoded variables and the operation name from
+// the URL of a GET request.
+func decodeURLParams(r *http.Request, params *httpPostBody) error {
+	values := r.URL.Query()
+	params.Query = values.Get("query")
+	params.OperationName = values.Get("operationName")
+
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+			return fmt.Errorf("variables must be a JSON object: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkDeclaredVariables rejects the variables which are not defined by the operation query.
+func checkDeclaredVariables(query *graphql.Query, variables map[string]interface{}) error {
+	declared := make(map[string]bool, len(query.DeclaredVariables))
+	for _, name := range query.DeclaredVariables {
+		declared[name] = true
+	}
 
-	output, err := h.exec(ctx, root, query)
-	writeResponse(output, err)
+	var unknown []string
+	for name := range variables {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown variable: $%s", strings.Join(unknown, ", $"))
+}
+
+// checkVariablesSize rejects variables larger than the limit set with WithMaxVariablesBytes.
+func (h *httpHandler) checkVariablesSize(variables map[string]interface{}) error {
+	if h.maxVariablesBytes <= 0 || variables == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	if len(data) > h.maxVariablesBytes {
+		return fmt.Errorf("variables exceed the maximum of %d bytes", h.maxVariablesBytes)
+	}
+	return nil
 }
 
 func (h *httpHandler) execute(ctx context.Context, root graphql.Type, query *graphql.Query) (interface{}, error) {
-	return h.executor.Execute(ctx, root, nil, query)
+	// Every request is executed by its own copy of the executor, which holds the state of
+	// the execution.
+	executor := *h.executor
+
+	if deferred, ok := ctx.Value(deferredKey).(*[]graphql.Deferred); ok {
+		output, d, err := executor.ExecuteIncremental(ctx, root, nil, query)
+		*deferred = d
+		return output, err
+	}
+
+	return executor.Execute(ctx, root, nil, query)
 }
 
 type graphqlVariableKeyType int
 
-const graphqlVariableKey graphqlVariableKeyType = 0
+const (
+	graphqlVariableKey graphqlVariableKeyType = iota
+	requestIDKey
+	deferredKey
+	extensionsKey
+	tracingKey
+	batchCacheKey
+)
 
 // ExtractVariables is used to returns the variables received as part of the graphql request.
 // This is intended to be used from within the interceptors.
@@ -136,6 +931,82 @@ func ExtractVariables(ctx context.Context) map[string]interface{} {
 	return nil
 }
 
+// FieldPathFromContext returns the path to the field being resolved, including the
+// indices of list elements. This is intended to be used from within resolvers.
+func FieldPathFromContext(ctx context.Context) []interface{} {
+	return graphql.FieldPathFromContext(ctx)
+}
+
 func addVariables(ctx context.Context, v map[string]interface{}) context.Context {
 	return context.WithValue(ctx, graphqlVariableKey, v)
 }
+
+// RequestIDFromContext returns the ID computed for the request by the function passed to WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	if v := ctx.Value(requestIDKey); v != nil {
+		return v.(string)
+	}
+
+	return ""
+}
+
+func addRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// responseExtensions holds the extensions set with SetExtension while executing an operation.
+type responseExtensions struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// SetExtension sets the entry key of the extensions of the response to value, replacing the
+// value previously set for key, e.g. to report tracing data or the cost of the query. It is
+// intended to be called from within resolvers and field middlewares, and may be called
+// concurrently. It does nothing outside of an operation executed by the HTTPHandler.
+func SetExtension(ctx context.Context, key string, value interface{}) {
+	extensions, ok := ctx.Value(extensionsKey).(*responseExtensions)
+	if !ok {
+		return
+	}
+
+	extensions.mu.Lock()
+	defer extensions.mu.Unlock()
+	if extensions.values == nil {
+		extensions.values = make(map[string]interface{})
+	}
+	extensions.values[key] = value
+}
+
+func addExtensions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, extensionsKey, &responseExtensions{})
+}
+
+// extensionsFromContext returns the extensions set with SetExtension, or nil if none was set.
+func extensionsFromContext(ctx context.Context) map[string]interface{} {
+	extensions, ok := ctx.Value(extensionsKey).(*responseExtensions)
+	if !ok {
+		return nil
+	}
+
+	extensions.mu.Lock()
+	defer extensions.mu.Unlock()
+	return extensions.values
+}
+
+// withRequestID returns a copy of err with the request id added to its extensions.
+func withRequestID(err *jerrors.Error, id string) *jerrors.Error {
+	if id == "" {
+		return err
+	}
+
+	stamped := *err
+	extensions := jerrors.Extension{}
+	if err.Extensions != nil {
+		extensions = *err.Extensions
+	}
+	extensions.RequestID = id
+	stamped.Extensions = &extensions
+
+	return &stamped
+}
@@ -1,17 +1,28 @@
 package jaal_test
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/kylelemons/godebug/pretty"
 	"go.appointy.com/jaal"
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
 	"go.appointy.com/jaal/schemabuilder"
 )
 
-func testHTTPRequest(req *http.Request) *httptest.ResponseRecorder {
+func testHTTPRequest(req *http.Request, opts ...jaal.HandlerOption) *httptest.ResponseRecorder {
 	schema := schemabuilder.NewSchema()
 
 	query := schema.Query()
@@ -22,26 +33,56 @@ func testHTTPRequest(req *http.Request) *httptest.ResponseRecorder {
 	builtSchema := schema.MustBuild()
 
 	rr := httptest.NewRecorder()
-	handler := jaal.HTTPHandler(builtSchema)
+	handler := jaal.HTTPHandler(builtSchema, opts...)
 
 	handler.ServeHTTP(rr, req)
 	return rr
 }
 
 func TestHTTPMustPost(t *testing.T) {
-	req, err := http.NewRequest("GET", "/graphql", nil)
+	for _, tc := range []struct {
+		name   string
+		accept string
+		opts   []jaal.HandlerOption
+	}{
+		{name: "non-browser"},
+		{name: "playground disabled", accept: "text/html", opts: []jaal.HandlerOption{jaal.WithPlayground(false)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/graphql", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Accept", tc.accept)
+
+			rr := testHTTPRequest(req, tc.opts...)
+
+			if rr.Code != 200 {
+				t.Errorf("expected 200, but received %d", rr.Code)
+			}
+
+			if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request must be a POST","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPPlayground(t *testing.T) {
+	req, err := http.NewRequest("GET", "/api/graphql", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
 
-	rr := testHTTPRequest(req)
+	rr := testHTTPRequest(req, jaal.WithPlaygroundTitle("Jaal"))
 
-	if rr.Code != 200 {
-		t.Errorf("expected 200, but received %d", rr.Code)
+	if diff := pretty.Compare(rr.Header().Get("Content-Type"), "text/html; charset=utf-8"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
 	}
 
-	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request must be a POST","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
-		t.Errorf("expected response to match, but received %s", diff)
+	if !strings.Contains(rr.Body.String(), "<title>Jaal</title>") || !strings.Contains(rr.Body.String(), `endpoint: "/api/graphql"`) {
+		t.Errorf("expected playground to have the title and reference the endpoint, but received %s", rr.Body.String())
 	}
 }
 
@@ -79,8 +120,33 @@ func TestHTTPMustHaveQuery(t *testing.T) {
 	}
 }
 
+func TestHTTPOperationName(t *testing.T) {
+	body := `{"query": "query A { a: mirror(value: 1) } query B { b: mirror(value: 2) }", "operationName": "B"}`
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"b":-2},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query A { a: mirror(value: 1) } query B { b: mirror(value: 2) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"must provide operation name","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
 func TestHTTPSuccess(t *testing.T) {
-	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: int64) { mirror(value: $value) }", "variables": { "value": 1 }}`))
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: Int!) { mirror(value: $value) }", "variables": { "value": 1 }}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -97,7 +163,7 @@ func TestHTTPSuccess(t *testing.T) {
 }
 
 func TestHTTPContentType(t *testing.T) {
-	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: int64) { mirror(value: $value) }", "variables": { "value": 1 }}`))
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query TestQuery($value: Int!) { mirror(value: $value) }", "variables": { "value": 1 }}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -112,3 +178,1454 @@ func TestHTTPContentType(t *testing.T) {
 		t.Errorf("expected response to match, but received %s", diff)
 	}
 }
+
+func TestHTTPStrictDecodingUnknownField(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }", "extra": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithStrictRequestDecoding())
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"json: unknown field \"extra\"","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }", "extra": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPStrictDecodingTrailingData(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }"} {"query": "{ mirror(value: 2) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithStrictRequestDecoding())
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request body must contain a single JSON object","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPStrictVariables(t *testing.T) {
+	body := `{"query": "query($value: Int!) { mirror(value: $value) }", "variables": {"value": 1, "limitt": 5}}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithStrictVariables())
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"unknown variable: $limitt","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// The variables which are not defined are ignored by default.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPMaxBodyBytes(t *testing.T) {
+	body := `{"query": "{ mirror(value: 1) }", "variables": {"padding": "` + strings.Repeat("x", 64) + `"}}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := testHTTPRequest(req, jaal.WithMaxBodyBytes(32))
+
+	if diff := pretty.Compare(rr.Code, http.StatusOK); diff != "" {
+		t.Errorf("expected status to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"request body exceeds the maximum of 32 bytes","extensions":{"code":"BAD_REQUEST"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// The status is 413 when responding with HTTP statuses.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = testHTTPRequest(req, jaal.WithMaxBodyBytes(32), jaal.WithHTTPStatusCodes())
+
+	if diff := pretty.Compare(rr.Code, http.StatusRequestEntityTooLarge); diff != "" {
+		t.Errorf("expected status to match, but received %s", diff)
+	}
+
+	// Bodies are limited to 1 MiB by default.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }", "variables": {"padding": "`+strings.Repeat("x", 1<<20)+`"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = testHTTPRequest(req)
+
+	if !strings.Contains(rr.Body.String(), "request body exceeds the maximum of 1048576 bytes") {
+		t.Errorf("expected the body to exceed the default maximum, but received %s", rr.Body.String())
+	}
+
+	// Larger bodies are accepted with a higher limit.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = testHTTPRequest(req, jaal.WithMaxBodyBytes(1024))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestFieldPathFromContext(t *testing.T) {
+	type Item struct {
+		Name string
+	}
+	type Order struct {
+		Items []*Item
+	}
+
+	var paths [][]interface{}
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("order", func(ctx context.Context) *Order {
+		return &Order{Items: []*Item{{Name: "a"}, {Name: "b"}}}
+	})
+	order := schema.Object("Order", Order{})
+	order.FieldFunc("items", func(ctx context.Context, in *Order) []*Item {
+		return in.Items
+	})
+	item := schema.Object("Item", Item{})
+	item.FieldFunc("name", func(ctx context.Context, in *Item) string {
+		paths = append(paths, jaal.FieldPathFromContext(ctx))
+		return in.Name
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ last: order { items { title: name } } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild()).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"last":{"items":[{"title":"a"},{"title":"b"}]}},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	if diff := pretty.Compare(paths, [][]interface{}{
+		{"last", "items", 0, "title"},
+		{"last", "items", 1, "title"},
+	}); diff != "" {
+		t.Errorf("expected paths to match, but received %s", diff)
+	}
+}
+
+func TestHTTPRequestID(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("fail", func(ctx context.Context) (string, error) {
+		return "", errors.New("failed request " + jaal.RequestIDFromContext(ctx))
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ fail }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Request-Id", "req-1")
+
+	rr := httptest.NewRecorder()
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithRequestID(func(r *http.Request) string {
+		return r.Header.Get("X-Request-Id")
+	}))
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"failed request req-1","extensions":{"code":"Unknown","requestId":"req-1"},"paths":["fail"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPContextFunc(t *testing.T) {
+	type userKey struct{}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("greet", func(ctx context.Context, args struct{ Greeting string }) string {
+		user, _ := ctx.Value(userKey{}).(string)
+		return args.Greeting + " " + user
+	})
+
+	var seen []interface{}
+	handler := jaal.HTTPHandler(schema.MustBuild(),
+		jaal.WithContextFunc(func(r *http.Request) context.Context {
+			return context.WithValue(r.Context(), userKey{}, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		}),
+		jaal.WithMiddlewares(func(next jaal.HandlerFunc) jaal.HandlerFunc {
+			return func(ctx context.Context, typ graphql.Type, query *graphql.Query) (interface{}, error) {
+				seen = append(seen, ctx.Value(userKey{}), jaal.ExtractVariables(ctx)["greeting"])
+				return next(ctx, typ, query)
+			}
+		}),
+	)
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "query($greeting: String!) { greet(greeting: $greeting) }", "variables": {"greeting": "Hello"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer harry")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"greet":"Hello harry"},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(seen, []interface{}{"harry", "Hello"}); diff != "" {
+		t.Errorf("expected the middleware to read the context, but received %s", diff)
+	}
+}
+
+func TestHTTPMaxSelectionNodes(t *testing.T) {
+	const query = `{"query": "{ a: mirror(value: 1) ...F ...F } fragment F on Query { b: mirror(value: 2) c: mirror(value: 3) }"}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithMaxSelectionNodes(4))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"query exceeds the maximum of 4 selection nodes","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req, jaal.WithMaxSelectionNodes(5))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"a":-1,"b":-2,"c":-3},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestPlaygroundHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/playground", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.PlaygroundHandler("/api/graphql").ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, but received %d", rr.Code)
+	}
+
+	if diff := pretty.Compare(rr.Header().Get("Content-Type"), "text/html; charset=utf-8"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	if !strings.Contains(rr.Body.String(), `endpoint: "/api/graphql"`) {
+		t.Errorf("expected playground to reference the endpoint, but received %s", rr.Body.String())
+	}
+}
+
+type unauthenticatedError struct{}
+
+func (unauthenticatedError) Error() string   { return "not authenticated" }
+func (unauthenticatedError) HTTPStatus() int { return http.StatusUnauthorized }
+
+func TestHTTPStatusCodes(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("me", func(ctx context.Context) (string, error) {
+		return "", unauthenticatedError{}
+	})
+	builtSchema := schema.MustBuild()
+
+	for _, tt := range []struct {
+		name   string
+		opts   []jaal.HandlerOption
+		status int
+	}{
+		{name: "default", status: http.StatusOK},
+		{name: "status codes", opts: []jaal.HandlerOption{jaal.WithHTTPStatusCodes()}, status: http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ me }"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			jaal.HTTPHandler(builtSchema, tt.opts...).ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("expected %d, but received %d", tt.status, rr.Code)
+			}
+
+			if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"not authenticated","extensions":{"code":"Unknown"},"paths":["me"]}]}`); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPMaxVariablesBytes(t *testing.T) {
+	body := `{"query": "query TestQuery($value: Int!) { mirror(value: $value) }", "variables": {"value": 1, "padding": "` + strings.Repeat("x", 64) + `"}}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithMaxVariablesBytes(32))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"variables exceed the maximum of 32 bytes","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req, jaal.WithMaxVariablesBytes(1024))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPDefer(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("user", func() *User {
+		return &User{Name: "jaal"}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string {
+		return in.Name
+	})
+	user.FieldFunc("friends", func(in *User) int64 {
+		return 3
+	})
+	builtSchema := schema.MustBuild()
+
+	const query = `{"query": "{ user { name ... @defer(label: \"friends\") { friends } } }"}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "multipart/mixed")
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(builtSchema).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Header().Get("Content-Type"), `multipart/mixed; boundary="-"`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	expected := "\r\n---\r\nContent-Type: application/json; charset=utf-8\r\n\r\n" +
+		`{"data":{"user":{"name":"jaal"}},"hasNext":true}` +
+		"\r\n---\r\nContent-Type: application/json; charset=utf-8\r\n\r\n" +
+		`{"data":{"friends":3},"path":["user"],"label":"friends","hasNext":false}` +
+		"\r\n-----\r\n"
+	if diff := pretty.Compare(rr.Body.String(), expected); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = httptest.NewRecorder()
+	jaal.HTTPHandler(builtSchema).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"user":{"name":"jaal","friends":3}},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+type validationErrors []error
+
+func (e validationErrors) Error() string   { return "validation failed" }
+func (e validationErrors) Errors() []error { return e }
+
+func TestHTTPMultipleErrors(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("validate", func(ctx context.Context) (bool, error) {
+		return false, validationErrors{
+			errors.New("name is required"),
+			errors.New("email is invalid"),
+			&jerrors.Error{Message: "item is out of stock", Paths: []string{"items", "2"}, Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput}},
+		}
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ check: validate }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild()).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[`+
+		`{"message":"name is required","extensions":{"code":"Unknown"},"paths":["check"]},`+
+		`{"message":"email is invalid","extensions":{"code":"Unknown"},"paths":["check"]},`+
+		`{"message":"item is out of stock","extensions":{"code":"BAD_USER_INPUT"},"paths":["check","items",2]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPGetQuery(t *testing.T) {
+	params := url.Values{}
+	params.Set("query", "query TestQuery($value: Int!) { mirror(value: $value) }")
+	params.Set("variables", `{"value": 1}`)
+
+	req, err := http.NewRequest("GET", "/graphql?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	params.Set("variables", `[1]`)
+	req, err = http.NewRequest("GET", "/graphql?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"variables must be a JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPGetRejectsMutation(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("value", func() int64 { return 1 })
+	schema.Mutation().FieldFunc("increment", func() int64 {
+		t.Error("mutation must not be executed")
+		return 2
+	})
+
+	req, err := http.NewRequest("GET", "/graphql?query="+url.QueryEscape("mutation { increment }"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild()).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"mutation operations must be sent as a POST","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPInvalidInputPreventsResolvers(t *testing.T) {
+	type Address struct {
+		City string
+	}
+	type User struct {
+		Name    string
+		Address *Address
+	}
+	type Account struct {
+		Id int64
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query()
+
+	address := schema.InputObject("AddressInput", Address{})
+	address.FieldFunc("city", func(target *Address, source string) error {
+		if source == "" {
+			return errors.New("city is required")
+		}
+		target.City = source
+		return nil
+	})
+
+	user := schema.InputObject("UserInput", User{})
+	user.FieldFunc("name", func(target *User, source string) {
+		target.Name = source
+	})
+	user.FieldFunc("address", func(target *User, source *Address) {
+		target.Address = source
+	})
+
+	var created []string
+	account := schema.Object("Account", Account{})
+	account.FieldFunc("id", func(in *Account) int64 {
+		return in.Id
+	})
+	account.FieldFunc("moveTo", func(args struct{ Address *Address }) bool {
+		created = append(created, "moveTo "+args.Address.City)
+		return true
+	})
+
+	mutation := schema.Mutation()
+	mutation.FieldFunc("createUser", func(args struct{ Input *User }) *Account {
+		created = append(created, args.Input.Name)
+		return &Account{Id: int64(len(created))}
+	})
+
+	builtSchema := schema.MustBuild()
+
+	for _, tc := range []struct {
+		name  string
+		query string
+	}{
+		{
+			name:  "later root field",
+			query: `mutation { first: createUser(input: {name: \"Harry\", address: {city: \"London\"}}) { id } second: createUser(input: {name: \"Ron\", address: {city: \"\"}}) { id } }`,
+		},
+		{
+			name:  "nested selection",
+			query: `mutation { createUser(input: {name: \"Harry\", address: {city: \"London\"}}) { id moveTo(address: {city: \"\"}) } }`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			created = nil
+
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "`+tc.query+`"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			jaal.HTTPHandler(builtSchema).ServeHTTP(rr, req)
+
+			if !strings.Contains(rr.Body.String(), "city is required") {
+				t.Errorf("expected city validation error, received %s", rr.Body.String())
+			}
+			if len(created) != 0 {
+				t.Errorf("expected no resolver to run, but ran %v", created)
+			}
+		})
+	}
+}
+
+func TestHTTPBatch(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(` [
+		{"query": "{ mirror(value: 1) }"},
+		{"query": "{ unknown }"},
+		{"query": "query Q($value: Int!) { mirror(value: $value) }", "variables": {"value": 3}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `[`+
+		`{"data":{"mirror":-1},"errors":null},`+
+		`{"data":null,"errors":[{"message":"unknown field \"unknown\"","extensions":{"code":"Unknown"},"paths":[],"locations":[{"line":1,"column":3}]}]},`+
+		`{"data":{"mirror":-3},"errors":null}]`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`[]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"batch must contain at least one operation","extensions":{"code":"Unknown"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+// testUserCache caches the names of users, and records the ids it fetches.
+type testUserCache struct {
+	mu      sync.Mutex
+	cache   map[int64]string
+	fetched *[]int64
+}
+
+func (l *testUserCache) load(id int64) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	name, ok := l.cache[id]
+	if !ok {
+		*l.fetched = append(*l.fetched, id)
+		name = fmt.Sprintf("user-%d", id)
+		l.cache[id] = name
+	}
+	return name
+}
+
+func TestHTTPBatchCache(t *testing.T) {
+	type userCacheKey struct{}
+
+	var fetched []int64
+
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("user", func(ctx context.Context, args struct{ Id int64 }) string {
+		users := jaal.BatchCacheFromContext(ctx).Load(userCacheKey{}, func() interface{} {
+			return &testUserCache{cache: make(map[int64]string), fetched: &fetched}
+		}).(*testUserCache)
+		return users.load(args.Id)
+	})
+	builtSchema := schema.MustBuild()
+
+	const body = `[
+		{"query": "{ a: user(id: 1) b: user(id: 2) }"},
+		{"query": "{ a: user(id: 2) b: user(id: 3) }"},
+		{"query": "{ a: user(id: 1) }"}
+	]`
+
+	for _, tc := range []struct {
+		name    string
+		opts    []jaal.HandlerOption
+		fetched []int64
+	}{
+		// The operations are executed in order, so a shared cache fetches every id once, in
+		// the order the operations first use it.
+		{name: "shared", fetched: []int64{1, 2, 3}},
+		{name: "isolated", opts: []jaal.HandlerOption{jaal.WithIsolatedBatchCaches()}, fetched: []int64{1, 2, 2, 3, 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fetched = nil
+
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			jaal.HTTPHandler(builtSchema, tc.opts...).ServeHTTP(rr, req)
+
+			if diff := pretty.Compare(rr.Body.String(), `[`+
+				`{"data":{"a":"user-1","b":"user-2"},"errors":null},`+
+				`{"data":{"a":"user-2","b":"user-3"},"errors":null},`+
+				`{"data":{"a":"user-1"},"errors":null}]`); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+			if diff := pretty.Compare(fetched, tc.fetched); diff != "" {
+				t.Errorf("expected fetched ids to match, but received %s", diff)
+			}
+		})
+	}
+
+	// A single request has a cache of its own.
+	fetched = nil
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ user(id: 1) }"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		jaal.HTTPHandler(builtSchema).ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if diff := pretty.Compare(fetched, []int64{1, 1}); diff != "" {
+		t.Errorf("expected fetched ids to match, but received %s", diff)
+	}
+}
+
+func TestHTTPMaxComplexity(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ a: mirror(value: 1) b: mirror(value: 2) c: mirror(value: 3) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithMaxComplexity(2))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"query has a complexity of 3, which exceeds the maximum of 2","extensions":{"code":"QUERY_TOO_COMPLEX"},"paths":[]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ a: mirror(value: 1) b: mirror(value: 2) c: mirror(value: 3) @skip(if: true) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req, jaal.WithMaxComplexity(2))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"a":-1,"b":-2},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPMaxDepth(t *testing.T) {
+	const query = `{"query": "{ a: mirror(value: 1) ...F } fragment F on Query { ...G } fragment G on Query { b: mirror(value: 2) }"}`
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(query))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithMaxDepth(1))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"a":-1,"b":-2},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPPanicHandler(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("ok", func() string { return "ok" })
+	query.FieldFunc("panic", func() string { panic("secret") })
+
+	var recovered interface{}
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithPanicHandler(func(ctx context.Context, value interface{}, stack []byte) {
+		recovered = value
+	}))
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ ok panic }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"ok":"ok","panic":null},"errors":[{"message":"internal server error","extensions":{"code":"Internal"},"paths":["panic"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if recovered != "secret" {
+		t.Errorf("expected the panic to be passed to the handler, but received %v", recovered)
+	}
+}
+
+func TestHTTPFieldMiddlewares(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ a: mirror(value: 1) b: mirror(value: 2) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields []string
+	rr := testHTTPRequest(req, jaal.WithFieldMiddlewares(func(ctx context.Context, info graphql.FieldInfo, next func() (interface{}, error)) (interface{}, error) {
+		fields = append(fields, info.Alias)
+		if info.Alias == "b" {
+			return int64(0), nil
+		}
+		return next()
+	}))
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"a":-1,"b":0},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(fields, []string{"a", "b"}); diff != "" {
+		t.Errorf("expected every field to be intercepted, but received %s", diff)
+	}
+}
+
+var ErrUnauthorized = errors.New("unauthorized")
+
+func TestHTTPErrorFormatter(t *testing.T) {
+	authenticate := func(next jaal.HandlerFunc) jaal.HandlerFunc {
+		return func(ctx context.Context, typ graphql.Type, query *graphql.Query) (interface{}, error) {
+			return nil, ErrUnauthorized
+		}
+	}
+	formatter := jaal.WithErrorFormatter(func(ctx context.Context, err error) *jerrors.Error {
+		if !errors.Is(err, ErrUnauthorized) {
+			return nil
+		}
+		jerr := jerrors.ConvertError(err)
+		jerr.Extensions = &jerrors.Extension{Code: "UNAUTHENTICATED"}
+		return jerr
+	})
+
+	for _, tt := range []struct {
+		name     string
+		query    string
+		opts     []jaal.HandlerOption
+		expected string
+	}{
+		{
+			name:     "mapped",
+			query:    `{"query": "{ mirror(value: 1) }"}`,
+			opts:     []jaal.HandlerOption{jaal.WithMiddlewares(authenticate), formatter},
+			expected: `{"data":null,"errors":[{"message":"unauthorized","extensions":{"code":"UNAUTHENTICATED"},"paths":[]}]}`,
+		},
+		{
+			name:     "fallback",
+			query:    `{"query": "{ missing }"}`,
+			opts:     []jaal.HandlerOption{formatter},
+			expected: `{"data":null,"errors":[{"message":"unknown field \"missing\"","extensions":{"code":"Unknown"},"paths":[],"locations":[{"line":1,"column":3}]}]}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(tt.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := testHTTPRequest(req, tt.opts...)
+
+			if diff := pretty.Compare(rr.Body.String(), tt.expected); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusMapper(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("secret", func(ctx context.Context) (string, error) {
+		return "", &jerrors.Error{Message: "forbidden", Extensions: &jerrors.Extension{Code: jerrors.CodeForbidden}}
+	})
+	builtSchema := schema.MustBuild()
+
+	for _, tt := range []struct {
+		name   string
+		opts   []jaal.HandlerOption
+		status int
+	}{
+		{name: "default", status: http.StatusOK},
+		{name: "status codes", opts: []jaal.HandlerOption{jaal.WithHTTPStatusCodes()}, status: http.StatusForbidden},
+		{name: "mapper", opts: []jaal.HandlerOption{jaal.WithHTTPStatusMapper(func(err *jerrors.Error) int {
+			return http.StatusNotFound
+		})}, status: http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ secret }"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			jaal.HTTPHandler(builtSchema, tt.opts...).ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("expected %d, but received %d", tt.status, rr.Code)
+			}
+
+			if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"forbidden","extensions":{"code":"FORBIDDEN"},"paths":["secret"]}]}`); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPExecutionTimeout(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	query := schema.Query()
+	query.FieldFunc("fast", func() string { return "ok" })
+	query.FieldFunc("slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithExecutionTimeout(10*time.Millisecond))
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ fast slow }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"fast":"ok","slow":null},"errors":[{"message":"execution timed out","extensions":{"code":"TIMEOUT"},"paths":["slow"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPVariableDefaultValue(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("limit", func(ctx context.Context, args struct{ Limit int64 }) int64 {
+		if jaal.ExtractVariables(ctx)["limit"] != float64(args.Limit) {
+			return -1
+		}
+		return args.Limit
+	})
+	handler := jaal.HTTPHandler(schema.MustBuild())
+
+	for _, tt := range []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "omitted",
+			body:     `{"query": "query Q($limit: Int = 10) { limit(limit: $limit) }"}`,
+			expected: `{"data":{"limit":10},"errors":null}`,
+		},
+		{
+			name:     "supplied",
+			body:     `{"query": "query Q($limit: Int = 10) { limit(limit: $limit) }", "variables": {"limit": 3}}`,
+			expected: `{"data":{"limit":3},"errors":null}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if diff := pretty.Compare(rr.Body.String(), tt.expected); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPResponseCompression(t *testing.T) {
+	operations := make([]string, 50)
+	for i := range operations {
+		operations[i] = `{"query": "{ mirror(value: 1) }"}`
+	}
+	body := "[" + strings.Join(operations, ",") + "]"
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	rr := testHTTPRequest(req, jaal.WithResponseCompression())
+
+	if diff := pretty.Compare(rr.Header().Get("Content-Encoding"), "gzip"); diff != "" {
+		t.Errorf("expected response to be compressed, but received %s", diff)
+	}
+	if diff := pretty.Compare(rr.Header().Get("Content-Type"), "application/json"); diff != "" {
+		t.Errorf("expected content type to match, but received %s", diff)
+	}
+
+	reader, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := make([]string, len(operations))
+	for i := range responses {
+		responses[i] = `{"data":{"mirror":-1},"errors":null}`
+	}
+	if diff := pretty.Compare(string(decompressed), "["+strings.Join(responses, ",")+"]"); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// Small responses are not compressed.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ mirror(value: 1) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rr = testHTTPRequest(req, jaal.WithResponseCompression())
+
+	if diff := pretty.Compare(rr.Header().Get("Content-Encoding"), ""); diff != "" {
+		t.Errorf("expected response not to be compressed, but received %s", diff)
+	}
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// Responses are not compressed unless the option is set.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rr = testHTTPRequest(req)
+
+	if diff := pretty.Compare(rr.Header().Get("Content-Encoding"), ""); diff != "" {
+		t.Errorf("expected response not to be compressed, but received %s", diff)
+	}
+}
+
+func TestHTTPIntrospectionDisabled(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ __schema { queryType { name } } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := testHTTPRequest(req, jaal.WithIntrospectionDisabled())
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[{"message":"introspection is disabled","extensions":{"code":"INTROSPECTION_DISABLED"},"paths":[],"locations":[{"line":1,"column":3}]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ __typename mirror(value: 1) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr = testHTTPRequest(req, jaal.WithIntrospectionDisabled())
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"__typename":"Query","mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPBigInt(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("successor", func(args struct{ Value schemabuilder.Int64 }) schemabuilder.Int64 {
+		return args.Value + 1
+	})
+	handler := jaal.HTTPHandler(schema.MustBuild())
+
+	for _, tt := range []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "string variable",
+			body:     `{"query": "query Q($value: BigInt!) { successor(value: $value) }", "variables": {"value": "9007199254740992"}}`,
+			expected: `{"data":{"successor":"9007199254740993"},"errors":null}`,
+		},
+		{
+			name:     "string literal",
+			body:     `{"query": "{ successor(value: \"9007199254740993\") }"}`,
+			expected: `{"data":{"successor":"9007199254740994"},"errors":null}`,
+		},
+		{
+			name:     "number literal",
+			body:     `{"query": "{ successor(value: 41) }"}`,
+			expected: `{"data":{"successor":"42"},"errors":null}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if diff := pretty.Compare(rr.Body.String(), tt.expected); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPMapInput(t *testing.T) {
+	type Character struct {
+		Metadata map[string]string
+		Counts   map[string]int
+	}
+
+	schema := schemabuilder.NewSchema()
+	input := schema.InputObject("CharacterInput", Character{})
+	input.FieldFunc("metadata", func(target *Character, source map[string]string) {
+		target.Metadata = source
+	})
+	input.FieldFunc("counts", func(target *Character, source map[string]int) {
+		target.Counts = source
+	})
+	schema.Mutation().FieldFunc("echo", func(args struct{ Input *Character }) *Character {
+		return args.Input
+	})
+	character := schema.Object("Character", Character{})
+	character.FieldFunc("metadata", func(in *Character) map[string]string {
+		return in.Metadata
+	})
+	character.FieldFunc("counts", func(in *Character) map[string]int {
+		return in.Counts
+	})
+	schema.Query().FieldFunc("character", func() *Character { return nil })
+	handler := jaal.HTTPHandler(schema.MustBuild())
+
+	for _, tt := range []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "literal",
+			body:     `{"query": "mutation { echo(input: {metadata: {house: \"Gryffindor\"}, counts: {wands: 1}}) { metadata counts } }"}`,
+			expected: `{"data":{"echo":{"metadata":{"house":"Gryffindor"},"counts":{"wands":1}}},"errors":null}`,
+		},
+		{
+			name:     "variable",
+			body:     `{"query": "mutation Q($metadata: StringMap) { echo(input: {metadata: $metadata}) { metadata } }", "variables": {"metadata": {"house": "Slytherin"}}}`,
+			expected: `{"data":{"echo":{"metadata":{"house":"Slytherin"}}},"errors":null}`,
+		},
+		{
+			name:     "bad value",
+			body:     `{"query": "mutation { echo(input: {metadata: {house: 1}}) { metadata } }"}`,
+			expected: `{"data":null,"errors":[{"message":"error parsing args for \"echo\": input: metadata : house: not a string","extensions":{"code":"Unknown"},"paths":[],"locations":[{"line":1,"column":12}]}]}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if diff := pretty.Compare(rr.Body.String(), tt.expected); diff != "" {
+				t.Errorf("expected response to match, but received %s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPFieldErrorPaths(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("allUsers", func() []*User {
+		return []*User{{Name: "harry"}, {Name: "ron"}, {Name: "voldemort"}}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string {
+		return in.Name
+	})
+	user.FieldFunc("email", func(in *User) (*string, error) {
+		if in.Name == "voldemort" {
+			return nil, errors.New("email is hidden")
+		}
+		email := in.Name + "@hogwarts.edu"
+		return &email, nil
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ allUsers { name email } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild()).ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"allUsers":[`+
+		`{"name":"harry","email":"harry@hogwarts.edu"},`+
+		`{"name":"ron","email":"ron@hogwarts.edu"},`+
+		`{"name":"voldemort","email":null}]},"errors":[`+
+		`{"message":"email is hidden","extensions":{"code":"Unknown"},"paths":["allUsers",2,"email"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPSiblingErrors(t *testing.T) {
+	var calls []string
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("first", func() (string, error) {
+		return "", errors.New("first failed")
+	})
+	schema.Query().FieldFunc("second", func() (string, error) {
+		return "", errors.New("second failed")
+	})
+	schema.Query().FieldFunc("ok", func() string {
+		return "ok"
+	})
+	schema.Mutation().FieldFunc("first", func() (string, error) {
+		calls = append(calls, "first")
+		return "", errors.New("first failed")
+	})
+	schema.Mutation().FieldFunc("second", func() (string, error) {
+		calls = append(calls, "second")
+		return "", errors.New("second failed")
+	})
+	handler := jaal.HTTPHandler(schema.MustBuild())
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ first ok second }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[`+
+		`{"message":"first failed","extensions":{"code":"Unknown"},"paths":["first"]},`+
+		`{"message":"second failed","extensions":{"code":"Unknown"},"paths":["second"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// The fields of a mutation are not resolved after one of them failed.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "mutation { first second }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":null,"errors":[`+
+		`{"message":"first failed","extensions":{"code":"Unknown"},"paths":["first"]}]}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(calls, []string{"first"}); diff != "" {
+		t.Errorf("expected only the first mutation to run, but received %s", diff)
+	}
+}
+
+func TestHTTPExtensions(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func(ctx context.Context) string {
+		jaal.SetExtension(ctx, "cost", 3)
+		return "world"
+	})
+	schema.Query().FieldFunc("plain", func() string {
+		return "plain"
+	})
+	handler := jaal.HTTPHandler(schema.MustBuild())
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ hello }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"hello":"world"},"errors":null,"extensions":{"cost":3}}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// The extensions are omitted when none was set.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ plain }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"plain":"plain"},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+}
+
+func TestHTTPTracing(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("users", func() []*User {
+		return []*User{{Name: "harry"}, {Name: "ron"}}
+	})
+	user := schema.Object("User", User{})
+	user.FieldFunc("name", func(in *User) string {
+		return in.Name
+	})
+
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ users { name } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	jaal.HTTPHandler(schema.MustBuild(), jaal.WithTracing()).ServeHTTP(rr, req)
+
+	var response struct {
+		Extensions struct {
+			Tracing struct {
+				Version   int       `json:"version"`
+				StartTime time.Time `json:"startTime"`
+				EndTime   time.Time `json:"endTime"`
+				Duration  int64     `json:"duration"`
+				Execution struct {
+					Resolvers []struct {
+						Path       []interface{} `json:"path"`
+						ParentType string        `json:"parentType"`
+						FieldName  string        `json:"fieldName"`
+						ReturnType string        `json:"returnType"`
+						Duration   int64         `json:"duration"`
+					} `json:"resolvers"`
+				} `json:"execution"`
+			} `json:"tracing"`
+		} `json:"extensions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	tracing := response.Extensions.Tracing
+	if tracing.Version != 1 || tracing.EndTime.Before(tracing.StartTime) || tracing.Duration <= 0 {
+		t.Errorf("expected the timings of the operation, but received %s", rr.Body.String())
+	}
+
+	var resolvers []string
+	for _, resolver := range tracing.Execution.Resolvers {
+		if resolver.Duration < 0 {
+			t.Errorf("expected a positive duration for %v, but received %d", resolver.Path, resolver.Duration)
+		}
+		resolvers = append(resolvers, fmt.Sprintf("%v %s.%s: %s", resolver.Path, resolver.ParentType, resolver.FieldName, resolver.ReturnType))
+	}
+	if diff := pretty.Compare(resolvers, []string{
+		"[users] Query.users: [User!]!",
+		"[users 0 name] User.name: String!",
+		"[users 1 name] User.name: String!",
+	}); diff != "" {
+		t.Errorf("expected resolvers to match, but received %s", diff)
+	}
+}
+
+func TestHTTPQueryCache(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("double", func(args struct{ N int64 }) int64 {
+		return 2 * args.N
+	})
+
+	var queries []*graphql.Query
+	record := func(next jaal.HandlerFunc) jaal.HandlerFunc {
+		return func(ctx context.Context, typ graphql.Type, query *graphql.Query) (interface{}, error) {
+			queries = append(queries, query)
+			return next(ctx, typ, query)
+		}
+	}
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithQueryCache(1), jaal.WithMiddlewares(record))
+
+	for _, tt := range []struct {
+		body     string
+		expected string
+	}{
+		{`{"query": "query($n: Int!) { double(n: $n) }", "variables": {"n": 1}}`, `{"data":{"double":2},"errors":null}`},
+		{`{"query": "query($n: Int!) { double(n: $n) }", "variables": {"n": 1}}`, `{"data":{"double":2},"errors":null}`},
+		{`{"query": "query($n: Int!) { double(n: $n) }", "variables": {"n": 2}}`, `{"data":{"double":4},"errors":null}`},
+		{`{"query": "query($n: Int!) { double(n: $n) }", "variables": {"n": 1}}`, `{"data":{"double":2},"errors":null}`},
+	} {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if diff := pretty.Compare(rr.Body.String(), tt.expected); diff != "" {
+			t.Errorf("expected response to match, but received %s", diff)
+		}
+	}
+
+	// The repeated operation reuses the cached query, until the operation with other variables
+	// evicts it.
+	if queries[0] != queries[1] {
+		t.Error("expected the repeated operation to reuse the cached query")
+	}
+	if queries[0] == queries[2] || queries[0] == queries[3] {
+		t.Error("expected the operations with other variables to be parsed again")
+	}
+}
+
+func TestHTTPGraphQLContentType(t *testing.T) {
+	req, err := http.NewRequest("POST", "/graphql", strings.NewReader(`{ mirror(value: 1) }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/graphql; charset=utf-8")
+
+	rr := testHTTPRequest(req)
+	if diff := pretty.Compare(rr.Body.String(), `{"data":{"mirror":-1},"errors":null}`); diff != "" {
+		t.Errorf("expected response to match, but received %s", diff)
+	}
+
+	// Bodies of other content types are decoded as JSON.
+	req, err = http.NewRequest("POST", "/graphql", strings.NewReader(`{ mirror(value: 1) }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = testHTTPRequest(req)
+	if !strings.Contains(rr.Body.String(), `"data":null`) {
+		t.Errorf("expected the raw query to fail to decode as JSON, but received %s", rr.Body.String())
+	}
+}
+
+func TestHTTPOperationObserver(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func() string {
+		return "world"
+	})
+	schema.Query().FieldFunc("fail", func() (string, error) {
+		return "", errors.New("failed")
+	})
+	schema.Mutation().FieldFunc("touch", func() bool {
+		return true
+	})
+
+	var stats []jaal.OperationStats
+	handler := jaal.HTTPHandler(schema.MustBuild(), jaal.WithOperationObserver(func(s jaal.OperationStats) {
+		stats = append(stats, s)
+	}))
+
+	for _, body := range []string{
+		`{"query": "query Greeting { hello }"}`,
+		`{"query": "mutation { touch }"}`,
+		`{"query": "{ fail a: fail }"}`,
+		`{"query": "{ missing }", "operationName": "Missing"}`,
+		`[{"query": "{ hello }"}, {"query": "{"}]`,
+	} {
+		req, err := http.NewRequest("POST", "/graphql", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for _, s := range stats {
+		if s.Duration < 0 || (s.Kind == "" && s.Duration != 0) {
+			t.Errorf("expected the duration of the execution, but received %v", s.Duration)
+		}
+	}
+
+	var received []string
+	for _, s := range stats {
+		received = append(received, fmt.Sprintf("%s %s: %d errors, complexity %d", s.Kind, s.OperationName, s.Errors, s.Complexity))
+	}
+	if diff := pretty.Compare(received, []string{
+		"query Greeting: 0 errors, complexity 1",
+		"mutation : 0 errors, complexity 1",
+		"query : 2 errors, complexity 2",
+		" Missing: 1 errors, complexity 0",
+		"query : 0 errors, complexity 1",
+		" : 1 errors, complexity 0",
+	}); diff != "" {
+		t.Errorf("expected the operations to be observed, but received %s", diff)
+	}
+}
+
+func BenchmarkHTTPQueryCache(b *testing.B) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("double", func(args struct{ N int64 }) int64 {
+		return 2 * args.N
+	})
+	built := schema.MustBuild()
+	body := `{"query": "query($n: Int!) { a: double(n: $n) b: double(n: 2) c: double(n: 3) }", "variables": {"n": 1}}`
+
+	for _, bb := range []struct {
+		name string
+		opts []jaal.HandlerOption
+	}{
+		{name: "uncached"},
+		{name: "cached", opts: []jaal.HandlerOption{jaal.WithQueryCache(16)}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			handler := jaal.HTTPHandler(built, bb.opts...)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package introspection
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// SchemaHash returns a SHA-256 hash of the types reachable from the schema. The types and their
+// fields, arguments and values are sorted before hashing, so schemas declaring the same types
+// hash identically regardless of the order they were registered in.
+func SchemaHash(schema *graphql.Schema) string {
+	types := make(map[string]graphql.Type)
+	graphql.CollectTypes(schema.Query, types)
+	graphql.CollectTypes(schema.Mutation, types)
+	graphql.CollectTypes(schema.Subscription, types)
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(graphql.PrintType(types[name])))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -15,9 +15,12 @@ type introspection struct {
 	query        graphql.Type
 	mutation     graphql.Type
 	subscription graphql.Type
+	directives   []Directive
 }
 
-type DirectiveLocation string
+// DirectiveLocation is a location where a directive may be used. It is the type of the
+// locations of custom directives registered with schemabuilder.Schema.Directive.
+type DirectiveLocation = graphql.DirectiveLocation
 
 const (
 	QUERY               DirectiveLocation = "QUERY"
@@ -27,6 +30,7 @@ const (
 	FRAGMENT_SPREAD                       = "FRAGMENT_SPREAD"
 	INLINE_FRAGMENT                       = "INLINE_FRAGMENT"
 	SUBSCRIPTION                          = "SUBSCRIPTION"
+	FIELD_DEFINITION                      = "FIELD_DEFINITION"
 )
 
 type TypeKind string
@@ -43,10 +47,12 @@ const (
 )
 
 type InputValue struct {
-	Name         string
-	Description  string
-	Type         Type
-	DefaultValue *string
+	Name              string
+	Description       string
+	Type              Type
+	DefaultValue      *string
+	IsDeprecated      bool
+	DeprecationReason string
 }
 
 func (s *introspection) registerInputValue(schema *schemabuilder.Schema) {
@@ -63,6 +69,12 @@ func (s *introspection) registerInputValue(schema *schemabuilder.Schema) {
 	obj.FieldFunc("defaultValue", func(in InputValue) *string {
 		return in.DefaultValue
 	})
+	obj.FieldFunc("isDeprecated", func(in InputValue) bool {
+		return in.IsDeprecated
+	})
+	obj.FieldFunc("deprecationReason", func(in InputValue) string {
+		return in.DeprecationReason
+	})
 }
 
 type EnumValue struct {
@@ -89,10 +101,11 @@ func (s *introspection) registerEnumValue(schema *schemabuilder.Schema) {
 }
 
 type Directive struct {
-	Name        string
-	Description string
-	Locations   []DirectiveLocation
-	Args        []InputValue
+	Name         string
+	Description  string
+	Locations    []DirectiveLocation
+	Args         []InputValue
+	IsRepeatable bool
 }
 
 func (s *introspection) registerDirective(schema *schemabuilder.Schema) {
@@ -109,6 +122,9 @@ func (s *introspection) registerDirective(schema *schemabuilder.Schema) {
 	obj.FieldFunc("args", func(in Directive) []InputValue {
 		return in.Args
 	})
+	obj.FieldFunc("isRepeatable", func(in Directive) bool {
+		return in.IsRepeatable
+	})
 
 	// if err := schemabuilder.RegisterScalar(reflect.TypeOf(DirectiveLocation("")), "directiveLocation", func(value interface{}, dest reflect.Value) error {
 	// 	asString, ok := value.(string)
@@ -129,6 +145,7 @@ func (s *introspection) registerDirective(schema *schemabuilder.Schema) {
 		"FRAGMENT_SPREAD":     DirectiveLocation("FRAGMENT_SPREAD"),
 		"INLINE_FRAGMENT":     DirectiveLocation("INLINE_FRAGMENT"),
 		"SUBSCRIPTION":        DirectiveLocation("SUBSCRIPTION"),
+		"FIELD_DEFINITION":    DirectiveLocation("FIELD_DEFINITION"),
 	})
 }
 
@@ -216,11 +233,25 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 			return t.Description
 		case *graphql.Interface:
 			return t.Description
+		case *graphql.Scalar:
+			return t.Description
 		default:
 			return ""
 		}
 	})
 
+	object.FieldFunc("specifiedByURL", func(t Type) *string {
+		if t, ok := t.Inner.(*graphql.Scalar); ok && t.SpecifiedByURL != "" {
+			return &t.SpecifiedByURL
+		}
+		return nil
+	})
+
+	// isOneOf is always false, as input objects cannot be marked oneOf.
+	object.FieldFunc("isOneOf", func(t Type) bool {
+		return false
+	})
+
 	object.FieldFunc("interfaces", func(t Type) []Type {
 		switch t := t.Inner.(type) {
 		case *graphql.Object:
@@ -264,9 +295,12 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 		switch t := t.Inner.(type) {
 		case *graphql.InputObject:
 			for name, f := range t.InputFields {
+				reason, deprecated := t.DeprecatedFields[name]
 				fields = append(fields, InputValue{
-					Name: name,
-					Type: Type{Inner: f},
+					Name:              name,
+					Type:              Type{Inner: f},
+					IsDeprecated:      deprecated,
+					DeprecationReason: reason,
 				})
 			}
 		}
@@ -278,41 +312,58 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 	object.FieldFunc("fields", func(t Type, args struct {
 		IncludeDeprecated *bool
 	}) []field {
+		includeDeprecated := args.IncludeDeprecated != nil && *args.IncludeDeprecated
 		var fields []field
 
 		switch t := t.Inner.(type) {
 		case *graphql.Object:
 			for name, f := range t.Fields {
+				if f.IsDeprecated && !includeDeprecated {
+					continue
+				}
 				var args []InputValue
 				for name, a := range f.Args {
+					reason, deprecated := f.DeprecatedArgs[name]
 					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
+						Name:              name,
+						Type:              Type{Inner: a},
+						IsDeprecated:      deprecated,
+						DeprecationReason: reason,
 					})
 				}
 				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
 
 				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
+					Name:              name,
+					Type:              Type{Inner: f.Type},
+					Args:              args,
+					IsDeprecated:      f.IsDeprecated,
+					DeprecationReason: f.DeprecationReason,
 				})
 			}
 		case *graphql.Interface:
 			for name, f := range t.Fields {
+				if f.IsDeprecated && !includeDeprecated {
+					continue
+				}
 				var args []InputValue
 				for name, a := range f.Args {
+					reason, deprecated := f.DeprecatedArgs[name]
 					args = append(args, InputValue{
-						Name: name,
-						Type: Type{Inner: a},
+						Name:              name,
+						Type:              Type{Inner: a},
+						IsDeprecated:      deprecated,
+						DeprecationReason: reason,
 					})
 				}
 				sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
 
 				fields = append(fields, field{
-					Name: name,
-					Type: Type{Inner: f.Type},
-					Args: args,
+					Name:              name,
+					Type:              Type{Inner: f.Type},
+					Args:              args,
+					IsDeprecated:      f.IsDeprecated,
+					DeprecationReason: f.DeprecationReason,
 				})
 			}
 		}
@@ -335,14 +386,18 @@ func (s *introspection) registerType(schema *schemabuilder.Schema) {
 	object.FieldFunc("enumValues", func(t Type, args struct {
 		IncludeDeprecated *bool
 	}) []EnumValue {
+		includeDeprecated := args.IncludeDeprecated != nil && *args.IncludeDeprecated
 
 		switch t := t.Inner.(type) {
 		case *graphql.Enum:
 			var enumVals []EnumValue
 			for k, v := range t.ReverseMap {
 				val := fmt.Sprintf("%v", k)
-				enumVals = append(enumVals,
-					EnumValue{Name: v, Description: val, IsDeprecated: false, DeprecationReason: ""})
+				enumVal := EnumValue{Name: v, Description: val, IsDeprecated: false, DeprecationReason: ""}
+				if enumVal.IsDeprecated && !includeDeprecated {
+					continue
+				}
+				enumVals = append(enumVals, enumVal)
 			}
 			sort.Slice(enumVals, func(i, j int) bool { return enumVals[i].Name < enumVals[j].Name })
 			return enumVals
@@ -382,78 +437,6 @@ func (s *introspection) registerField(schema *schemabuilder.Schema) {
 	})
 }
 
-func collectTypes(typ graphql.Type, types map[string]graphql.Type) {
-	switch typ := typ.(type) {
-	case *graphql.Object:
-		if _, ok := types[typ.Name]; ok {
-			return
-		}
-		types[typ.Name] = typ
-
-		for _, field := range typ.Fields {
-			collectTypes(field.Type, types)
-
-			for _, arg := range field.Args {
-				collectTypes(arg, types)
-			}
-		}
-
-	case *graphql.Union:
-		if _, ok := types[typ.Name]; ok {
-			return
-		}
-		types[typ.Name] = typ
-		for _, graphqlTyp := range typ.Types {
-			collectTypes(graphqlTyp, types)
-		}
-
-	case *graphql.Interface:
-		if _, ok := types[typ.Name]; ok {
-			return
-		}
-		types[typ.Name] = typ
-
-		for _, field := range typ.Fields {
-			collectTypes(field.Type, types)
-
-			for _, arg := range field.Args {
-				collectTypes(arg, types)
-			}
-		}
-		for _, object := range typ.Types {
-			collectTypes(object, types)
-		}
-
-	case *graphql.List:
-		collectTypes(typ.Type, types)
-
-	case *graphql.Scalar:
-		if _, ok := types[typ.Type]; ok {
-			return
-		}
-		types[typ.Type] = typ
-
-	case *graphql.Enum:
-		if _, ok := types[typ.Type]; ok {
-			return
-		}
-		types[typ.Type] = typ
-
-	case *graphql.InputObject:
-		if _, ok := types[typ.Name]; ok {
-			return
-		}
-		types[typ.Name] = typ
-
-		for _, field := range typ.InputFields {
-			collectTypes(field, types)
-		}
-
-	case *graphql.NonNull:
-		collectTypes(typ.Type, types)
-	}
-}
-
 var includeDirective = Directive{
 	Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
 	Locations: []DirectiveLocation{
@@ -488,6 +471,52 @@ var skipDirective = Directive{
 	},
 }
 
+var deferDirective = Directive{
+	Description: "Directs the executor to deliver this fragment after the rest of the response, when the `if` argument is true.",
+	Locations: []DirectiveLocation{
+		FRAGMENT_SPREAD,
+		INLINE_FRAGMENT,
+	},
+	Name: "defer",
+	Args: []InputValue{
+		InputValue{
+			Name:        "if",
+			Type:        Type{Inner: &graphql.Scalar{Type: "Boolean"}},
+			Description: "Deferred when true.",
+		},
+		InputValue{
+			Name:        "label",
+			Type:        Type{Inner: &graphql.Scalar{Type: "String"}},
+			Description: "Identifies the patch delivering the fragment.",
+		},
+	},
+}
+
+// customDirectives converts the custom directives of a schema, sorted by name.
+func customDirectives(definitions map[string]*graphql.DirectiveDefinition) []Directive {
+	var directives []Directive
+	for _, definition := range definitions {
+		var args []InputValue
+		for name, a := range definition.Args {
+			args = append(args, InputValue{
+				Name: name,
+				Type: Type{Inner: a},
+			})
+		}
+		sort.Slice(args, func(i, j int) bool { return args[i].Name < args[j].Name })
+
+		directives = append(directives, Directive{
+			Name:         definition.Name,
+			Description:  definition.Description,
+			Locations:    definition.Locations,
+			Args:         args,
+			IsRepeatable: definition.Repeatable,
+		})
+	}
+	sort.Slice(directives, func(i, j int) bool { return directives[i].Name < directives[j].Name })
+	return directives
+}
+
 func (s *introspection) registerQuery(schema *schemabuilder.Schema) {
 	object := schema.Query()
 
@@ -504,7 +533,7 @@ func (s *introspection) registerQuery(schema *schemabuilder.Schema) {
 			QueryType:        &Type{Inner: s.query},
 			MutationType:     &Type{Inner: s.mutation},
 			SubscriptionType: &Type{Inner: s.subscription},
-			Directives:       []Directive{includeDirective, skipDirective},
+			Directives:       append([]Directive{includeDirective, skipDirective, deferDirective}, s.directives...),
 		}
 	})
 
@@ -542,14 +571,20 @@ func (s *introspection) schema() *graphql.Schema {
 // AddIntrospectionToSchema adds the introspection fields to existing schema
 func AddIntrospectionToSchema(schema *graphql.Schema) {
 	types := make(map[string]graphql.Type)
-	collectTypes(schema.Query, types)
-	collectTypes(schema.Mutation, types)
-	collectTypes(schema.Subscription, types)
+	graphql.CollectTypes(schema.Query, types)
+	graphql.CollectTypes(schema.Mutation, types)
+	graphql.CollectTypes(schema.Subscription, types)
+	for _, directive := range schema.Directives {
+		for _, arg := range directive.Args {
+			graphql.CollectTypes(arg, types)
+		}
+	}
 	is := &introspection{
 		types:        types,
 		query:        schema.Query,
 		mutation:     schema.Mutation,
 		subscription: schema.Subscription,
+		directives:   customDirectives(schema.Directives),
 	}
 	isSchema := is.schema()
 
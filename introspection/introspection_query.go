@@ -17,6 +17,7 @@ query IntrospectionQuery {
 			args {
 				...InputValue
 			}
+			isRepeatable
 		}
 	}
 }
@@ -24,6 +25,7 @@ fragment FullType on __Type {
 	kind
 	name
 	description
+	specifiedByURL
 	fields(includeDeprecated: true) {
 		name
 		description
@@ -57,6 +59,8 @@ fragment InputValue on __InputValue {
 	description
 	type { ...TypeRef }
 	defaultValue
+	isDeprecated
+	deprecationReason
 }
 fragment TypeRef on __Type {
 	kind
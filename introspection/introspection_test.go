@@ -6,10 +6,12 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"go.appointy.com/jaal/graphql"
 
 	"github.com/stretchr/testify/require"
+	"go.appointy.com/jaal/internal"
 	"go.appointy.com/jaal/introspection"
 	"go.appointy.com/jaal/schemabuilder"
 )
@@ -255,6 +257,9 @@ func TestIntrospectionForInterface(t *testing.T) {
 						map[string]interface{}{
 							"name": "skip",
 						},
+						map[string]interface{}{
+							"name": "defer",
+						},
 					},
 				},
 			},
@@ -317,8 +322,8 @@ func TestIntrospectionForInterface(t *testing.T) {
 									map[string]interface{}{
 										"name": "in",
 										"type": map[string]interface{}{
-											"kind": "INPUT_OBJECT",
-											"name": "NodeInput",
+											"kind": "NON_NULL",
+											"name": "",
 										},
 									},
 								},
@@ -333,8 +338,8 @@ func TestIntrospectionForInterface(t *testing.T) {
 									map[string]interface{}{
 										"name": "in",
 										"type": map[string]interface{}{
-											"kind": "INPUT_OBJECT",
-											"name": "NodeInput",
+											"kind": "NON_NULL",
+											"name": "",
 										},
 									},
 								},
@@ -888,6 +893,46 @@ func TestIntrospectionForInterface(t *testing.T) {
 								},
 							},
 						},
+						map[string]interface{}{
+							"name":        "defer",
+							"description": "Directs the executor to deliver this fragment after the rest of the response, when the `if` argument is true.",
+							"locations": []interface{}{
+								"FRAGMENT_SPREAD",
+								"INLINE_FRAGMENT",
+							},
+							"args": []interface{}{
+								map[string]interface{}{
+									"name":         "if",
+									"description":  "Deferred when true.",
+									"defaultValue": nil,
+									"type": map[string]interface{}{
+										"name":          "Boolean",
+										"kind":          "SCALAR",
+										"description":   "",
+										"fields":        []interface{}{},
+										"interfaces":    []interface{}{},
+										"possibleTypes": []interface{}{},
+										"enumValues":    []interface{}{},
+										"inputFields":   []interface{}{},
+									},
+								},
+								map[string]interface{}{
+									"name":         "label",
+									"description":  "Identifies the patch delivering the fragment.",
+									"defaultValue": nil,
+									"type": map[string]interface{}{
+										"name":          "String",
+										"kind":          "SCALAR",
+										"description":   "",
+										"fields":        []interface{}{},
+										"interfaces":    []interface{}{},
+										"possibleTypes": []interface{}{},
+										"enumValues":    []interface{}{},
+										"inputFields":   []interface{}{},
+									},
+								},
+							},
+						},
 					},
 				},
 			},
@@ -910,7 +955,7 @@ func TestIntrospectionForInterface(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			a, _ := json.Marshal(result)
+			a, _ := json.Marshal(internal.AsJSON(result))
 			b, _ := json.Marshal(tt.expectedResult)
 
 			if !reflect.DeepEqual(a, b) {
@@ -1059,3 +1104,389 @@ func (s *node) registerEnumType(schema *schemabuilder.Schema) {
 		"EMPLOYEE": ProviderType(1),
 	})
 }
+
+func executeIntrospection(t *testing.T, builder *schemabuilder.Schema, queryString string) interface{} {
+	schema := builder.MustBuild()
+	introspection.AddIntrospectionToSchema(schema)
+
+	query, err := graphql.Parse(queryString, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := graphql.ValidateQuery(context.Background(), schema.Query, query.SelectionSet); err != nil {
+		t.Fatal(err)
+	}
+
+	e := graphql.Executor{}
+	result, err := e.Execute(context.Background(), schema.Query, nil, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return internal.AsJSON(result)
+}
+
+type protoStatus int32
+
+func TestIntrospectionEnumName(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Enum(protoStatus(0), map[string]interface{}{
+		"ACTIVE":   protoStatus(0),
+		"INACTIVE": protoStatus(1),
+	}, schemabuilder.WithName("Status"))
+	builder.Query().FieldFunc("status", func(args struct{ In protoStatus }) protoStatus {
+		return args.In
+	})
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		__type(name: "Status") {
+			name
+			kind
+			enumValues {
+				name
+			}
+		}
+		protoStatus: __type(name: "protoStatus") {
+			name
+		}
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"__type": {
+			"name": "Status",
+			"kind": "ENUM",
+			"enumValues": [{"name": "ACTIVE"}, {"name": "INACTIVE"}]
+		},
+		"protoStatus": null
+	}`), result)
+}
+
+func TestSchemaHash(t *testing.T) {
+	type Account struct {
+		Name string
+	}
+
+	build := func(fields []string, extra bool) *graphql.Schema {
+		schema := schemabuilder.NewSchema()
+		account := schema.Object("Account", Account{})
+		for _, field := range fields {
+			switch field {
+			case "name":
+				account.FieldFunc("name", func(in *Account) string { return in.Name })
+			case "balance":
+				account.FieldFunc("balance", func(in *Account, args struct{ Currency string }) float64 { return 0 })
+			}
+		}
+		if extra {
+			account.FieldFunc("owner", func(in *Account) string { return "" })
+		}
+		schema.Query().FieldFunc("account", func() *Account { return nil })
+		schema.Query().FieldFunc("accounts", func() []*Account { return nil })
+		return schema.MustBuild()
+	}
+
+	a := introspection.SchemaHash(build([]string{"name", "balance"}, false))
+	b := introspection.SchemaHash(build([]string{"balance", "name"}, false))
+	c := introspection.SchemaHash(build([]string{"name", "balance"}, true))
+
+	require.Len(t, a, 64)
+	require.Equal(t, a, b)
+	require.NotEqual(t, a, c)
+}
+
+func TestIntrospectionCustomDirective(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Enum(protoStatus(0), map[string]interface{}{
+		"ACTIVE":   protoStatus(0),
+		"INACTIVE": protoStatus(1),
+	}, schemabuilder.WithName("Status"))
+	builder.Query().FieldFunc("name", func() string { return "" })
+	builder.Mutation()
+	builder.Directive("when", []introspection.DirectiveLocation{introspection.FIELD},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			return next(ctx)
+		},
+		schemabuilder.WithDirectiveArgs(struct{ Status protoStatus }{}),
+		schemabuilder.WithDirectiveDescription("Resolves the field when the status matches."),
+		schemabuilder.WithDirectiveRepeatable(),
+	)
+
+	result := executeIntrospection(t, builder, `{
+		__schema {
+			directives {
+				name
+				description
+				locations
+				args { name type { kind name ofType { name } } }
+				isRepeatable
+			}
+		}
+	}`)
+
+	directives := result.(map[string]interface{})["__schema"].(map[string]interface{})["directives"].([]interface{})
+	require.Equal(t, false, directives[0].(map[string]interface{})["isRepeatable"])
+	require.Equal(t, internal.ParseJSON(`{
+		"name": "when",
+		"description": "Resolves the field when the status matches.",
+		"locations": ["FIELD"],
+		"args": [{"name": "status", "type": {"kind": "NON_NULL", "name": "", "ofType": {"name": "Status"}}}],
+		"isRepeatable": true
+	}`), directives[len(directives)-1])
+}
+
+func TestIntrospectionFieldDirective(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Directive("auth", []introspection.DirectiveLocation{introspection.FIELD_DEFINITION},
+		func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+			return next(ctx)
+		},
+	)
+	builder.Query().FieldFunc("name", func() string { return "" }, schemabuilder.WithDirective("auth", nil))
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		__schema {
+			directives { name locations }
+		}
+	}`)
+
+	directives := result.(map[string]interface{})["__schema"].(map[string]interface{})["directives"].([]interface{})
+	require.Equal(t, internal.ParseJSON(`{
+		"name": "auth",
+		"locations": ["FIELD_DEFINITION"]
+	}`), directives[len(directives)-1])
+}
+
+func TestIntrospectionConnection(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	builder := schemabuilder.NewSchema()
+	builder.Object("User", User{}).FieldFunc("name", func(in *User) string { return in.Name })
+	builder.Query().FieldFunc("users", func() schemabuilder.Page { return schemabuilder.Page{} }, builder.Connection("User", &User{}))
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		users: __type(name: "Query") { fields { name type { kind name } } }
+		connection: __type(name: "UserConnection") { fields { name } }
+		edge: __type(name: "UserEdge") { fields { name type { name } } }
+		pageInfo: __type(name: "PageInfo") { fields { name } }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"users": {"fields": [{"name": "users", "type": {"kind": "OBJECT", "name": "UserConnection"}}]},
+		"connection": {"fields": [{"name": "edges"}, {"name": "pageInfo"}, {"name": "totalCount"}]},
+		"edge": {"fields": [{"name": "cursor", "type": {"name": ""}}, {"name": "node", "type": {"name": "User"}}]},
+		"pageInfo": {"fields": [{"name": "endCursor"}, {"name": "hasNextPage"}, {"name": "hasPreviousPage"}, {"name": "startCursor"}]}
+	}`), result)
+}
+
+func TestIntrospectionNullability(t *testing.T) {
+	type User struct {
+		Name string
+	}
+
+	builder := schemabuilder.NewSchema()
+	builder.Object("User", User{}).FieldFunc("name", func(in *User) string { return in.Name })
+	query := builder.Query()
+	query.FieldFunc("me", func() *User { return nil })
+	query.FieldFunc("self", func() User { return User{} })
+	query.FieldFunc("admin", func() *User { return &User{} }, schemabuilder.NonNull())
+	query.FieldFunc("count", func() int64 { return 0 })
+	query.FieldFunc("limit", func() *int64 { return nil })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		__type(name: "Query") { fields { name type { kind name ofType { kind name } } } }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"__type": {"fields": [
+			{"name": "admin", "type": {"kind": "NON_NULL", "name": "", "ofType": {"kind": "OBJECT", "name": "User"}}},
+			{"name": "count", "type": {"kind": "NON_NULL", "name": "", "ofType": {"kind": "SCALAR", "name": "Int"}}},
+			{"name": "limit", "type": {"kind": "SCALAR", "name": "Int", "ofType": null}},
+			{"name": "me", "type": {"kind": "OBJECT", "name": "User", "ofType": null}},
+			{"name": "self", "type": {"kind": "NON_NULL", "name": "", "ofType": {"kind": "OBJECT", "name": "User"}}}
+		]}
+	}`), result)
+}
+
+func TestIntrospectionDeprecatedArgs(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("search", func(args struct {
+		Query string
+		Limit *int64 `graphql:",deprecated=Use first instead."`
+		First *int64
+	}) string {
+		return ""
+	})
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		__type(name: "Query") {
+			fields { name args { name isDeprecated deprecationReason } }
+		}
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"__type": {
+			"fields": [{
+				"name": "search",
+				"args": [
+					{"name": "first", "isDeprecated": false, "deprecationReason": ""},
+					{"name": "limit", "isDeprecated": true, "deprecationReason": "Use first instead."},
+					{"name": "query", "isDeprecated": false, "deprecationReason": ""}
+				]
+			}]
+		}
+	}`), result)
+}
+
+func TestIntrospectionHiddenFields(t *testing.T) {
+	type Account struct {
+		Name         string
+		PasswordHash string `graphql:"-" json:"-"`
+		secret       string
+	}
+
+	builder := schemabuilder.NewSchema()
+	account := builder.Object("Account", Account{})
+	account.FieldFunc("name", func(in *Account) string { return in.Name })
+	builder.Query().FieldFunc("account", func(args struct {
+		Name     string
+		Token    string `graphql:"-"`
+		Internal string `json:"-"`
+		secret   string
+	}) *Account {
+		return nil
+	})
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		account: __type(name: "Account") { fields { name } }
+		query: __type(name: "Query") { fields { name args { name } } }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"account": {"fields": [{"name": "name"}]},
+		"query": {"fields": [{"name": "account", "args": [{"name": "name"}]}]}
+	}`), result)
+}
+
+func TestIntrospectionIncludeDeprecated(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	user := builder.Object("User", User{})
+	user.FieldFunc("name", func(in User) string { return in.Name })
+	user.FieldFunc("fullName", func(in User) string { return in.Name }, schemabuilder.Deprecated("Use name instead."))
+	builder.Query().FieldFunc("me", func() User { return User{} })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		omitted: __type(name: "User") { fields { name } }
+		excluded: __type(name: "User") { fields(includeDeprecated: false) { name } }
+		included: __type(name: "User") { fields(includeDeprecated: true) { name isDeprecated } }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"omitted": {"fields": [{"name": "name"}]},
+		"excluded": {"fields": [{"name": "name"}]},
+		"included": {"fields": [{"name": "fullName", "isDeprecated": true}, {"name": "name", "isDeprecated": false}]}
+	}`), result)
+}
+
+type date struct {
+	Value string
+}
+
+func TestIntrospectionScalarDescription(t *testing.T) {
+	typ := reflect.TypeOf(date{})
+	require.NoError(t, schemabuilder.RegisterScalar(typ, "Date", func(value interface{}, dest reflect.Value) error {
+		dest.Field(0).SetString(value.(string))
+		return nil
+	}, schemabuilder.WithScalarDescription("A calendar date, such as 2006-01-02.")))
+
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("today", func(args struct{ After *date }) date { return date{} })
+	builder.Query().FieldFunc("name", func() string { return "" })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		date: __type(name: "Date") { name description }
+		string: __type(name: "String") { name description }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"date": {"name": "Date", "description": "A calendar date, such as 2006-01-02."},
+		"string": {"name": "String", "description": ""}
+	}`), result)
+}
+
+func TestIntrospectionDefaultDateTimeScalar(t *testing.T) {
+	builder := schemabuilder.NewSchema()
+	builder.Query().FieldFunc("now", func() time.Time { return time.Time{} })
+	builder.Query().FieldFunc("name", func() string { return "" })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		dateTime: __type(name: "DateTime") { name specifiedByURL }
+		string: __type(name: "String") { name specifiedByURL }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"dateTime": {"name": "DateTime", "specifiedByURL": "https://datatracker.ietf.org/doc/html/rfc3339"},
+		"string": {"name": "String", "specifiedByURL": null}
+	}`), result)
+}
+
+func TestIntrospectionIsOneOf(t *testing.T) {
+	type Filter struct {
+		Name string
+	}
+	builder := schemabuilder.NewSchema()
+	filter := builder.InputObject("Filter", Filter{})
+	filter.FieldFunc("name", func(target *Filter, source string) {
+		target.Name = source
+	})
+	builder.Query().FieldFunc("name", func(args struct{ Filter Filter }) string { return "" })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		filter: __type(name: "Filter") { kind isOneOf }
+		string: __type(name: "String") { kind isOneOf }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"filter": {"kind": "INPUT_OBJECT", "isOneOf": false},
+		"string": {"kind": "SCALAR", "isOneOf": false}
+	}`), result)
+}
+
+func TestIntrospectionFederation(t *testing.T) {
+	type Account struct {
+		Id string
+	}
+
+	builder := schemabuilder.NewSchema()
+	account := builder.Object("Account", Account{})
+	account.FieldFunc("id", func(in *Account) string { return in.Id })
+	account.Key("id")
+	builder.RegisterReferenceResolver("Account", func(ctx context.Context, key map[string]interface{}) (interface{}, error) {
+		return &Account{Id: key["id"].(string)}, nil
+	})
+	builder.Query().FieldFunc("name", func() string { return "" })
+	builder.Mutation()
+
+	result := executeIntrospection(t, builder, `{
+		entity: __type(name: "_Entity") { kind possibleTypes { name } }
+		service: __type(name: "_Service") { kind fields { name } }
+		any: __type(name: "_Any") { kind }
+	}`)
+
+	require.Equal(t, internal.ParseJSON(`{
+		"entity": {"kind": "UNION", "possibleTypes": [{"name": "Account"}]},
+		"service": {"kind": "OBJECT", "fields": [{"name": "sdl"}]},
+		"any": {"kind": "SCALAR"}
+	}`), result)
+}
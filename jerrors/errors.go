@@ -1,21 +1,121 @@
 package jerrors
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 
 	"google.golang.org/grpc/status"
 )
 
+// CodeBadUserInput is the code of errors caused by invalid input provided by the client
+const CodeBadUserInput = "BAD_USER_INPUT"
+
+// CodeInternal is the code of errors hiding an internal failure, such as a panic, from the client
+const CodeInternal = "Internal"
+
+// CodeQueryTooComplex is the code of errors rejecting queries exceeding the maximum complexity
+const CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+
+// CodeUnauthenticated is the code of errors caused by a request lacking valid credentials
+const CodeUnauthenticated = "UNAUTHENTICATED"
+
+// CodeForbidden is the code of errors caused by a request lacking the permission to an operation
+const CodeForbidden = "FORBIDDEN"
+
+// CodeBadRequest is the code of errors caused by a malformed request
+const CodeBadRequest = "BAD_REQUEST"
+
+// CodeTimeout is the code of errors of fields which weren't resolved before the execution timed out
+const CodeTimeout = "TIMEOUT"
+
+// CodeIntrospectionDisabled is the code of errors rejecting introspection queries when introspection is disabled
+const CodeIntrospectionDisabled = "INTROSPECTION_DISABLED"
+
 // Error represents the error returned by server in response
 type Error struct {
 	Message    string     `json:"message"`
 	Extensions *Extension `json:"extensions"`
-	Paths      []string   `json:"paths"`
+	// Paths is the path of the field the error belongs to in the response. The indices of list
+	// elements, such as "2", are written as numbers in JSON.
+	Paths []string `json:"paths"`
+	// Locations are the positions in the query of the tokens causing the error, such as a
+	// misspelled field. They are only set for the errors of parsing and validation.
+	Locations []Location `json:"locations,omitempty"`
+
+	httpStatus int
+}
+
+// Location is a position in the query, counting lines and columns from 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// jsonError is the JSON representation of an Error.
+type jsonError struct {
+	Message    string        `json:"message"`
+	Extensions *Extension    `json:"extensions"`
+	Paths      []interface{} `json:"paths"`
+	Locations  []Location    `json:"locations,omitempty"`
+}
+
+// MarshalJSON writes the paths of the error as a GraphQL response path, such as
+// ["users", 2, "email"].
+func (e *Error) MarshalJSON() ([]byte, error) {
+	v := jsonError{Message: e.Message, Extensions: e.Extensions, Locations: e.Locations}
+	if e.Paths != nil {
+		v.Paths = make([]interface{}, 0, len(e.Paths))
+		for _, segment := range e.Paths {
+			if isIndex(segment) {
+				v.Paths = append(v.Paths, json.Number(segment))
+			} else {
+				v.Paths = append(v.Paths, segment)
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads an error written by MarshalJSON.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var v jsonError
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+
+	e.Message, e.Extensions, e.Paths, e.Locations = v.Message, v.Extensions, nil, v.Locations
+	if v.Paths != nil {
+		e.Paths = make([]string, 0, len(v.Paths))
+		for _, segment := range v.Paths {
+			e.Paths = append(e.Paths, fmt.Sprint(segment))
+		}
+	}
+	return nil
+}
+
+// isIndex reports whether the path segment is the index of a list element. Field names can't
+// start with a digit.
+func isIndex(segment string) bool {
+	if segment == "" || (segment[0] == '0' && segment != "0") {
+		return false
+	}
+	for _, c := range segment {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // Extension contains extra fields in the error
 type Extension struct {
-	Code string `json:"code"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
 func (e *Error) Error() string {
@@ -26,8 +126,29 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// HTTPStatus returns the HTTP status of the error it was converted from, if that error
+// implements interface{ HTTPStatus() int }, or 0.
+func (e *Error) HTTPStatus() int {
+	if e == nil {
+		return 0
+	}
+
+	return e.httpStatus
+}
+
 //NestErrorPaths is used to nest paths along with the error
+//
+// An error implementing interface{ Errors() []error } is expanded into a MultiError, with
+// path nested in every one of its errors.
 func NestErrorPaths(e error, path string) error {
+	if multi := ConvertMultiError(e); multi != nil {
+		nested := &MultiError{Errors: make([]*Error, 0, len(multi.Errors))}
+		for _, err := range multi.Errors {
+			nested.Errors = append(nested.Errors, NestErrorPaths(err, path).(*Error))
+		}
+		return nested
+	}
+
 	err := ConvertError(e)
 
 	newError := &Error{
@@ -35,17 +156,32 @@ func NestErrorPaths(e error, path string) error {
 		Extensions: &Extension{
 			Code: err.Extensions.Code,
 		},
-		Message: err.Message,
+		Message:    err.Message,
+		Locations:  err.Locations,
+		httpStatus: err.httpStatus,
 	}
 	newError.Paths = append(newError.Paths, err.Paths...)
 
 	return newError
 }
 
-// ConvertError converts any error to jerrors.Error
+// ConvertError converts any error to jerrors.Error. The code and paths of a wrapped
+// jerrors.Error are preserved along with the message of the wrapping error. The locations are
+// taken from an error implementing interface{ Locations() []Location }, if any.
 func ConvertError(e error) *Error {
 	err, ok := (e).(*Error)
 	if !ok {
+		var wrapped *Error
+		if errors.As(e, &wrapped) && wrapped != nil {
+			return &Error{
+				Paths:      wrapped.Paths,
+				Extensions: wrapped.Extensions,
+				Message:    e.Error(),
+				Locations:  locations(e, wrapped.Locations),
+				httpStatus: httpStatus(e),
+			}
+		}
+
 		codeErr := status.Convert(e)
 
 		return &Error{
@@ -53,7 +189,9 @@ func ConvertError(e error) *Error {
 			Extensions: &Extension{
 				Code: codeErr.Code().String(),
 			},
-			Message: codeErr.Message(),
+			Message:    codeErr.Message(),
+			Locations:  locations(e, nil),
+			httpStatus: httpStatus(e),
 		}
 	}
 
@@ -64,6 +202,26 @@ type MultiError struct {
 	Errors []*Error
 }
 
+// ConvertMultiError converts a MultiError, or an error implementing interface{ Errors() []error },
+// to a MultiError. It returns nil for any other error.
+func ConvertMultiError(e error) *MultiError {
+	var multi *MultiError
+	if errors.As(e, &multi) {
+		return multi
+	}
+
+	var list interface{ Errors() []error }
+	if !errors.As(e, &list) || len(list.Errors()) == 0 {
+		return nil
+	}
+
+	multi = &MultiError{}
+	for _, err := range list.Errors() {
+		multi.Errors = append(multi.Errors, ConvertError(err))
+	}
+	return multi
+}
+
 func (e *MultiError) Error() string {
 	var s strings.Builder
 
@@ -74,3 +232,24 @@ func (e *MultiError) Error() string {
 
 	return s.String()
 }
+
+// httpStatus returns the HTTP status of e if it implements interface{ HTTPStatus() int }.
+func httpStatus(e error) int {
+	var statusErr interface{ HTTPStatus() int }
+	if errors.As(e, &statusErr) {
+		return statusErr.HTTPStatus()
+	}
+
+	return 0
+}
+
+// locations returns the locations of e if it implements interface{ Locations() []Location }, or
+// else fallback.
+func locations(e error, fallback []Location) []Location {
+	var located interface{ Locations() []Location }
+	if errors.As(e, &located) {
+		return located.Locations()
+	}
+
+	return fallback
+}
@@ -13,3 +13,12 @@ func WithMiddlewares(mm ...MiddlewareFunc) HandlerOption {
 		h.Middlewares = append(h.Middlewares, mm...)
 	}
 }
+
+// WithFieldMiddlewares installs middlewares running around the resolver of every field, e.g. to
+// time fields or deny access to them. Like the middlewares of WithMiddlewares, the first one is
+// the outermost.
+func WithFieldMiddlewares(mm ...graphql.FieldMiddleware) HandlerOption {
+	return func(h *handlerOptions) {
+		h.FieldMiddlewares = append(h.FieldMiddlewares, mm...)
+	}
+}
@@ -0,0 +1,55 @@
+package jaal
+
+import (
+	"time"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+)
+
+// WithOperationObserver registers a function which is called once for every operation executed
+// by the HTTPHandler, including every operation of a batch, with its statistics, e.g. to export
+// metrics. It is also called for the operations which fail to be parsed or validated, with a
+// zero Duration, so that error rates are accurate.
+func WithOperationObserver(f func(OperationStats)) HandlerOption {
+	return func(h *handlerOptions) {
+		h.OperationObserver = f
+	}
+}
+
+// OperationStats are the statistics of an operation passed to the function registered with
+// WithOperationObserver.
+type OperationStats struct {
+	// OperationName is the name of the operation, or the name requested by the client if the
+	// operation could not be parsed.
+	OperationName string
+	// Kind is "query" or "mutation", or empty if the operation could not be parsed.
+	Kind string
+	// Duration is the time spent executing the operation, excluding its parsing and validation.
+	Duration time.Duration
+	// Errors is the number of errors of the response.
+	Errors int
+	// Complexity is the complexity of the operation, as computed for WithMaxComplexity, or 0 if
+	// the operation could not be validated.
+	Complexity int
+}
+
+// observe fills in stats from the operation query once it has been validated.
+func (h *httpHandler) observe(stats *OperationStats, root graphql.Type, query *graphql.Query) {
+	stats.OperationName = query.Name
+	stats.Kind = query.Kind
+	if complexity, err := graphql.ComplexityWithListFactor(root, query.SelectionSet, h.listComplexityFactor); err == nil {
+		stats.Complexity = complexity
+	}
+}
+
+// errorCount returns the number of errors of a response failing with err.
+func errorCount(err error) int {
+	if multi := jerrors.ConvertMultiError(err); multi != nil {
+		return len(multi.Errors)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package jaal
+
+import (
+	"html/template"
+	"net/http"
+)
+
+var playgroundTemplate = template.Must(template.New("playground").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="user-scalable=no, initial-scale=1.0, minimum-scale=1.0, maximum-scale=1.0, minimal-ui" />
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/graphql-playground-react/build/static/css/index.css" />
+  <link rel="shortcut icon" href="https://cdn.jsdelivr.net/npm/graphql-playground-react/build/favicon.png" />
+  <script src="https://cdn.jsdelivr.net/npm/graphql-playground-react/build/static/js/middleware.js"></script>
+</head>
+<body>
+  <div id="root"></div>
+  <script>
+    window.addEventListener('load', function (event) {
+      GraphQLPlayground.init(document.getElementById('root'), {
+        endpoint: {{.Endpoint}}
+      })
+    })
+  </script>
+</body>
+</html>
+`))
+
+const defaultPlaygroundTitle = "GraphQL Playground"
+
+type playgroundData struct {
+	Title    string
+	Endpoint string
+}
+
+// PlaygroundHandler serves the GraphQL Playground, configured to send queries to the
+// graphql handler mounted at endpoint.
+func PlaygroundHandler(endpoint string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servePlayground(w, playgroundData{Title: defaultPlaygroundTitle, Endpoint: endpoint})
+	})
+}
+
+func servePlayground(w http.ResponseWriter, data playgroundData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := playgroundTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
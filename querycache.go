@@ -0,0 +1,89 @@
+package jaal
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// WithQueryCache caches the parsed and validated queries of the last size distinct operations,
+// so that repeated operations are executed without being parsed and validated again. Since
+// variables are substituted while parsing, operations are only identical when their query
+// text, operation name and variables all are. A cached query is shared by all the requests
+// hitting the cache, so neither middlewares nor resolvers may modify it or its args.
+func WithQueryCache(size int) HandlerOption {
+	return func(h *handlerOptions) {
+		h.QueryCacheSize = size
+	}
+}
+
+// queryCache is a least recently used cache of validated queries.
+type queryCache struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type queryCacheEntry struct {
+	key   string
+	query *graphql.Query
+}
+
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// queryCacheKey returns the key identifying the operation of params in a queryCache.
+func queryCacheKey(params *httpPostBody) string {
+	// The variables were decoded from JSON, and maps are encoded with sorted keys.
+	variables, _ := json.Marshal(params.Variables)
+	return fmt.Sprintf("%d:%s%d:%s%s", len(params.Query), params.Query, len(params.OperationName), params.OperationName, variables)
+}
+
+// get returns the query cached for key, if any. A nil cache is always empty.
+func (c *queryCache) get(key string) (*graphql.Query, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*queryCacheEntry).query, true
+}
+
+// add caches query for key, evicting the least recently used query if the cache is full. It
+// does nothing on a nil cache.
+func (c *queryCache) add(key string, query *graphql.Query) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*queryCacheEntry).query = query
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&queryCacheEntry{key: key, query: query})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
@@ -0,0 +1,158 @@
+package schemabuilder
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// buildBatchFunction builds a field resolved by a function registered with BatchFieldFunc, which
+// receives the sources of many objects at once.
+func (sb *schemaBuilder) buildBatchFunction(typ reflect.Type, m *method) (*graphql.Field, error) {
+	funcCtx := &funcContext{typ: typ}
+
+	callableFunc, err := funcCtx.getFuncVal(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Directives) > 0 {
+		return nil, fmt.Errorf("%s is batched, so directives cannot be applied to it", funcCtx.funcType)
+	}
+
+	in := funcCtx.getFuncInputTypes()
+	if len(in) > 0 && in[0] == contextType {
+		funcCtx.hasContext = true
+		in = in[1:]
+	}
+
+	if len(in) == 0 || in[0].Kind() != reflect.Slice || (in[0].Elem() != typ && in[0].Elem() != reflect.PtrTo(typ)) {
+		return nil, fmt.Errorf("%s should accept the sources as []%s or []*%s", funcCtx.funcType, typ, typ)
+	}
+	sourcesTyp := in[0]
+	funcCtx.hasSource = true
+	funcCtx.isPtrFunc = sourcesTyp.Elem().Kind() == reflect.Ptr
+	in = in[1:]
+
+	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, m, in)
+	if err != nil {
+		return nil, err
+	}
+	funcCtx.hasArgs = argParser != nil
+
+	in = funcCtx.consumeSelectionSet(in)
+
+	if len(in) != 0 {
+		return nil, fmt.Errorf("%s arguments should be [context, ][]*%s[, args][, selectionSet]", funcCtx.funcType, typ)
+	}
+
+	// The function must return a slice of values, and can optionally return an error.
+	out := funcCtx.funcType
+	if out.NumOut() == 0 || out.NumOut() > 2 || out.Out(0).Kind() != reflect.Slice || (out.NumOut() == 2 && out.Out(1) != errType) {
+		return nil, fmt.Errorf("%s return values should be []result[, error]", funcCtx.funcType)
+	}
+	funcCtx.hasRet = true
+	funcCtx.hasError = out.NumOut() == 2
+
+	// The result of every source may be a function, or a list of functions, called lazily like
+	// the functions returned by FieldFunc.
+	result := out.Out(0).Elem()
+	if result.Kind() == reflect.Func || (result.Kind() == reflect.Slice && result.Elem().Kind() == reflect.Func) {
+		function := result
+		if result.Kind() == reflect.Slice {
+			function = result.Elem()
+			funcCtx.returnsFuncList = true
+		} else {
+			funcCtx.returnsFunc = true
+		}
+
+		if function.NumIn() > 0 {
+			return nil, fmt.Errorf("%s should have zero arguments", function)
+		}
+		if function.NumOut() == 0 || function.NumOut() > 2 || function.Out(0) == errType || (function.NumOut() == 2 && function.Out(1) != errType) {
+			return nil, fmt.Errorf("%s return values should be result[, error]", function)
+		}
+		funcCtx.elementFuncHasErr = function.NumOut() == 2
+
+		result = function.Out(0)
+		if funcCtx.returnsFuncList {
+			result = reflect.SliceOf(result)
+		}
+	}
+
+	retType, err := sb.getType(result)
+	if err != nil {
+		return nil, err
+	}
+	if m.MarkedNonNullable {
+		if _, ok := retType.(*graphql.NonNull); !ok {
+			retType = &graphql.NonNull{Type: retType}
+		}
+	}
+
+	args, err := funcCtx.argsTypeMap(argType)
+	if err != nil {
+		return nil, err
+	}
+
+	batchResolver := func(ctx context.Context, sources []interface{}, args interface{}, selectionSet *graphql.SelectionSet) ([]interface{}, error) {
+		funcInputArgs := make([]reflect.Value, 0, funcCtx.funcType.NumIn())
+		if funcCtx.hasContext {
+			funcInputArgs = append(funcInputArgs, reflect.ValueOf(withSelectionSet(ctx, selectionSet)))
+		}
+
+		sourceValues := reflect.MakeSlice(sourcesTyp, len(sources), len(sources))
+		for i, source := range sources {
+			sourceValues.Index(i).Set(funcCtx.sourceValue(source))
+		}
+		funcInputArgs = append(funcInputArgs, sourceValues)
+
+		if funcCtx.hasArgs {
+			funcInputArgs = append(funcInputArgs, reflect.ValueOf(args))
+		}
+		if funcCtx.hasSelectionSet {
+			funcInputArgs = append(funcInputArgs, reflect.ValueOf(selectionSet))
+		}
+
+		funcOutputArgs := callableFunc.Call(funcInputArgs)
+		if funcCtx.hasError {
+			if err := funcOutputArgs[1]; !err.IsNil() {
+				return nil, err.Interface().(error)
+			}
+		}
+
+		results := funcOutputArgs[0]
+		if results.Len() != len(sources) {
+			return nil, fmt.Errorf("%s returned %d results for %d sources", funcCtx.funcType, results.Len(), len(sources))
+		}
+
+		values := make([]interface{}, results.Len())
+		for i := range values {
+			values[i] = results.Index(i).Interface()
+		}
+		return values, nil
+	}
+
+	return &graphql.Field{
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			values, err := batchResolver(ctx, []interface{}{source}, args, selectionSet)
+			if err != nil {
+				return nil, err
+			}
+			return values[0], nil
+		},
+		BatchResolver:     batchResolver,
+		Args:              args,
+		DeprecatedArgs:    funcCtx.deprecatedArgs(argType),
+		Type:              retType,
+		ParseArguments:    argParser.Parse,
+		Expensive:         funcCtx.hasContext,
+		External:          true,
+		IsDeprecated:      m.Deprecated,
+		DeprecationReason: m.DeprecationReason,
+		LazyExecution:     funcCtx.returnsFunc,
+		LazyListExecution: funcCtx.returnsFuncList,
+		LazyResolver:      funcCtx.resolveListElementFunc,
+	}, nil
+}
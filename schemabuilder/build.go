@@ -18,6 +18,14 @@ type schemaBuilder struct {
 	enumMappings map[reflect.Type]*EnumMapping
 	typeCache    map[reflect.Type]cachedType // typeCache maps Go types to GraphQL datatypes
 	inputObjects map[reflect.Type]*InputObject
+
+	// unions are the Go types of the members of the unions registered with Schema.Union, by
+	// name, and unionTypes the unions built from them.
+	unions     map[string][]reflect.Type
+	unionTypes map[string]*graphql.Union
+
+	// fieldNameMapper converts the Go names of the fields of args structs into GraphQL names.
+	fieldNameMapper func(string) string
 }
 
 // cachedType is a container for GraphQL datatype and the list of its fields
@@ -26,6 +34,27 @@ type cachedType struct {
 	fields  map[string]argField
 }
 
+// validateNames checks that the fields of the objects and input objects, and the values of the
+// enums, registered on the schema have distinct names.
+func (sb *schemaBuilder) validateNames() error {
+	for typ, object := range sb.objects {
+		if len(object.duplicates) > 0 {
+			return fmt.Errorf("bad type %s: duplicate field %s", typ, object.duplicates[0])
+		}
+	}
+	for typ, inputObject := range sb.inputObjects {
+		if len(inputObject.duplicates) > 0 {
+			return fmt.Errorf("bad input type %s: duplicate field %s", typ, inputObject.duplicates[0])
+		}
+	}
+	for typ, mapping := range sb.enumMappings {
+		if len(mapping.duplicates) > 0 {
+			return fmt.Errorf("bad enum %s: duplicate value %s", typ, mapping.duplicates[0])
+		}
+	}
+	return nil
+}
+
 // getType is the "core" function of the GraphQL schema builder.  It takes in a reflect type and builds the appropriate graphQL "type".
 // This includes going through struct fields and attached object methods to generate the entire graphql graph of possible queries.
 // This function will be called recursively for types as we go through the graph.
@@ -36,11 +65,11 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 	}
 
 	if typeName, ok := getScalar(nodeType); ok {
-		return &graphql.NonNull{Type: &graphql.Scalar{Type: typeName}}, nil
+		return &graphql.NonNull{Type: &graphql.Scalar{Type: typeName, Description: scalarDescriptions[typeName], SpecifiedByURL: scalarSpecifiedByURLs[typeName], Unwrapper: getScalarUnwrapper(nodeType)}}, nil
 	}
 	if nodeType.Kind() == reflect.Ptr {
 		if typeName, ok := getScalar(nodeType.Elem()); ok {
-			return &graphql.Scalar{Type: typeName}, nil // XXX: prefix typ with "*"
+			return &graphql.Scalar{Type: typeName, Description: scalarDescriptions[typeName], SpecifiedByURL: scalarSpecifiedByURLs[typeName], Unwrapper: getScalarUnwrapper(nodeType.Elem())}, nil // XXX: prefix typ with "*"
 		}
 	}
 
@@ -58,6 +87,14 @@ func (sb *schemaBuilder) getType(nodeType reflect.Type) (graphql.Type, error) {
 		return sb.types[nodeType.Elem()], nil
 	}
 
+	// Interfaces implemented by the members of a union or interface
+	if nodeType.Kind() == reflect.Interface {
+		if err := sb.buildAbstract(nodeType); err != nil {
+			return nil, err
+		}
+		return sb.types[nodeType], nil
+	}
+
 	switch nodeType.Kind() {
 	case reflect.Slice:
 		elementType, err := sb.getType(nodeType.Elem())
@@ -84,7 +121,7 @@ func (sb *schemaBuilder) getEnum(typ reflect.Type) (string, []string, bool) {
 		for mapping := range sb.enumMappings[typ].Map {
 			values = append(values, mapping)
 		}
-		return typ.Name(), values, true
+		return sb.enumMappings[typ].name(typ), values, true
 	}
 	return "", nil, false
 }
@@ -92,6 +129,9 @@ func (sb *schemaBuilder) getEnum(typ reflect.Type) (string, []string, bool) {
 // getScalar grabs the appropriate scalar graphql field type name for the passed
 // in variable reflect type.
 func getScalar(typ reflect.Type) (string, bool) {
+	if name, ok := scalars[typ]; ok {
+		return name, true
+	}
 	for match, name := range scalars {
 		if typesIdenticalOrScalarAliases(match, typ) {
 			return name, true
@@ -100,6 +140,38 @@ func getScalar(typ reflect.Type) (string, bool) {
 	return "", false
 }
 
+// getScalarUnwrapper returns the unwrapper which serializes values of a scalar registered
+// with a custom serializer, or nil if the scalar uses the default unwrapper.
+func getScalarUnwrapper(typ reflect.Type) func(interface{}) (interface{}, error) {
+	serialize, ok := scalarSerializers[typ]
+	if !ok {
+		return nil
+	}
+
+	return func(source interface{}) (interface{}, error) {
+		value := reflect.ValueOf(source)
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return nil, nil
+			}
+			value = value.Elem()
+		}
+		return serialize(value.Interface())
+	}
+}
+
+// scalarSerializers are the custom serializers of scalars registered with WithSerializer.
+var scalarSerializers = map[reflect.Type]SerializeFunc{}
+
+// scalarDescriptions are the descriptions of scalars registered with WithScalarDescription, by name.
+var scalarDescriptions = map[string]string{
+	"StringMap": "An object whose values are strings, such as {key: \"value\"}.",
+	"IntMap":    "An object whose values are integers, such as {key: 1}.",
+}
+
+// scalarSpecifiedByURLs are the URLs of the specifications of scalars registered with WithSpecifiedByURL, by name.
+var scalarSpecifiedByURLs = map[string]string{}
+
 var scalars = map[reflect.Type]string{
 	reflect.TypeOf(bool(false)):                      "Boolean",
 	reflect.TypeOf(int(0)):                           "Int",
@@ -117,7 +189,13 @@ var scalars = map[reflect.Type]string{
 	reflect.TypeOf(string("")):                       "String",
 	reflect.TypeOf(ID{Value: ""}):                    "ID",
 	reflect.TypeOf(Map{Value: ""}):                   "Map",
+	reflect.TypeOf(JSON{}):                           "JSON",
 	reflect.TypeOf(Timestamp(timestamp.Timestamp{})): "Timestamp",
 	reflect.TypeOf(Duration(duration.Duration{})):    "Duration",
 	reflect.TypeOf(Bytes{Value: []byte{}}):           "Bytes",
+
+	// Maps are read from and written as JSON objects, so that input literals like
+	// {key: "value"} are accepted as arguments.
+	reflect.TypeOf(map[string]string{}): "StringMap",
+	reflect.TypeOf(map[string]int{}):    "IntMap",
 }
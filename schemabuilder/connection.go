@@ -0,0 +1,297 @@
+package schemabuilder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Page is a page of the nodes of a connection, returned by the resolvers of fields registered
+// with the FieldOption returned by Schema.Connection.
+type Page struct {
+	// Items is a slice of the nodes in the page, of the node type of the connection.
+	Items interface{}
+
+	// Offset is the index of the first item of the page in the whole list. The cursor of an
+	// item defaults to EncodeCursor of its index.
+	Offset int64
+
+	// Cursor, if set, returns the cursor of an item instead, e.g. encoding its key.
+	Cursor func(item interface{}) string
+
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int64
+}
+
+// PageInfo is the standard Relay object describing the page returned for a connection.
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     string
+	EndCursor       string
+}
+
+var pageType = reflect.TypeOf(Page{})
+
+// EncodeCursor returns the default cursor of the item at index, the base64 encoding of the index.
+func EncodeCursor(index int64) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(index, 10)))
+}
+
+// DecodeCursor returns the index of an item from a cursor returned by EncodeCursor.
+func DecodeCursor(cursor string) (int64, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	index, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return index, nil
+}
+
+// Pagination holds the args of a paginated list field, to be embedded in the args struct of its
+// resolver and passed to Paginate. All of them are optional: the items after the cursor After,
+// if set, are skipped by Offset, and at most First are returned. Limit is a synonym of First,
+// the smaller of both applying when both are set.
+type Pagination struct {
+	First  *int64
+	After  *string
+	Offset *int64
+	Limit  *int64
+}
+
+// Paginate returns the page of the slice items selected by args, which can be returned as is by
+// the resolver of a connection. For example:
+//   query.FieldFunc("users", func(args struct{ schemabuilder.Pagination }) (*schemabuilder.Page, error) {
+//     return schemabuilder.Paginate(users, args.Pagination)
+//   }, usersConnection)
+//
+// It fails if First, Offset or Limit is negative, or if After is not the cursor of an item.
+func Paginate(items interface{}, args Pagination) (*Page, error) {
+	value := reflect.ValueOf(items)
+	if value.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("paginated items should be a slice, not %T", items)
+	}
+	total := int64(value.Len())
+
+	var start int64
+	if args.After != nil {
+		index, err := DecodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= total {
+			return nil, fmt.Errorf("invalid cursor %q", *args.After)
+		}
+		start = index + 1
+	}
+	if args.Offset != nil {
+		if *args.Offset < 0 {
+			return nil, fmt.Errorf("offset must be non-negative, received %d", *args.Offset)
+		}
+		if *args.Offset < total-start {
+			start += *args.Offset
+		} else {
+			start = total
+		}
+	}
+
+	end := total
+	for _, count := range []struct {
+		name  string
+		value *int64
+	}{{"first", args.First}, {"limit", args.Limit}} {
+		if count.value == nil {
+			continue
+		}
+		if *count.value < 0 {
+			return nil, fmt.Errorf("%s must be non-negative, received %d", count.name, *count.value)
+		}
+		if *count.value < end-start {
+			end = start + *count.value
+		}
+	}
+
+	return &Page{
+		Items:           value.Slice(int(start), int(end)).Interface(),
+		Offset:          start,
+		HasNextPage:     end < total,
+		HasPreviousPage: start > 0,
+		TotalCount:      total,
+	}, nil
+}
+
+// Connection registers the Relay connection objects for nodes of the Go type of nodeType: a
+// <name>Connection object with the edges, pageInfo and totalCount fields, a <name>Edge object
+// with the node and cursor fields, and the PageInfo object shared by all connections.
+//
+// The returned FieldOption exposes a field as the connection. The resolver of the field
+// returns a Page, or a *Page, holding the nodes, and the cursors of the edges are computed from
+// it. For example:
+//   usersConnection := schema.Connection("User", &User{})
+//   query.FieldFunc("users", func(args struct{ First int64 }) (*schemabuilder.Page, error) {
+//     users, total, err := db.ListUsers(args.First)
+//     return &schemabuilder.Page{Items: users, TotalCount: total, HasNextPage: args.First < total}, err
+//   }, usersConnection)
+func (s *Schema) Connection(name string, nodeType interface{}) FieldOption {
+	nodeTyp := reflect.TypeOf(nodeType)
+
+	// Every connection needs its own Go types to be registered as objects, which are told apart
+	// by the name in their tag.
+	tag := reflect.StructTag(fmt.Sprintf(`connection:%q`, name))
+	edgeTyp := reflect.StructOf([]reflect.StructField{
+		{Name: "Node", Type: nodeTyp, Tag: tag},
+		{Name: "Cursor", Type: reflect.TypeOf("")},
+	})
+	connectionTyp := reflect.StructOf([]reflect.StructField{
+		{Name: "Page", Type: pageType, Tag: tag},
+	})
+
+	edge := s.Object(name+"Edge", reflect.Zero(edgeTyp).Interface())
+	edge.FieldFunc("node", makeFieldFunc(edgeTyp, nodeTyp, func(in reflect.Value) (reflect.Value, error) {
+		return in.Field(0), nil
+	}))
+	edge.FieldFunc("cursor", makeFieldFunc(edgeTyp, reflect.TypeOf(""), func(in reflect.Value) (reflect.Value, error) {
+		return in.Field(1), nil
+	}))
+
+	connection := s.Object(name+"Connection", reflect.Zero(connectionTyp).Interface())
+	connection.FieldFunc("edges", makeFieldFunc(connectionTyp, reflect.SliceOf(edgeTyp), func(in reflect.Value) (reflect.Value, error) {
+		page := in.Field(0).Interface().(Page)
+		items, err := page.items(nodeTyp)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		edges := reflect.MakeSlice(reflect.SliceOf(edgeTyp), items.Len(), items.Len())
+		for i := 0; i < items.Len(); i++ {
+			edges.Index(i).Field(0).Set(items.Index(i))
+			edges.Index(i).Field(1).SetString(page.cursor(items, i))
+		}
+		return edges, nil
+	}))
+	connection.FieldFunc("pageInfo", makeFieldFunc(connectionTyp, reflect.TypeOf(PageInfo{}), func(in reflect.Value) (reflect.Value, error) {
+		page := in.Field(0).Interface().(Page)
+		items, err := page.items(nodeTyp)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		info := PageInfo{HasNextPage: page.HasNextPage, HasPreviousPage: page.HasPreviousPage}
+		if items.Len() > 0 {
+			info.StartCursor = page.cursor(items, 0)
+			info.EndCursor = page.cursor(items, items.Len()-1)
+		}
+		return reflect.ValueOf(info), nil
+	}))
+	connection.FieldFunc("totalCount", makeFieldFunc(connectionTyp, reflect.TypeOf(int64(0)), func(in reflect.Value) (reflect.Value, error) {
+		return in.Field(0).FieldByName("TotalCount"), nil
+	}))
+
+	s.registerPageInfo()
+
+	return func(m *method) {
+		m.Fn = connectionFunc(m.Fn, connectionTyp)
+	}
+}
+
+// registerPageInfo registers the PageInfo object, once for all connections.
+func (s *Schema) registerPageInfo() {
+	pageInfo := s.Object("PageInfo", PageInfo{})
+	if len(pageInfo.Methods) > 0 {
+		return
+	}
+
+	pageInfo.FieldFunc("hasNextPage", func(in PageInfo) bool { return in.HasNextPage })
+	pageInfo.FieldFunc("hasPreviousPage", func(in PageInfo) bool { return in.HasPreviousPage })
+	pageInfo.FieldFunc("startCursor", func(in PageInfo) *string { return nonEmpty(in.StartCursor) })
+	pageInfo.FieldFunc("endCursor", func(in PageInfo) *string { return nonEmpty(in.EndCursor) })
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// items returns the items of the page, checking that they are of the node type of the connection.
+func (p *Page) items(nodeTyp reflect.Type) (reflect.Value, error) {
+	items := reflect.ValueOf(p.Items)
+	if !items.IsValid() {
+		return reflect.MakeSlice(reflect.SliceOf(nodeTyp), 0, 0), nil
+	}
+	if items.Kind() != reflect.Slice || !items.Type().Elem().AssignableTo(nodeTyp) {
+		return reflect.Value{}, fmt.Errorf("page items should be a slice of %s, not %s", nodeTyp, items.Type())
+	}
+	return items, nil
+}
+
+// cursor returns the cursor of the i-th item of the page.
+func (p *Page) cursor(items reflect.Value, i int) string {
+	if p.Cursor != nil {
+		return p.Cursor(items.Index(i).Interface())
+	}
+	return EncodeCursor(p.Offset + int64(i))
+}
+
+// makeFieldFunc creates a field func of an object of the Go type typ, returning a value of the
+// Go type ret, along with an error.
+func makeFieldFunc(typ, ret reflect.Type, f func(in reflect.Value) (reflect.Value, error)) interface{} {
+	funcTyp := reflect.FuncOf([]reflect.Type{reflect.PtrTo(typ)}, []reflect.Type{ret, errType}, false)
+
+	return reflect.MakeFunc(funcTyp, func(args []reflect.Value) []reflect.Value {
+		out, err := f(args[0].Elem())
+		if err != nil {
+			return []reflect.Value{reflect.Zero(ret), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{out, reflect.Zero(errType)}
+	}).Interface()
+}
+
+// connectionFunc wraps the resolver fn returning a Page or a *Page, so that it returns the page as
+// a *connectionTyp instead.
+func connectionFunc(fn interface{}, connectionTyp reflect.Type) interface{} {
+	fnVal := reflect.ValueOf(fn)
+	fnTyp := fnVal.Type()
+	if fnTyp.Kind() != reflect.Func || fnTyp.NumOut() == 0 || (fnTyp.Out(0) != pageType && fnTyp.Out(0) != reflect.PtrTo(pageType)) {
+		panic(fmt.Errorf("%s should return a schemabuilder.Page to be exposed as a connection", fnTyp))
+	}
+
+	in := make([]reflect.Type, 0, fnTyp.NumIn())
+	for i := 0; i < fnTyp.NumIn(); i++ {
+		in = append(in, fnTyp.In(i))
+	}
+	out := []reflect.Type{reflect.PtrTo(connectionTyp)}
+	for i := 1; i < fnTyp.NumOut(); i++ {
+		out = append(out, fnTyp.Out(i))
+	}
+
+	return reflect.MakeFunc(reflect.FuncOf(in, out, fnTyp.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if fnTyp.IsVariadic() {
+			results = fnVal.CallSlice(args)
+		} else {
+			results = fnVal.Call(args)
+		}
+
+		page := results[0]
+		if page.Kind() == reflect.Ptr {
+			if page.IsNil() {
+				results[0] = reflect.Zero(out[0])
+				return results
+			}
+			page = page.Elem()
+		}
+
+		connection := reflect.New(connectionTyp)
+		connection.Elem().Field(0).Set(page)
+		results[0] = connection
+		return results
+	}).Interface()
+}
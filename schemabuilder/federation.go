@@ -0,0 +1,198 @@
+package schemabuilder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// ReferenceResolver resolves an entity of the Apollo Federation from its representation, which
+// holds its __typename along with the fields of its key. It returns the entity, either as a
+// value or a pointer of the type registered for it, or nil if it does not exist.
+type ReferenceResolver func(ctx context.Context, key map[string]interface{}) (interface{}, error)
+
+// RegisterReferenceResolver makes the object registered with typeName an entity of the Apollo
+// Federation, identified by the field registered with Key. For example:
+//   user := schema.Object("User", User{})
+//   user.Key("id")
+//   schema.RegisterReferenceResolver("User", func(ctx context.Context, key map[string]interface{}) (interface{}, error) {
+//     return db.User(ctx, key["id"].(string))
+//   })
+//
+// A schema with entities exposes the _service query, returning the SDL of the schema annotated
+// with the @key of every entity, and the _entities query, resolving the entities of a list of
+// representations, which let the schema be served behind an Apollo Gateway.
+func (s *Schema) RegisterReferenceResolver(typeName string, f ReferenceResolver) {
+	if s.referenceResolvers == nil {
+		s.referenceResolvers = make(map[string]ReferenceResolver)
+	}
+	s.referenceResolvers[typeName] = f
+}
+
+// buildFederation adds the _service and _entities queries to the query of schema, resolving the
+// entities registered with resolvers.
+func (sb *schemaBuilder) buildFederation(schema *graphql.Schema, resolvers map[string]ReferenceResolver) error {
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The entities are held by a struct with a field per entity, named after it, which is
+	// resolved as the _Entity union.
+	entity := &graphql.Union{
+		Name:        "_Entity",
+		Description: "An entity of the Apollo Federation, resolved through the _entities query.",
+		Types:       make(map[string]*graphql.Object),
+	}
+	var fields []reflect.StructField
+	for _, name := range names {
+		typ, object, ok := sb.objectByName(name)
+		if !ok {
+			return fmt.Errorf("reference resolver registered for %s, which is not a registered object", name)
+		}
+		if object.key == "" {
+			return fmt.Errorf("bad entity %s: should have a key", name)
+		}
+		if !unicode.IsUpper([]rune(name)[0]) {
+			return fmt.Errorf("bad entity %s: should have a capitalized name", name)
+		}
+
+		built, err := sb.getType(reflect.PtrTo(typ))
+		if err != nil {
+			return err
+		}
+		obj := built.(*graphql.Object)
+		obj.FederationKey = object.key
+
+		entity.Types[name] = obj
+		fields = append(fields, reflect.StructField{Name: name, Type: reflect.PtrTo(typ)})
+	}
+	entitiesTyp := reflect.SliceOf(reflect.PtrTo(reflect.StructOf(fields)))
+
+	sdl := printSDL(schema, entity)
+
+	query := schema.Query.(*graphql.Object)
+	query.Fields["_service"] = &graphql.Field{
+		Type: &graphql.NonNull{Type: &graphql.Object{
+			Name: "_Service",
+			Fields: map[string]*graphql.Field{
+				"sdl": {
+					Type:           &graphql.Scalar{Type: "String"},
+					ParseArguments: nilParseArguments,
+					Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+						return sdl, nil
+					},
+				},
+			},
+		}},
+		ParseArguments: nilParseArguments,
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			return struct{}{}, nil
+		},
+	}
+
+	query.Fields["_entities"] = &graphql.Field{
+		Type: &graphql.NonNull{Type: &graphql.List{Type: entity}},
+		Args: map[string]graphql.Type{
+			"representations": &graphql.NonNull{Type: &graphql.List{Type: &graphql.NonNull{Type: &graphql.Scalar{
+				Type:        "_Any",
+				Description: "The representation of an entity, holding its __typename and the fields of its key.",
+			}}}},
+		},
+		ParseArguments: func(args interface{}) (interface{}, error) {
+			return parseRepresentations(args, resolvers)
+		},
+		Resolve: func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+			representations := args.([]map[string]interface{})
+			entities := reflect.MakeSlice(entitiesTyp, len(representations), len(representations))
+
+			for i, representation := range representations {
+				typename := representation["__typename"].(string)
+				value, err := resolvers[typename](ctx, representation)
+				if err != nil {
+					return nil, err
+				}
+				if value == nil {
+					continue
+				}
+
+				holder := reflect.New(entitiesTyp.Elem().Elem())
+				field := holder.Elem().FieldByName(typename)
+				switch v := reflect.ValueOf(value); v.Type() {
+				case field.Type():
+					field.Set(v)
+				case field.Type().Elem():
+					ptr := reflect.New(v.Type())
+					ptr.Elem().Set(v)
+					field.Set(ptr)
+				default:
+					return nil, fmt.Errorf("reference resolver of %s returned %T, expected %s", typename, value, field.Type())
+				}
+
+				if !field.IsNil() {
+					entities.Index(i).Set(holder)
+				}
+			}
+
+			return entities.Interface(), nil
+		},
+	}
+
+	return nil
+}
+
+// objectByName returns the object registered with name, along with its type.
+func (sb *schemaBuilder) objectByName(name string) (reflect.Type, *Object, bool) {
+	for typ, object := range sb.objects {
+		if object.Name == name {
+			return typ, object, true
+		}
+	}
+	return nil, nil, false
+}
+
+// parseRepresentations parses the representations argument of the _entities query, checking
+// that every representation is an object naming an entity.
+func parseRepresentations(args interface{}, resolvers map[string]ReferenceResolver) (interface{}, error) {
+	asMap, ok := args.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("not an object")
+	}
+	list, ok := asMap["representations"].([]interface{})
+	if !ok {
+		return nil, errors.New("representations: not a list")
+	}
+
+	representations := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		representation, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("representations: not an object")
+		}
+		typename, _ := representation["__typename"].(string)
+		if _, ok := resolvers[typename]; !ok {
+			return nil, fmt.Errorf("representations: unknown entity %q", typename)
+		}
+		representations = append(representations, representation)
+	}
+	return representations, nil
+}
+
+// printSDL prints the SDL of the types of schema, along with the members of entity which may
+// only be reachable through the _entities query.
+func printSDL(schema *graphql.Schema, entity *graphql.Union) string {
+	types := make(map[string]graphql.Type)
+	graphql.CollectTypes(schema.Query, types)
+	graphql.CollectTypes(schema.Mutation, types)
+	graphql.CollectTypes(schema.Subscription, types)
+	for _, object := range entity.Types {
+		graphql.CollectTypes(object, types)
+	}
+	return graphql.PrintTypes(types)
+}
@@ -2,6 +2,7 @@ package schemabuilder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -30,7 +31,7 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 	in := funcCtx.getFuncInputTypes()
 	in = funcCtx.consumeContextAndSource(in)
 
-	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, in)
+	argParser, argType, in, err := funcCtx.getArgParserAndTyp(sb, m, in)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -54,11 +55,34 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 		return nil, nil, err
 	}
 
+	if m.ListNullPolicy == DropNulls {
+		list, ok := retType.(*graphql.List)
+		if nonNull, isNonNull := retType.(*graphql.NonNull); isNonNull {
+			list, ok = nonNull.Type.(*graphql.List)
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("%s should return a list to drop its null elements", funcCtx.funcType)
+		}
+		list.DropNulls = true
+	}
+
 	args, err := funcCtx.argsTypeMap(argType)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	lazyResolver := func(ctx context.Context, fun interface{}) (interface{}, error) {
+		callableFunc := reflect.ValueOf(fun)
+
+		var funcOutputArgs []reflect.Value
+		funcOutputArgs = callableFunc.Call([]reflect.Value{})
+
+		return funcCtx.extractResultAndErr(funcOutputArgs)
+	}
+	if funcCtx.returnsFuncList {
+		lazyResolver = funcCtx.resolveListElementFunc
+	}
+
 	return &graphql.Field{
 		Resolve: func(ctx context.Context, source, funcRawArgs interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
 			// Set up function arguments.
@@ -67,26 +91,34 @@ func (sb *schemaBuilder) buildFunctionAndFuncCtx(typ reflect.Type, m *method) (*
 			var funcOutputArgs []reflect.Value
 			funcOutputArgs = callableFunc.Call(funcInputArgs)
 
-			return funcCtx.extractResultAndErr(funcOutputArgs, retType)
-
-		},
-		Args:           args,
-		Type:           retType,
-		ParseArguments: argParser.Parse,
-		Expensive:      funcCtx.hasContext,
-		External:       true,
-		LazyExecution:  funcCtx.returnsFunc,
-		LazyResolver: func(ctx context.Context, fun interface{}) (interface{}, error) {
-			callableFunc := reflect.ValueOf(fun)
-
-			var funcOutputArgs []reflect.Value
-			funcOutputArgs = callableFunc.Call([]reflect.Value{})
+			return funcCtx.extractResultAndErr(funcOutputArgs)
 
-			return funcCtx.extractResultAndErr(funcOutputArgs, retType)
 		},
+		Args:              args,
+		DeprecatedArgs:    funcCtx.deprecatedArgs(argType),
+		Type:              retType,
+		ParseArguments:    argParser.Parse,
+		Expensive:         funcCtx.hasContext,
+		External:          true,
+		IsDeprecated:      m.Deprecated,
+		DeprecationReason: m.DeprecationReason,
+		LazyExecution:     funcCtx.returnsFunc,
+		LazyListExecution: funcCtx.returnsFuncList,
+		LazyResolver:      lazyResolver,
+		Directives:        fieldDirectives(m),
 	}, funcCtx, nil
 }
 
+// fieldDirectives returns the directives applied to the field of m, whose args are parsed and
+// handlers set once the directives of the schema are built.
+func fieldDirectives(m *method) []*graphql.FieldDirective {
+	var directives []*graphql.FieldDirective
+	for _, directive := range m.Directives {
+		directives = append(directives, &graphql.FieldDirective{Name: directive.Name, Args: directive.Args})
+	}
+	return directives
+}
+
 // funcContext is used to parse the function signature in buildFunction.
 type funcContext struct {
 	hasContext      bool
@@ -96,12 +128,21 @@ type funcContext struct {
 	hasRet          bool
 	hasError        bool
 
+	// hasPresence is set for functions returning (result, bool[, error]), whose result is null
+	// when the bool is false.
+	hasPresence bool
+
 	funcType  reflect.Type
 	isPtrFunc bool
 	typ       reflect.Type
 
 	returnsFunc    bool
 	wrapperFuncTyp reflect.Type
+
+	returnsFuncList   bool
+	elementFuncHasErr bool
+
+	returnsChan bool
 }
 
 // getFuncVal returns a reflect.Value of an executable function.
@@ -145,10 +186,18 @@ func (funcCtx *funcContext) consumeContextAndSource(in []reflect.Type) []reflect
 
 // getArgParserAndTyp reads a list of input parameters, and, if we have a set of custom parameters for the field func (at this point any input type other
 // than the selectionSet is considered the args input), we will return the argParser for that type and pop that field from the returned input parameters.
-func (funcCtx *funcContext) getArgParserAndTyp(sb *schemaBuilder, in []reflect.Type) (*argParser, graphql.Type, []reflect.Type, error) {
+func (funcCtx *funcContext) getArgParserAndTyp(sb *schemaBuilder, m *method, in []reflect.Type) (*argParser, graphql.Type, []reflect.Type, error) {
 	var argParser *argParser
 	var argType graphql.Type
-	if len(in) > 0 && in[0] != selectionSetType {
+	if len(in) > 0 && in[0] == argsMapType {
+		var err error
+		if argParser, argType, err = sb.makeArgsMapParser(m.Args); err != nil {
+			return nil, nil, in, fmt.Errorf("attempted to parse args spec of %s, but failed: %s", funcCtx.funcType, err.Error())
+		}
+		in = in[1:]
+	} else if m.Args != nil {
+		return nil, nil, in, fmt.Errorf("%s should accept args as map[string]interface{} to declare them with WithArgs", funcCtx.funcType)
+	} else if len(in) > 0 && in[0] != selectionSetType {
 		var err error
 		if argParser, argType, err = sb.makeInputObjectParser(in[0]); err != nil {
 			return nil, nil, in, fmt.Errorf("attempted to parse %s as arguments struct, but failed: %s", in[0].Name(), err.Error())
@@ -158,6 +207,42 @@ func (funcCtx *funcContext) getArgParserAndTyp(sb *schemaBuilder, in []reflect.T
 	return argParser, argType, in, nil
 }
 
+// makeArgsMapParser constructs an argParser passing the args through as a map[string]interface{}. If spec is
+// not nil, the args are declared and validated using the fields of spec, otherwise no args are declared and
+// any args are accepted.
+func (sb *schemaBuilder) makeArgsMapParser(spec interface{}) (*argParser, graphql.Type, error) {
+	var specParser *argParser
+	var argType graphql.Type = &graphql.InputObject{InputFields: make(map[string]graphql.Type)}
+	if spec != nil {
+		var err error
+		if specParser, argType, err = sb.makeInputObjectParser(reflect.TypeOf(spec)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &argParser{
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			asMap, ok := value.(map[string]interface{})
+			if !ok && value != nil {
+				return errors.New("not an object")
+			}
+
+			if specParser != nil {
+				if err := specParser.FromJSON(asMap, reflect.New(specParser.Type).Elem()); err != nil {
+					return err
+				}
+			}
+
+			if asMap == nil {
+				asMap = make(map[string]interface{})
+			}
+			dest.Set(reflect.ValueOf(asMap))
+			return nil
+		},
+		Type: argsMapType,
+	}, argType, nil
+}
+
 // consumeSelectionSet reads the input parameters and will pop off the selectionSet type if we detect it in the input fields.
 // Check out graphql.SelectionSet for more infomation about selection sets.
 func (funcCtx *funcContext) consumeSelectionSet(in []reflect.Type) []reflect.Type {
@@ -184,7 +269,36 @@ func (funcCtx *funcContext) parseReturnSignature(m *method) (err error) {
 			funcCtx.returnsFunc = true
 		}
 
+		if out[0].Kind() == reflect.Slice && out[0].Elem().Kind() == reflect.Func {
+			funcCtx.returnsFuncList = true
+		}
+
+		if out[0].Kind() == reflect.Chan {
+			if funcCtx.typ != subscriptionType {
+				err = fmt.Errorf("%s returns a channel, which only subscriptions may return", funcCtx.funcType)
+				return
+			}
+			if out[0].ChanDir()&reflect.RecvDir == 0 {
+				err = fmt.Errorf("%s should return a channel which can be received from", funcCtx.funcType)
+				return
+			}
+			funcCtx.returnsChan = true
+		}
+
 		out = out[1:]
+
+		if len(out) > 0 && out[0] == boolType {
+			if funcCtx.returnsFunc || funcCtx.returnsFuncList || funcCtx.returnsChan {
+				err = fmt.Errorf("%s returns whether its result is present, which lazy and subscription fields may not", funcCtx.funcType)
+				return
+			}
+			if m.MarkedNonNullable {
+				err = fmt.Errorf("%s is marked non-nullable, but returns whether its result is present", funcCtx.funcType)
+				return
+			}
+			funcCtx.hasPresence = true
+			out = out[1:]
+		}
 	}
 
 	if len(out) > 0 && out[0] == errType {
@@ -193,7 +307,7 @@ func (funcCtx *funcContext) parseReturnSignature(m *method) (err error) {
 	}
 
 	if len(out) != 0 {
-		err = fmt.Errorf("%s return values should [result][, error]", funcCtx.funcType)
+		err = fmt.Errorf("%s return values should [result[, present]][, error]", funcCtx.funcType)
 		return
 	}
 
@@ -222,7 +336,24 @@ func (funcCtx *funcContext) getReturnType(sb *schemaBuilder, m *method) (graphql
 			funcCtx.funcType = function
 		}
 
-		retType, err = sb.getType(funcCtx.funcType.Out(0))
+		if funcCtx.returnsFuncList {
+			function := funcCtx.funcType.Out(0).Elem()
+
+			if function.NumIn() > 0 {
+				return nil, fmt.Errorf("%s should have zero arguments", function)
+			}
+			if function.NumOut() == 0 || function.NumOut() > 2 || function.Out(0) == errType || (function.NumOut() == 2 && function.Out(1) != errType) {
+				return nil, fmt.Errorf("%s return values should be result[, error]", function)
+			}
+			funcCtx.elementFuncHasErr = function.NumOut() == 2
+
+			retType, err = sb.getType(reflect.SliceOf(function.Out(0)))
+		} else if funcCtx.returnsChan {
+			// Every value received from the channel is an event of the subscription.
+			retType, err = sb.getType(funcCtx.funcType.Out(0).Elem())
+		} else {
+			retType, err = sb.getType(funcCtx.funcType.Out(0))
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -232,6 +363,11 @@ func (funcCtx *funcContext) getReturnType(sb *schemaBuilder, m *method) (graphql
 				retType = &graphql.NonNull{Type: retType}
 			}
 		}
+
+		// A result which may be absent is nullable, whatever its type.
+		if nonNull, ok := retType.(*graphql.NonNull); ok && funcCtx.hasPresence {
+			retType = nonNull.Type
+		}
 	} else {
 		var err error
 		retType, err = sb.getType(reflect.TypeOf(true))
@@ -258,27 +394,24 @@ func (funcCtx *funcContext) argsTypeMap(argType graphql.Type) (map[string]graphq
 	return args, nil
 }
 
+// deprecatedArgs returns the deprecation reasons of the deprecated input arg fields, by name.
+func (funcCtx *funcContext) deprecatedArgs(argType graphql.Type) map[string]string {
+	if inputObject, ok := argType.(*graphql.InputObject); ok && funcCtx.hasArgs {
+		return inputObject.DeprecatedFields
+	}
+	return nil
+}
+
 // prepareResolveArgs converts the provided source, args and context into the required list of reflect.Value types that the function needs to be called.
 func (funcCtx *funcContext) prepareResolveArgs(source interface{}, hasArgs bool, args interface{}, ctx context.Context, selectionSet *graphql.SelectionSet) []reflect.Value {
 	in := make([]reflect.Value, 0, funcCtx.funcType.NumIn())
 	if funcCtx.hasContext {
-		in = append(in, reflect.ValueOf(ctx))
+		in = append(in, reflect.ValueOf(withSelectionSet(ctx, selectionSet)))
 	}
 
 	// Set up source.
 	if funcCtx.hasSource {
-		sourceValue := reflect.ValueOf(source)
-		ptrSource := sourceValue.Kind() == reflect.Ptr
-		switch {
-		case ptrSource && !funcCtx.isPtrFunc:
-			in = append(in, sourceValue.Elem())
-		case !ptrSource && funcCtx.isPtrFunc:
-			copyPtr := reflect.New(funcCtx.typ)
-			copyPtr.Elem().Set(sourceValue)
-			in = append(in, copyPtr)
-		default:
-			in = append(in, sourceValue)
-		}
+		in = append(in, funcCtx.sourceValue(source))
 	}
 
 	// Set up other arguments.
@@ -292,28 +425,69 @@ func (funcCtx *funcContext) prepareResolveArgs(source interface{}, hasArgs bool,
 	return in
 }
 
+// sourceValue converts source to the pointer or value of the object type the function expects.
+func (funcCtx *funcContext) sourceValue(source interface{}) reflect.Value {
+	sourceValue := reflect.ValueOf(source)
+	ptrSource := sourceValue.Kind() == reflect.Ptr
+	switch {
+	case !sourceValue.IsValid():
+		// The root objects, such as Subscription, may be resolved without a source.
+		if funcCtx.isPtrFunc {
+			return reflect.New(funcCtx.typ)
+		}
+		return reflect.Zero(funcCtx.typ)
+	case ptrSource && !funcCtx.isPtrFunc:
+		return sourceValue.Elem()
+	case !ptrSource && funcCtx.isPtrFunc:
+		copyPtr := reflect.New(funcCtx.typ)
+		copyPtr.Elem().Set(sourceValue)
+		return copyPtr
+	default:
+		return sourceValue
+	}
+}
+
 // extractResultAndErr converts the response from calling the function into the expected type for the response object (as opposed to a reflect.Value).
 // It also handles reading whether the function ended with errors.
-func (funcCtx *funcContext) extractResultAndErr(out []reflect.Value, retType graphql.Type) (interface{}, error) {
+func (funcCtx *funcContext) extractResultAndErr(out []reflect.Value) (interface{}, error) {
 	var result interface{}
+	present := true
 	if funcCtx.hasRet {
 		result = out[0].Interface()
 		out = out[1:]
 	} else {
 		result = true
 	}
+	if funcCtx.hasPresence {
+		present = out[0].Bool()
+		out = out[1:]
+	}
 	if funcCtx.hasError {
 		if err := out[0]; !err.IsNil() {
 			return nil, err.Interface().(error)
 		}
 	}
+	if !present {
+		return nil, nil
+	}
+
+	return result, nil
+}
 
-	if _, ok := retType.(*graphql.NonNull); ok {
-		resultValue := reflect.ValueOf(result)
-		if resultValue.Kind() == reflect.Ptr && resultValue.IsNil() {
-			return nil, fmt.Errorf("%s is marked non-nullable but returned a null value", funcCtx.funcType)
+// resolveListElementFunc calls a single function of a list returned by a lazy list field, or a
+// function returned for a single source by a batch field.
+func (funcCtx *funcContext) resolveListElementFunc(ctx context.Context, fun interface{}) (interface{}, error) {
+	callableFunc := reflect.ValueOf(fun)
+	if callableFunc.IsNil() {
+		return nil, nil
+	}
+
+	out := callableFunc.Call([]reflect.Value{})
+	if funcCtx.elementFuncHasErr {
+		if err := out[1]; !err.IsNil() {
+			return nil, err.Interface().(error)
 		}
 	}
 
-	return result, nil
+	return out[0].Interface(), nil
 }
@@ -4,12 +4,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
 )
 
 // argField is a representation of an input parameter field for a function.  It
@@ -77,18 +80,20 @@ func (sb *schemaBuilder) getEnumArgParser(typ reflect.Type) (*argParser, graphql
 	for mapping := range sb.enumMappings[typ].Map {
 		values = append(values, mapping)
 	}
+	name := sb.enumMappings[typ].name(typ)
+	// Values are strings both as literals, and in the variables of the request.
 	return &argParser{FromJSON: func(value interface{}, dest reflect.Value) error {
 		asString, ok := value.(string)
 		if !ok {
-			return errors.New("not a string")
+			return fmt.Errorf("not a string, expected a value of enum %s", name)
 		}
 		val, ok := sb.enumMappings[typ].Map[asString]
 		if !ok {
-			return fmt.Errorf("unknown enum value %v", asString)
+			return fmt.Errorf("unknown value %s of enum %s", asString, name)
 		}
 		dest.Set(reflect.ValueOf(val).Convert(dest.Type()))
 		return nil
-	}, Type: typ}, &graphql.Enum{Type: typ.Name(), Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap}
+	}, Type: typ}, &graphql.Enum{Type: name, Values: values, ReverseMap: sb.enumMappings[typ].ReverseMap}
 
 }
 
@@ -113,26 +118,48 @@ func wrapWithZeroValue(inner *argParser, fieldArgTyp graphql.Type) (*argParser,
 
 // getScalarArgParser creates an arg parser for a scalar type.
 func getScalarArgParser(typ reflect.Type) (*argParser, graphql.Type, bool) {
-	for match, argParser := range scalarArgParsers {
-		if typesIdenticalOrScalarAliases(match, typ) {
-			name, ok := getScalar(typ)
-			if !ok {
-				panic(typ)
+	argParser, ok := scalarArgParsers[typ]
+	if !ok {
+		for match, parser := range scalarArgParsers {
+			if typesIdenticalOrScalarAliases(match, typ) {
+				argParser, ok = parser, true
+				break
 			}
+		}
+	}
+	if !ok {
+		return nil, nil, false
+	}
 
-			if typ != argParser.Type {
-				// The scalar may be a type alias here,
-				// so we annotate the parser to output the
-				// alias instead of the underlying type.
-				newParser := *argParser
-				newParser.Type = typ
-				argParser = &newParser
-			}
+	name, ok := getScalar(typ)
+	if !ok {
+		panic(typ)
+	}
 
-			return argParser, &graphql.Scalar{Type: name}, true
-		}
+	if typ != argParser.Type {
+		// The scalar may be a type alias here,
+		// so we annotate the parser to output the
+		// alias instead of the underlying type.
+		newParser := *argParser
+		newParser.Type = typ
+		argParser = &newParser
+	}
+
+	return argParser, &graphql.Scalar{Type: name, Description: scalarDescriptions[name], SpecifiedByURL: scalarSpecifiedByURLs[name]}, true
+}
+
+// checkIntRange returns a BAD_USER_INPUT error if value lies outside of the range [min, max]
+// of the destination integer type, which would otherwise be silently truncated.
+func checkIntRange(value float64, min, max int64) error {
+	if value >= float64(min) && value <= float64(max) {
+		return nil
+	}
+
+	return &jerrors.Error{
+		Message:    fmt.Sprintf("%s is out of range, expected a value between %d and %d", strconv.FormatFloat(value, 'f', -1, 64), min, max),
+		Extensions: &jerrors.Extension{Code: jerrors.CodeBadUserInput},
+		Paths:      []string{},
 	}
-	return nil, nil, false
 }
 
 // scalarArgParsers are the static arg parsers that we can use for all scalar & static types.
@@ -179,6 +206,24 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 			return nil
 		},
 	},
+	reflect.TypeOf(int(0)): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			asFloat, ok := value.(float64)
+			if !ok {
+				if value == nil {
+					asFloat = 0
+				} else {
+					return errors.New("not a number")
+				}
+			}
+			// GraphQL Int is a signed 32-bit integer.
+			if err := checkIntRange(asFloat, math.MinInt32, math.MaxInt32); err != nil {
+				return err
+			}
+			dest.Set(reflect.ValueOf(int(asFloat)).Convert(dest.Type()))
+			return nil
+		},
+	},
 	reflect.TypeOf(int64(0)): {
 		FromJSON: func(value interface{}, dest reflect.Value) error {
 			asFloat, ok := value.(float64)
@@ -203,6 +248,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, math.MinInt32, math.MaxInt32); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(int32(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -217,6 +265,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, math.MinInt16, math.MaxInt16); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(int16(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -231,6 +282,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, math.MinInt8, math.MaxInt8); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(int8(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -259,6 +313,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, 0, math.MaxUint32); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(uint32(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -273,6 +330,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, 0, math.MaxUint16); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(uint16(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -287,6 +347,9 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 					return errors.New("not a number")
 				}
 			}
+			if err := checkIntRange(asFloat, 0, math.MaxUint8); err != nil {
+				return err
+			}
 			dest.Set(reflect.ValueOf(uint8(asFloat)).Convert(dest.Type()))
 			return nil
 		},
@@ -335,6 +398,60 @@ var scalarArgParsers = map[reflect.Type]*argParser{
 			return nil
 		},
 	},
+	reflect.TypeOf(JSON{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			dest.Field(0).Set(reflect.ValueOf(&value).Elem())
+			return nil
+		},
+	},
+	reflect.TypeOf(map[string]string{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			if value == nil {
+				return nil
+			}
+			asMap, ok := value.(map[string]interface{})
+			if !ok {
+				return errors.New("not an object")
+			}
+
+			m := make(map[string]string, len(asMap))
+			for key, value := range asMap {
+				asString, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("%s: not a string", key)
+				}
+				m[key] = asString
+			}
+			dest.Set(reflect.ValueOf(m))
+			return nil
+		},
+	},
+	reflect.TypeOf(map[string]int{}): {
+		FromJSON: func(value interface{}, dest reflect.Value) error {
+			if value == nil {
+				return nil
+			}
+			asMap, ok := value.(map[string]interface{})
+			if !ok {
+				return errors.New("not an object")
+			}
+
+			m := make(map[string]int, len(asMap))
+			for key, value := range asMap {
+				asFloat, ok := value.(float64)
+				if !ok {
+					return fmt.Errorf("%s: not a number", key)
+				}
+				// GraphQL Int is a signed 32-bit integer.
+				if err := checkIntRange(asFloat, math.MinInt32, math.MaxInt32); err != nil {
+					return err
+				}
+				m[key] = int(asFloat)
+			}
+			dest.Set(reflect.ValueOf(m))
+			return nil
+		},
+	},
 	reflect.TypeOf(Timestamp(timestamp.Timestamp{})): {
 		FromJSON: func(value interface{}, dest reflect.Value) error {
 			v, ok := value.(string)
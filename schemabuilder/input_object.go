@@ -33,7 +33,7 @@ func (sb *schemaBuilder) makeInputObjectParser(typ reflect.Type) (*argParser, gr
 				value := asMap[name]
 				fieldDest := dest.FieldByIndex(field.field.Index)
 				if err := field.parser.FromJSON(value, fieldDest); err != nil {
-					return fmt.Errorf("%s: %s", name, err)
+					return fmt.Errorf("%s: %w", name, err)
 				}
 			}
 
@@ -52,34 +52,56 @@ func (sb *schemaBuilder) makeInputObjectParser(typ reflect.Type) (*argParser, gr
 func (sb *schemaBuilder) generateArgParser(typ reflect.Type) (*graphql.InputObject, map[string]argField, error) {
 	fields := make(map[string]argField)
 	argType := &graphql.InputObject{
-		Name:        typ.Name(),
-		InputFields: make(map[string]graphql.Type),
+		Name:             typ.Name(),
+		InputFields:      make(map[string]graphql.Type),
+		DeprecatedFields: make(map[string]string),
 	}
 
 	// Cache type information ahead of time to catch self-reference
 	sb.typeCache[typ] = cachedType{argType, fields}
 
+	if err := sb.addArgFields(typ, typ, nil, argType, fields); err != nil {
+		return nil, nil, err
+	}
+	return argType, fields, nil
+}
+
+// addArgFields adds the fields of the struct typ, found at index in the args struct argsTyp, to
+// argType and fields. The fields of embedded structs, such as Pagination, are added as args.
+func (sb *schemaBuilder) addArgFields(argsTyp, typ reflect.Type, index []int, argType *graphql.InputObject, fields map[string]argField) error {
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+		field.Index = append(append([]int(nil), index...), field.Index...)
 		if field.Anonymous {
-			return nil, nil, fmt.Errorf("bad arg type %s: anonymous fields not supported", typ)
+			if field.Type.Kind() != reflect.Struct {
+				return fmt.Errorf("bad arg type %s: anonymous fields must be structs", argsTyp)
+			}
+			if err := sb.addArgFields(argsTyp, field.Type, field.Index, argType, fields); err != nil {
+				return err
+			}
+			continue
 		}
 
-		fieldInfo, err := parseGraphQLFieldInfo(field)
+		fieldInfo, err := parseGraphQLFieldInfo(field, sb.fieldNameMapper)
 		if err != nil {
-			return nil, nil, fmt.Errorf("bad type %s: %s", typ, err.Error())
+			return fmt.Errorf("bad type %s: %s", argsTyp, err.Error())
 		}
 		if fieldInfo.Skipped {
 			continue
 		}
 
 		if _, ok := fields[fieldInfo.Name]; ok {
-			return nil, nil, fmt.Errorf("bad arg type %s: duplicate field %s", typ, fieldInfo.Name)
+			return fmt.Errorf("bad arg type %s: duplicate field %s", argsTyp, fieldInfo.Name)
 		}
 
 		parser, fieldArgTyp, err := sb.generateObjectParser(field.Type)
 		if err != nil {
-			return nil, nil, err
+			return err
+		}
+
+		// Args of types which cannot be nil must be provided, unless marked optional.
+		if !isNillable(field.Type) && !fieldInfo.OptionalInputField {
+			fieldArgTyp = &graphql.NonNull{Type: fieldArgTyp}
 		}
 
 		fields[fieldInfo.Name] = argField{
@@ -87,9 +109,12 @@ func (sb *schemaBuilder) generateArgParser(typ reflect.Type) (*graphql.InputObje
 			parser: parser,
 		}
 		argType.InputFields[fieldInfo.Name] = fieldArgTyp
+		if fieldInfo.Deprecated {
+			argType.DeprecatedFields[fieldInfo.Name] = fieldInfo.DeprecationReason
+		}
 	}
 
-	return argType, fields, nil
+	return nil
 }
 
 // generateObjectParser generates the parser the object in args struct
@@ -146,6 +171,11 @@ func (sb *schemaBuilder) generateObjectParserInner(typ reflect.Type) (*argParser
 			return nil, nil, err
 		}
 
+		// Fields of types which cannot be nil must be provided.
+		if !isNillable(sourceTyp) {
+			fieldArgTyp = &graphql.NonNull{Type: fieldArgTyp}
+		}
+
 		fields[name] = argField{
 			field:  field,
 			parser: parser,
@@ -163,6 +193,12 @@ func (sb *schemaBuilder) generateObjectParserInner(typ reflect.Type) (*argParser
 			target := reflect.New(typ)
 			for name, field := range fields {
 				value, exists := asMap[name]
+				if fieldTyp, ok := argType.InputFields[name].(*graphql.NonNull); ok && value == nil {
+					if !exists {
+						return fmt.Errorf("field %q of type %s is required", name, fieldTyp)
+					}
+					return fmt.Errorf("field %q of type %s must not be null", name, fieldTyp)
+				}
 				if !exists {
 					continue
 				}
@@ -172,7 +208,12 @@ func (sb *schemaBuilder) generateObjectParserInner(typ reflect.Type) (*argParser
 				source := reflect.New(sourceTyp).Elem()
 
 				if err := field.parser.FromJSON(value, source); err != nil {
-					return fmt.Errorf("%s : %s", name, err)
+					return fmt.Errorf("%s : %w", name, err)
+				}
+				if validate := obj.validators[name]; validate != nil {
+					if err := validate(source.Interface()); err != nil {
+						return fmt.Errorf("%s : %w", name, err)
+					}
 				}
 
 				output := reflect.ValueOf(function).Call([]reflect.Value{target, source})
@@ -226,7 +267,9 @@ func (sb *schemaBuilder) getInputFieldParser(typ reflect.Type) (*argParser, grap
 	}
 }
 
-// generateSliceParser generates the parser for a slice input by generating the parser for underlying object and using it to fill the values in list
+// generateSliceParser generates the parser for a slice input by generating the parser for underlying object and using it to fill the values in list.
+// A null value leaves the slice nil, and a value which is not a list is coerced into a list of one element, as per
+// the spec. The items of a list of lists must themselves be lists, unless the list was coerced from a single value.
 func (sb *schemaBuilder) generateSliceParser(typ reflect.Type) (*argParser, graphql.Type, error) {
 	inner, argType, err := sb.generateObjectParser(typ.Elem())
 	if err != nil {
@@ -235,9 +278,20 @@ func (sb *schemaBuilder) generateSliceParser(typ reflect.Type) (*argParser, grap
 
 	return &argParser{
 		FromJSON: func(value interface{}, dest reflect.Value) error {
+			if value == nil {
+				// optional value
+				return nil
+			}
+
 			asSlice, ok := value.([]interface{})
 			if !ok {
-				return errors.New("not a list")
+				asSlice = []interface{}{value}
+			} else if isListType(typ.Elem()) {
+				for _, item := range asSlice {
+					if _, ok := item.([]interface{}); !ok && item != nil {
+						return errors.New("not a list")
+					}
+				}
 			}
 
 			sourceTyp := typ.Elem()
@@ -258,3 +312,22 @@ func (sb *schemaBuilder) generateSliceParser(typ reflect.Type) (*argParser, grap
 		Type: typ,
 	}, &graphql.List{Type: argType}, nil
 }
+
+// isListType reports whether typ, or the type it points to, is parsed as a list.
+func isListType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Slice && !isScalarType(typ)
+}
+
+// isNillable reports whether the zero value of typ is nil, which makes input fields of that type
+// optional.
+func isNillable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,232 @@
+package schemabuilder
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Merge combines schemas built separately, such as one schema per package, into a new schema
+// which can be built and served from a single endpoint. The fields of the Query, Mutation and
+// Subscription objects are unioned, and a field registered by more than one schema is an error
+// naming the schemas by their index. Types registered on several schemas, such as a shared User
+// object, are deduplicated: they must be registered with the same Go type, and the fields they
+// have in common must be resolved by the same function, and unions registered on several schemas
+// must have the same members. The schemas are not modified.
+func Merge(schemas ...*Schema) (*Schema, error) {
+	m := &merger{
+		schema:  NewSchema(),
+		sources: make(map[string]int),
+	}
+	m.schema.enumTypes = make(map[reflect.Type]*EnumMapping)
+
+	for i, s := range schemas {
+		if m.schema.fieldNameMapper == nil {
+			m.schema.fieldNameMapper = s.fieldNameMapper
+		}
+		for _, object := range s.objects {
+			if err := m.mergeObject(object, i); err != nil {
+				return nil, err
+			}
+		}
+		for name, inputObject := range s.inputObjects {
+			if err := m.mergeInputObject(name, inputObject, i); err != nil {
+				return nil, err
+			}
+		}
+		for typ, mapping := range s.enumTypes {
+			if err := m.mergeEnum(typ, mapping, i); err != nil {
+				return nil, err
+			}
+		}
+		for name, directive := range s.directives {
+			if existing, ok := m.schema.directives[name]; ok {
+				if existing != directive && !sameFunc(existing.Handler, directive.Handler) {
+					return nil, fmt.Errorf("directive @%s of schema %d is already registered by schema %d", name, i, m.sources["directive "+name])
+				}
+				continue
+			}
+			m.schema.directives[name] = directive
+			m.sources["directive "+name] = i
+		}
+		for name, members := range s.unions {
+			if existing, ok := m.schema.unions[name]; ok {
+				if !reflect.DeepEqual(existing, members) {
+					return nil, fmt.Errorf("union %s of schema %d conflicts with the union registered by schema %d", name, i, m.sources["type "+name])
+				}
+				continue
+			}
+			m.schema.unions[name] = members
+			m.sources["type "+name] = i
+		}
+		for name, resolver := range s.referenceResolvers {
+			if existing, ok := m.schema.referenceResolvers[name]; ok {
+				if !sameFunc(existing, resolver) {
+					return nil, fmt.Errorf("reference resolver of %s of schema %d is already registered by schema %d", name, i, m.sources["resolver "+name])
+				}
+				continue
+			}
+			m.schema.RegisterReferenceResolver(name, resolver)
+			m.sources["resolver "+name] = i
+		}
+	}
+
+	return m.schema, nil
+}
+
+// merger holds the schema being merged, along with the index of the schema which first
+// registered every type, field, directive and reference resolver, to name it in errors.
+type merger struct {
+	schema *Schema
+
+	// sources are the schemas registering definitions, by kind and name, such as "type User"
+	// or "field User.name".
+	sources map[string]int
+}
+
+// mergeObject adds the fields of object, registered on the i-th schema, to the object of the
+// same name.
+func (m *merger) mergeObject(object *Object, i int) error {
+	existing, ok := m.schema.objects[object.Name]
+	if !ok {
+		existing = &Object{
+			Name:        object.Name,
+			Description: object.Description,
+			Type:        object.Type,
+			Methods:     make(Methods, len(object.Methods)),
+			key:         object.key,
+			typename:    object.typename,
+			duplicates:  object.duplicates,
+		}
+		m.schema.objects[object.Name] = existing
+		m.sources["type "+object.Name] = i
+	} else {
+		if reflect.TypeOf(existing.Type) != reflect.TypeOf(object.Type) {
+			return fmt.Errorf("object %s of schema %d has type %s, but schema %d registers it with type %s",
+				object.Name, i, reflect.TypeOf(object.Type), m.sources["type "+object.Name], reflect.TypeOf(existing.Type))
+		}
+		if existing.key != "" && object.key != "" && existing.key != object.key {
+			return fmt.Errorf("object %s of schema %d has key %s, but schema %d registers it with key %s",
+				object.Name, i, object.key, m.sources["type "+object.Name], existing.key)
+		}
+		if existing.Description == "" {
+			existing.Description = object.Description
+		}
+		if existing.key == "" {
+			existing.key = object.key
+		}
+		if existing.typename == nil {
+			existing.typename = object.typename
+		}
+		existing.duplicates = append(existing.duplicates, object.duplicates...)
+	}
+
+	for _, name := range object.implements {
+		if !containsString(existing.implements, name) {
+			existing.implements = append(existing.implements, name)
+		}
+	}
+
+	for name, method := range object.Methods {
+		field := object.Name + "." + name
+		if other, ok := existing.Methods[name]; ok {
+			// The fields of the root objects belong to a single schema, while the fields of a
+			// shared object may be registered by every schema using it.
+			if isRootObject(object) || !sameFunc(other.Fn, method.Fn) {
+				return fmt.Errorf("field %s of schema %d is already registered by schema %d", field, i, m.sources["field "+field])
+			}
+			continue
+		}
+		existing.Methods[name] = method
+		m.sources["field "+field] = i
+	}
+
+	return nil
+}
+
+// mergeInputObject adds the fields of inputObject, registered with name on the i-th schema, to
+// the input object of the same name.
+func (m *merger) mergeInputObject(name string, inputObject *InputObject, i int) error {
+	existing, ok := m.schema.inputObjects[name]
+	if !ok {
+		existing = &InputObject{
+			Name:   inputObject.Name,
+			Type:   inputObject.Type,
+			Fields: make(map[string]interface{}, len(inputObject.Fields)),
+		}
+		existing.duplicates = inputObject.duplicates
+		m.schema.inputObjects[name] = existing
+		m.sources["type "+name] = i
+	} else if reflect.TypeOf(existing.Type) != reflect.TypeOf(inputObject.Type) {
+		return fmt.Errorf("input object %s of schema %d has type %s, but schema %d registers it with type %s",
+			name, i, reflect.TypeOf(inputObject.Type), m.sources["type "+name], reflect.TypeOf(existing.Type))
+	}
+
+	for fieldName, f := range inputObject.Fields {
+		field := name + "." + fieldName
+		if other, ok := existing.Fields[fieldName]; ok {
+			if !sameFunc(other, f) {
+				return fmt.Errorf("field %s of schema %d is already registered by schema %d", field, i, m.sources["field "+field])
+			}
+			continue
+		}
+		existing.Fields[fieldName] = f
+		if validate := inputObject.validators[fieldName]; validate != nil {
+			if existing.validators == nil {
+				existing.validators = make(map[string]func(value interface{}) error)
+			}
+			existing.validators[fieldName] = validate
+		}
+		m.sources["field "+field] = i
+	}
+
+	return nil
+}
+
+// mergeEnum adds the enum of type typ registered on the i-th schema, unless another schema
+// registers the same enum.
+func (m *merger) mergeEnum(typ reflect.Type, mapping *EnumMapping, i int) error {
+	name := mapping.name(typ)
+	if existing, ok := m.schema.enumTypes[typ]; ok {
+		if existing.name(typ) != name || !reflect.DeepEqual(existing.Map, mapping.Map) {
+			return fmt.Errorf("enum %s of schema %d conflicts with the enum registered by schema %d", name, i, m.sources["type "+existing.name(typ)])
+		}
+		return nil
+	}
+	for other, existing := range m.schema.enumTypes {
+		if existing.name(other) == name {
+			return fmt.Errorf("enum %s of schema %d has type %s, but schema %d registers it with type %s", name, i, typ, m.sources["type "+name], other)
+		}
+	}
+
+	m.schema.enumTypes[typ] = mapping
+	m.sources["type "+name] = i
+	return nil
+}
+
+// isRootObject reports whether object is the Query, Mutation or Subscription object.
+func isRootObject(object *Object) bool {
+	switch object.Type.(type) {
+	case query, mutation, Subscription:
+		return true
+	default:
+		return false
+	}
+}
+
+// sameFunc reports whether the functions a and b run the same code.
+func sameFunc(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != reflect.Func || vb.Kind() != reflect.Func {
+		return false
+	}
+	return va.Pointer() == vb.Pointer()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 
 	"go.appointy.com/jaal/graphql"
 )
@@ -30,11 +31,15 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	var description string
 	var methods Methods
 	var objectKey string
+	var typename func(source interface{}) string
+	var implements []string
 	if object, ok := sb.objects[typ]; ok {
 		name = object.Name
 		description = object.Description
 		methods = object.Methods
 		objectKey = object.key
+		typename = object.typename
+		implements = object.implements
 	} else {
 		if typ.Name() != "query" && typ.Name() != "mutation" && typ.Name() != "Subscription" {
 			return fmt.Errorf("%s not registered as object", typ.Name())
@@ -53,6 +58,7 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		Description: description,
 		Fields:      make(map[string]*graphql.Field),
 		Interfaces:  make(map[string]*graphql.Interface),
+		Typename:    typename,
 	}
 	sb.types[typ] = object
 
@@ -96,14 +102,14 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 	for _, name := range names {
 		method := methods[name]
 
-		// if method.Batch {
-		// 	batchField, err := sb.buildBatchFunctionWithFallback(typ, method)
-		// 	if err != nil {
-		// 		return err
-		// 	}
-		// 	object.Fields[name] = batchField
-		// 	continue
-		// }
+		if method.Batch {
+			batchField, err := sb.buildBatchFunction(typ, method)
+			if err != nil {
+				return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
+			}
+			object.Fields[name] = batchField
+			continue
+		}
 
 		built, err := sb.buildFunction(typ, method)
 		if err != nil {
@@ -112,6 +118,10 @@ func (sb *schemaBuilder) buildStruct(typ reflect.Type) error {
 		object.Fields[name] = built
 	}
 
+	if err := sb.inheritInterfaceFields(typ, object, implements); err != nil {
+		return err
+	}
+
 	if objectKey != "" {
 		keyPtr, ok := object.Fields[objectKey]
 		if !ok {
@@ -187,6 +197,102 @@ func (sb *schemaBuilder) buildUnionStruct(typ reflect.Type) error {
 	return nil
 }
 
+// buildAbstract builds the type of the values of the Go interface typ: the union registered
+// with Schema.Union, or the interface struct registered with Schema.Object, whose members all
+// implement typ. Values of typ are resolved as the member of their dynamic type.
+func (sb *schemaBuilder) buildAbstract(typ reflect.Type) error {
+	if _, ok := sb.types[typ]; ok {
+		return nil
+	}
+
+	var names []string
+	var build func() (graphql.Type, error)
+	for name, members := range sb.unions {
+		if implementsAll(members, typ) {
+			name := name
+			names = append(names, name)
+			build = func() (graphql.Type, error) {
+				return sb.buildUnion(name)
+			}
+		}
+	}
+	for structTyp, object := range sb.objects {
+		if hasInterfaceMarkerEmbedded(structTyp) && implementsAll(interfaceMembers(structTyp), typ) {
+			structTyp := structTyp
+			names = append(names, object.Name)
+			build = func() (graphql.Type, error) {
+				if err := sb.buildStruct(structTyp); err != nil {
+					return nil, err
+				}
+				return sb.types[structTyp], nil
+			}
+		}
+	}
+	sort.Strings(names)
+	switch len(names) {
+	case 0:
+		return fmt.Errorf("bad type %s: should be a scalar, slice, or struct type, or an interface implemented by the members of a union or interface", typ)
+	case 1:
+	default:
+		return fmt.Errorf("bad type %s: implemented by the members of %s", typ, strings.Join(names, ", "))
+	}
+
+	built, err := build()
+	if err != nil {
+		return err
+	}
+	sb.types[typ] = built
+	return nil
+}
+
+// buildUnion builds the graphql.Union registered with Schema.Union as name. Values of the union
+// are resolved as the member of their dynamic type.
+func (sb *schemaBuilder) buildUnion(name string) (*graphql.Union, error) {
+	if union, ok := sb.unionTypes[name]; ok {
+		return union, nil
+	}
+
+	members := make(map[reflect.Type]*graphql.Object)
+	union := &graphql.Union{
+		Name:  name,
+		Types: make(map[string]*graphql.Object),
+		ResolveType: func(source interface{}) *graphql.Object {
+			return members[reflect.TypeOf(source)]
+		},
+	}
+	sb.unionTypes[name] = union
+
+	for _, member := range sb.unions[name] {
+		memberTyp, err := sb.getType(member)
+		if err != nil {
+			return nil, err
+		}
+
+		obj, ok := memberTyp.(*graphql.Object)
+		if !ok {
+			return nil, fmt.Errorf("bad type %s: union type member must be a pointer to a struct, received %s", name, memberTyp.String())
+		}
+
+		if union.Types[obj.Name] != nil {
+			return nil, fmt.Errorf("bad type %s: union type member may only appear once", name)
+		}
+
+		union.Types[obj.Name] = obj
+		members[member] = obj
+	}
+	return union, nil
+}
+
+// implementsAll reports whether all the types of members implement the Go interface typ.
+func implementsAll(members []reflect.Type, typ reflect.Type) bool {
+	for _, member := range members {
+		if !member.Implements(typ) {
+			return false
+		}
+	}
+	return true
+}
+
 // buildField generates a graphQL field for a struct's field.  This field can be used to "resolve" a response for a graphql request.
 func (sb *schemaBuilder) buildField(field reflect.StructField) (*graphql.Field, error) {
 	retType, err := sb.getType(field.Type)
@@ -234,6 +340,12 @@ func hasInterfaceMarkerEmbedded(typ reflect.Type) bool {
 func (sb *schemaBuilder) buildInterfaceStruct(typ reflect.Type) error {
 	var name string
 	var description string
+	var methods Methods
+	if object, ok := sb.objects[typ]; ok {
+		name = object.Name
+		description = object.Description
+		methods = object.Methods
+	}
 
 	if name == "" {
 		name = typ.Name()
@@ -242,11 +354,15 @@ func (sb *schemaBuilder) buildInterfaceStruct(typ reflect.Type) error {
 		}
 	}
 
+	members := make(map[reflect.Type]*graphql.Object)
 	interfaceType := &graphql.Interface{
 		Name:        name,
 		Description: description,
 		Types:       make(map[string]*graphql.Object),
 		Fields:      make(map[string]*graphql.Field),
+		ResolveType: func(source interface{}) *graphql.Object {
+			return members[reflect.TypeOf(source)]
+		},
 	}
 	sb.types[typ] = interfaceType
 	var fieldMap map[string]*graphql.Field
@@ -273,6 +389,7 @@ func (sb *schemaBuilder) buildInterfaceStruct(typ reflect.Type) error {
 		}
 
 		interfaceType.Types[obj.Name] = obj
+		members[field.Type] = obj
 
 		if fieldMap == nil {
 			fieldMap = make(map[string]*graphql.Field)
@@ -289,6 +406,22 @@ func (sb *schemaBuilder) buildInterfaceStruct(typ reflect.Type) error {
 		}
 
 	}
+
+	// Fields registered on the interface take precedence over the fields common to its member
+	// types, which must then all provide them.
+	if len(methods) > 0 {
+		fieldMap = make(map[string]*graphql.Field, len(methods))
+		for name, method := range methods {
+			if method.Batch {
+				return fmt.Errorf("bad method %s on type %s: interface fields cannot be batched", name, typ)
+			}
+			built, err := sb.buildFunction(typ, method)
+			if err != nil {
+				return fmt.Errorf("bad method %s on type %s: %s", name, typ, err)
+			}
+			fieldMap[name] = built
+		}
+	}
 	interfaceType.Fields = fieldMap
 
 	for _, typ := range interfaceType.Types {
@@ -297,3 +430,126 @@ func (sb *schemaBuilder) buildInterfaceStruct(typ reflect.Type) error {
 
 	return nil
 }
+
+// interfaceMembers returns the member types of the interface struct typ.
+func interfaceMembers(typ reflect.Type) []reflect.Type {
+	var members []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || !field.Anonymous || field.Type == reflect.TypeOf(Interface{}) {
+			continue
+		}
+		members = append(members, field.Type)
+	}
+	return members
+}
+
+// interfaceObject returns the struct type and the registered object of the interface named name.
+func (sb *schemaBuilder) interfaceObject(name string) (reflect.Type, *Object, bool) {
+	for typ, object := range sb.objects {
+		if object.Name == name && hasInterfaceMarkerEmbedded(typ) {
+			return typ, object, true
+		}
+	}
+	return nil, nil, false
+}
+
+// inheritInterfaceFields adds the fields of the interfaces implemented by the object of the
+// type typ that the object doesn't register itself. The inherited resolvers are called with the
+// interface struct holding the source.
+func (sb *schemaBuilder) inheritInterfaceFields(typ reflect.Type, object *graphql.Object, implements []string) error {
+	for _, interfaceName := range implements {
+		interfaceTyp, interfaceObject, ok := sb.interfaceObject(interfaceName)
+		if !ok {
+			return fmt.Errorf("bad type %s: implements %s, which is not a registered interface", typ, interfaceName)
+		}
+
+		index := -1
+		for i := 0; i < interfaceTyp.NumField(); i++ {
+			if field := interfaceTyp.Field(i); field.Anonymous && field.Type == reflect.PtrTo(typ) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("bad type %s: implements %s but is not one of its member types", typ, interfaceName)
+		}
+
+		var names []string
+		for name := range interfaceObject.Methods {
+			if _, ok := object.Fields[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			method := interfaceObject.Methods[name]
+			if method.Batch {
+				return fmt.Errorf("bad method %s on type %s: interface fields cannot be batched", name, interfaceTyp)
+			}
+
+			built, err := sb.buildFunction(interfaceTyp, method)
+			if err != nil {
+				return fmt.Errorf("bad method %s on type %s: %s", name, interfaceTyp, err)
+			}
+
+			resolve := built.Resolve
+			built.Resolve = func(ctx context.Context, source, args interface{}, selectionSet *graphql.SelectionSet) (interface{}, error) {
+				value := reflect.ValueOf(source)
+				if value.Kind() != reflect.Ptr {
+					ptr := reflect.New(value.Type())
+					ptr.Elem().Set(value)
+					value = ptr
+				}
+
+				wrapped := reflect.New(interfaceTyp)
+				wrapped.Elem().Field(index).Set(value)
+				return resolve(ctx, wrapped.Interface(), args, selectionSet)
+			}
+			object.Fields[name] = built
+		}
+	}
+	return nil
+}
+
+// validateInterfaces checks that the member types of the interfaces with registered fields
+// provide all of them, either registering or inheriting them. It runs once all the types are
+// built, as member types may still be under construction when their interface is built.
+func (sb *schemaBuilder) validateInterfaces() error {
+	var interfaces []*graphql.Interface
+	for typ, built := range sb.types {
+		iface, ok := built.(*graphql.Interface)
+		if object := sb.objects[typ]; ok && object != nil && len(object.Methods) > 0 {
+			interfaces = append(interfaces, iface)
+		}
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+
+	for _, iface := range interfaces {
+		var names []string
+		for name := range iface.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var types []string
+		for name := range iface.Types {
+			types = append(types, name)
+		}
+		sort.Strings(types)
+
+		for _, typeName := range types {
+			obj := iface.Types[typeName]
+			for _, name := range names {
+				field, ok := obj.Fields[name]
+				if !ok {
+					return fmt.Errorf("bad type %s: missing field %s of interface %s", obj.Name, name, iface.Name)
+				}
+				if want := iface.Fields[name].Type.String(); field.Type.String() != want {
+					return fmt.Errorf("bad type %s: field %s is of type %s, but of type %s on interface %s", obj.Name, name, field.Type, want, iface.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -24,10 +24,17 @@ type graphQLFieldInfo struct {
 	// OptionalInputField indicates that this field should be treated as an optional
 	// field on graphQL input args.
 	OptionalInputField bool
+
+	// Deprecated indicates that this field is marked deprecated with the tag
+	// `graphql:",deprecated=reason"`, with DeprecationReason as the reason.
+	Deprecated        bool
+	DeprecationReason string
 }
 
 // parseGraphQLFieldInfo parses a struct field and returns a struct with the parsed information about the field (tag info, name, etc).
-func parseGraphQLFieldInfo(field reflect.StructField) (*graphQLFieldInfo, error) {
+// The name of the field is the name given by its graphql tag, e.g. `graphql:"id"`, if any, or its Go name converted
+// by mapper, which defaults to makeGraphql. Unexported fields, and fields tagged `json:"-"` or `graphql:"-"`, are skipped.
+func parseGraphQLFieldInfo(field reflect.StructField, mapper func(string) string) (*graphQLFieldInfo, error) {
 	if field.PkgPath != "" { //If the field of struct is not exported, then it is not exposed
 		return &graphQLFieldInfo{Skipped: true}, nil
 	}
@@ -37,16 +44,36 @@ func parseGraphQLFieldInfo(field reflect.StructField) (*graphQLFieldInfo, error)
 	if len(tags) > 0 {
 		name = tags[0]
 	}
-	if name == "-" {
+	graphqlTags := strings.Split(field.Tag.Get("graphql"), ",")
+	if name == "-" || graphqlTags[0] == "-" {
 		return &graphQLFieldInfo{Skipped: true}, nil
 	}
 
-	name = makeGraphql(field.Name)
+	if mapper == nil {
+		mapper = makeGraphql
+	}
+	if graphqlTags[0] != "" {
+		name = graphqlTags[0]
+	} else {
+		name = mapper(field.Name)
+	}
 
 	var key bool
 	var optional bool
 
-	return &graphQLFieldInfo{Name: name, KeyField: key, OptionalInputField: optional}, nil
+	var deprecated bool
+	var reason string
+	for _, option := range graphqlTags[1:] {
+		if option == "optional" {
+			optional = true
+		}
+		if option == "deprecated" || strings.HasPrefix(option, "deprecated=") {
+			deprecated = true
+			reason = strings.TrimPrefix(strings.TrimPrefix(option, "deprecated"), "=")
+		}
+	}
+
+	return &graphQLFieldInfo{Name: name, KeyField: key, OptionalInputField: optional, Deprecated: deprecated, DeprecationReason: reason}, nil
 }
 
 // makeGraphql converts a field name "MyField" into a graphQL field name "myField".
@@ -62,7 +89,67 @@ func makeGraphql(s string) string {
 	return b.String()
 }
 
+// CamelCase converts the Go name of a field into a camel case GraphQL name, lowering the case of
+// acronyms past their first letter, e.g. "UserID" into "userId", and "HTTPURL" into "httpurl".
+// It can be used with Schema.WithFieldNameMapper.
+func CamelCase(s string) string {
+	var b strings.Builder
+	for i, word := range splitWords(s) {
+		word = strings.ToLower(word)
+		if i > 0 {
+			r := []rune(word)
+			r[0] = unicode.ToUpper(r[0])
+			word = string(r)
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+// SnakeCase converts the Go name of a field into a snake case GraphQL name, e.g. "UserID" into
+// "user_id", and "HTTPURLParser" into "httpurl_parser". It can be used with
+// Schema.WithFieldNameMapper.
+func SnakeCase(s string) string {
+	words := splitWords(s)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
+
+// splitWords splits a Go name into its words. A word starts at an upper case letter following
+// a lower case letter or a digit, and at the last upper case letter of an acronym followed by a
+// lower case letter, e.g. "HTTPServerID" is split into "HTTP", "Server" and "ID".
+func splitWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		switch {
+		case cur == '_':
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+		case unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			words = append(words, string(runes[start:i]))
+			start = i
+		case unicode.IsUpper(prev) && unicode.IsLower(cur) && i-1 > start:
+			words = append(words, string(runes[start:i-1]))
+			start = i - 1
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
 // Common Types that we will need to perform type assertions against.
 var errType = reflect.TypeOf((*error)(nil)).Elem()
 var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
 var selectionSetType = reflect.TypeOf(&graphql.SelectionSet{})
+var argsMapType = reflect.TypeOf(map[string]interface{}{})
+var subscriptionType = reflect.TypeOf(Subscription{})
+var boolType = reflect.TypeOf(true)
@@ -3,6 +3,7 @@ package schemabuilder
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"go.appointy.com/jaal/graphql"
 )
@@ -14,6 +15,14 @@ type Schema struct {
 	objects      map[string]*Object
 	enumTypes    map[reflect.Type]*EnumMapping
 	inputObjects map[string]*InputObject
+	directives   map[string]*Directive
+
+	// unions are the Go types of the members of the unions registered with Union, by name.
+	unions map[string][]reflect.Type
+
+	referenceResolvers map[string]ReferenceResolver
+
+	fieldNameMapper func(string) string
 }
 
 // NewSchema creates a new schema.
@@ -21,11 +30,21 @@ func NewSchema() *Schema {
 	schema := &Schema{
 		objects:      make(map[string]*Object),
 		inputObjects: make(map[string]*InputObject),
+		directives:   make(map[string]*Directive),
+		unions:       make(map[string][]reflect.Type),
 	}
 
 	return schema
 }
 
+// WithFieldNameMapper sets how the Go names of the fields of args structs and input objects are
+// converted into GraphQL names, e.g. CamelCase or SnakeCase. By default, only the first letter
+// of a name is lowered, so that "UserID" becomes "userID". A name given by the graphql tag of a
+// field, e.g. `graphql:"userId"`, is kept as is.
+func (s *Schema) WithFieldNameMapper(mapper func(string) string) {
+	s.fieldNameMapper = mapper
+}
+
 // Enum registers an enumType in the schema. The val should be any arbitrary value
 // of the enumType to be used for reflection, and the enumMap should be
 // the corresponding map of the enums.
@@ -44,14 +63,86 @@ func NewSchema() *Schema {
 //     "two":   enumType(2),
 //     "three": enumType(3),
 //   })
-func (s *Schema) Enum(val interface{}, enumMap interface{}) {
+//
+// The GraphQL name of the enum defaults to the name of the Go type, and can be overridden with WithName:
+//   s.Enum(enumType(1), enumMap, schemabuilder.WithName("Numbers"))
+func (s *Schema) Enum(val interface{}, enumMap interface{}, opts ...EnumOption) {
 	typ := reflect.TypeOf(val)
 	if s.enumTypes == nil {
 		s.enumTypes = make(map[reflect.Type]*EnumMapping)
 	}
 
 	eMap, rMap := getEnumMap(enumMap, typ)
-	s.enumTypes[typ] = &EnumMapping{Map: eMap, ReverseMap: rMap}
+	mapping := &EnumMapping{Map: eMap, ReverseMap: rMap}
+	for _, opt := range opts {
+		opt(mapping)
+	}
+
+	// A value with several names could not be serialized.
+	names := make([]string, 0, len(eMap))
+	for name := range eMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	named := make(map[interface{}]bool, len(names))
+	for _, name := range names {
+		if named[eMap[name]] {
+			mapping.duplicates = append(mapping.duplicates, name)
+		}
+		named[eMap[name]] = true
+	}
+
+	s.enumTypes[typ] = mapping
+}
+
+// EnumAuto registers an integer enumType in the schema like Enum, mapping every name of names to
+// the value of its index, as the constants of the enum are declared with iota. The val should be
+// any arbitrary value of the enumType to be used for reflection.
+//
+// For example the enum
+//   type enumType int32
+//   const (
+//	  one enumType = iota
+//	  two
+//	  three
+//   )
+//
+// can be registered as:
+//   s.EnumAuto(enumType(0), []string{"one", "two", "three"})
+func (s *Schema) EnumAuto(val interface{}, names []string, opts ...EnumOption) {
+	if len(names) == 0 {
+		panic("enum auto has no names")
+	}
+
+	typ := reflect.TypeOf(val)
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.OverflowInt(int64(len(names) - 1)) {
+			panic(fmt.Sprintf("enum %s cannot hold %d values", typ, len(names)))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.OverflowUint(uint64(len(names) - 1)) {
+			panic(fmt.Sprintf("enum %s cannot hold %d values", typ, len(names)))
+		}
+	default:
+		panic("enum auto type is not an integer")
+	}
+
+	enumMap := make(map[string]interface{}, len(names))
+	var duplicates []string
+	for i, name := range names {
+		if name == "" {
+			panic("enum auto name is empty")
+		}
+		if _, ok := enumMap[name]; ok {
+			duplicates = append(duplicates, name)
+			continue
+		}
+		enumMap[name] = reflect.ValueOf(i).Convert(typ).Interface()
+	}
+	s.Enum(val, enumMap, opts...)
+	s.enumTypes[typ].duplicates = append(s.enumTypes[typ].duplicates, duplicates...)
 }
 
 func getEnumMap(enumMap interface{}, typ reflect.Type) (map[string]interface{}, map[interface{}]string) {
@@ -88,18 +179,24 @@ func getEnumMap(enumMap interface{}, typ reflect.Type) (map[string]interface{},
 // We'll read the fields of the struct to determine it's basic "Fields" and
 // we'll return an Object struct that we can use to register custom
 // relationships and fields on the object.
-func (s *Schema) Object(name string, typ interface{}) *Object {
+func (s *Schema) Object(name string, typ interface{}, opts ...ObjectOption) *Object {
 	if object, ok := s.objects[name]; ok {
 		if reflect.TypeOf(object.Type) != reflect.TypeOf(typ) {
 			var t = reflect.TypeOf(object.Type)
 			panic("re-registered object with different type, already registered type :" + fmt.Sprintf(" %s.%s", t.PkgPath(), t.Name()))
 		}
+		for _, opt := range opts {
+			opt(object)
+		}
 		return object
 	}
 	object := &Object{
 		Name: name,
 		Type: typ,
 	}
+	for _, opt := range opts {
+		opt(object)
+	}
 	s.objects[name] = object
 	return object
 }
@@ -133,6 +230,92 @@ func (s *Schema) InputObject(name string, typ interface{}) *InputObject {
 	return inputObject
 }
 
+// Directive registers a custom directive, which can be used at the given locations of a query.
+// The handler is called for every field the directive is applied to, and can transform the
+// value of the field or short-circuit its execution. For example:
+//   s.Directive("upper", []introspection.DirectiveLocation{introspection.FIELD},
+//     func(ctx context.Context, args interface{}, next func(context.Context) (interface{}, error)) (interface{}, error) {
+//       value, err := next(ctx)
+//       if s, ok := value.(string); ok {
+//         return strings.ToUpper(s), err
+//       }
+//       return value, err
+//     })
+func (s *Schema) Directive(name string, locations []graphql.DirectiveLocation, handler DirectiveHandler, opts ...DirectiveOption) {
+	directive := &Directive{
+		Name:      name,
+		Locations: locations,
+		Handler:   handler,
+	}
+	for _, opt := range opts {
+		opt(directive)
+	}
+	if s.directives == nil {
+		s.directives = make(map[string]*Directive)
+	}
+	s.directives[name] = directive
+}
+
+// Union registers a union of the objects of the Go types of members, such as &User{} and
+// &Droid{}. Fields whose resolvers return a Go interface implemented by all the members are of
+// the union type, and every value returned is resolved as the member of its dynamic type, so no
+// one-hot struct embedding the Union marker is needed. For example:
+//   type SearchResult interface{ isSearchResult() }
+//   s.Union("SearchResult", &User{}, &Droid{})
+//   s.Query().FieldFunc("search", func(args struct{ Text string }) []SearchResult { ... })
+func (s *Schema) Union(name string, members ...interface{}) {
+	if len(members) == 0 {
+		panic("union " + name + " has no members")
+	}
+	types := make([]reflect.Type, 0, len(members))
+	for _, member := range members {
+		types = append(types, reflect.TypeOf(member))
+	}
+	if s.unions == nil {
+		s.unions = make(map[string][]reflect.Type)
+	}
+	s.unions[name] = types
+}
+
+// Types lists the names of the types registered on a Schema, by category.
+type Types struct {
+	Objects      []string
+	InputObjects []string
+	Enums        []string
+	Scalars      []string
+}
+
+// Types returns the sorted names of the objects, input objects and enums registered on the
+// schema, and of the scalars available to it, without building the schema.
+func (s *Schema) Types() Types {
+	var types Types
+
+	for name := range s.objects {
+		types.Objects = append(types.Objects, name)
+	}
+	for name := range s.inputObjects {
+		types.InputObjects = append(types.InputObjects, name)
+	}
+	for typ, mapping := range s.enumTypes {
+		types.Enums = append(types.Enums, mapping.name(typ))
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range scalars {
+		if !seen[name] {
+			seen[name] = true
+			types.Scalars = append(types.Scalars, name)
+		}
+	}
+
+	sort.Strings(types.Objects)
+	sort.Strings(types.InputObjects)
+	sort.Strings(types.Enums)
+	sort.Strings(types.Scalars)
+
+	return types
+}
+
 type query struct{}
 
 // Query returns an Object struct that we can use to register all the top level
@@ -155,6 +338,19 @@ type Subscription struct {
 
 // Subscription returns an Object struct that we can use to register all the top level
 // graphql subscription functions we'd like to expose.
+//
+// A subscription resolver returns the source stream of the subscription, either as a function,
+// e.g. func() T, which is polled to produce every event, or as a channel, e.g. <-chan T, every
+// value received from which is an event. For example:
+//   subscription.FieldFunc("messages", func(ctx context.Context, args struct{ Room string }) (<-chan *Message, error) {
+//     return broker.Subscribe(ctx, args.Room)
+//   })
+//
+// A value is received from the channel only once the response to the previous one has been
+// delivered, so a slow client holds back the sender rather than buffering events. The
+// subscription completes normally when the resolver closes the channel. The context passed to
+// the resolver is cancelled when the client unsubscribes, after which the channel is no
+// longer received from.
 func (s *Schema) Subscription() *Object {
 	return s.Object("Subscription", Subscription{})
 }
@@ -169,6 +365,10 @@ func (s *Schema) Build() (*graphql.Schema, error) {
 		enumMappings: s.enumTypes,
 		typeCache:    make(map[reflect.Type]cachedType, 0),
 		inputObjects: make(map[reflect.Type]*InputObject, 0),
+		unions:       s.unions,
+		unionTypes:   make(map[string]*graphql.Union),
+
+		fieldNameMapper: s.fieldNameMapper,
 	}
 
 	for _, object := range s.objects {
@@ -197,6 +397,10 @@ func (s *Schema) Build() (*graphql.Schema, error) {
 		sb.inputObjects[typ] = inputObject
 	}
 
+	if err := sb.validateNames(); err != nil {
+		return nil, err
+	}
+
 	queryTyp, err := sb.getType(reflect.TypeOf(&query{}))
 	if err != nil {
 		return nil, err
@@ -209,11 +413,129 @@ func (s *Schema) Build() (*graphql.Schema, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &graphql.Schema{
+	schema := &graphql.Schema{
 		Query:        queryTyp,
 		Mutation:     mutationTyp,
 		Subscription: subscriptionTyp,
-	}, nil
+	}
+	if len(s.referenceResolvers) > 0 {
+		if err := sb.buildFederation(schema, s.referenceResolvers); err != nil {
+			return nil, err
+		}
+	}
+	if err := sb.validateInterfaces(); err != nil {
+		return nil, err
+	}
+	if schema.Directives, err = sb.buildDirectives(s.directives); err != nil {
+		return nil, err
+	}
+	if err := bindFieldDirectives(schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// bindFieldDirectives parses the args of the directives applied to the fields of schema with
+// WithDirective, and sets their handlers.
+func bindFieldDirectives(schema *graphql.Schema) error {
+	types := make(map[string]graphql.Type)
+	graphql.CollectTypes(schema.Query, types)
+	graphql.CollectTypes(schema.Mutation, types)
+	graphql.CollectTypes(schema.Subscription, types)
+
+	for name, typ := range types {
+		var fields map[string]*graphql.Field
+		switch typ := typ.(type) {
+		case *graphql.Object:
+			fields = typ.Fields
+		case *graphql.Interface:
+			fields = typ.Fields
+		}
+
+		for fieldName, field := range fields {
+			for _, directive := range field.Directives {
+				// Fields may be shared by an interface and its member types.
+				if directive.Handler != nil {
+					continue
+				}
+
+				definition, ok := schema.Directives[directive.Name]
+				if !ok {
+					return fmt.Errorf("field %s.%s: directive @%s is not registered", name, fieldName, directive.Name)
+				}
+				if !containsLocation(definition.Locations, "FIELD_DEFINITION") {
+					return fmt.Errorf("field %s.%s: directive @%s may not be used on FIELD_DEFINITION", name, fieldName, directive.Name)
+				}
+
+				args := directive.Args
+				if args == nil {
+					args = map[string]interface{}{}
+				}
+				parsed, err := definition.ParseArguments(args)
+				if err != nil {
+					return fmt.Errorf("field %s.%s: error parsing args for @%s: %s", name, fieldName, directive.Name, err)
+				}
+				directive.Args = parsed
+				directive.Handler = definition.Handler
+			}
+		}
+	}
+	return nil
+}
+
+func containsLocation(locations []graphql.DirectiveLocation, location graphql.DirectiveLocation) bool {
+	for _, l := range locations {
+		if l == location {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDirectives builds the custom directives registered on the schema, parsing their args
+// as the args struct of a field would be.
+func (sb *schemaBuilder) buildDirectives(directives map[string]*Directive) (map[string]*graphql.DirectiveDefinition, error) {
+	if len(directives) == 0 {
+		return nil, nil
+	}
+
+	built := make(map[string]*graphql.DirectiveDefinition, len(directives))
+	for name, directive := range directives {
+		if _, ok := graphql.BuiltinDirectives[name]; ok {
+			return nil, fmt.Errorf("directive @%s is built in and cannot be redefined", name)
+		}
+		if directive.Handler == nil {
+			return nil, fmt.Errorf("directive @%s must have a handler", name)
+		}
+
+		definition := &graphql.DirectiveDefinition{
+			Name:           name,
+			Description:    directive.Description,
+			Locations:      directive.Locations,
+			Args:           make(map[string]graphql.Type),
+			Repeatable:     directive.Repeatable,
+			ParseArguments: nilParseArguments,
+			Handler:        graphql.DirectiveHandler(directive.Handler),
+		}
+
+		if directive.Args != nil {
+			parser, argType, err := sb.makeInputObjectParser(reflect.TypeOf(directive.Args))
+			if err != nil {
+				return nil, fmt.Errorf("attempted to parse args of directive @%s, but failed: %s", name, err.Error())
+			}
+			inputObject, ok := argType.(*graphql.InputObject)
+			if !ok {
+				return nil, fmt.Errorf("args of directive @%s should be an object", name)
+			}
+			for field, typ := range inputObject.InputFields {
+				definition.Args[field] = typ
+			}
+			definition.ParseArguments = parser.Parse
+		}
+
+		built[name] = definition
+	}
+	return built, nil
 }
 
 //MustBuild builds a schema and panics if an error occurs.
@@ -231,6 +553,8 @@ func (s *Schema) Clone() *Schema {
 		objects:      make(map[string]*Object, len(s.objects)),
 		inputObjects: make(map[string]*InputObject, len(s.inputObjects)),
 		enumTypes:    make(map[reflect.Type]*EnumMapping, len(s.enumTypes)),
+		directives:   make(map[string]*Directive, len(s.directives)),
+		unions:       make(map[string][]reflect.Type, len(s.unions)),
 	}
 
 	for key, value := range s.objects {
@@ -245,6 +569,16 @@ func (s *Schema) Clone() *Schema {
 		copy.enumTypes[key] = copyEnumMappings(value)
 	}
 
+	for key, value := range s.directives {
+		directive := *value
+		directive.Locations = append([]graphql.DirectiveLocation(nil), value.Locations...)
+		copy.directives[key] = &directive
+	}
+
+	for key, value := range s.unions {
+		copy.unions[key] = append([]reflect.Type(nil), value...)
+	}
+
 	return &copy
 }
 
@@ -254,12 +588,19 @@ func copyObject(object *Object) *Object {
 		Description: object.Description,
 		Type:        object.Type,
 		Methods:     make(Methods, len(object.Methods)),
+		typename:    object.typename,
+		implements:  append([]string(nil), object.implements...),
 	}
 
 	for name, m := range object.Methods {
 		copy.Methods[name] = &method{
 			MarkedNonNullable: m.MarkedNonNullable,
 			Fn:                m.Fn,
+			Deprecated:        m.Deprecated,
+			DeprecationReason: m.DeprecationReason,
+			ListNullPolicy:    m.ListNullPolicy,
+			Args:              m.Args,
+			Batch:             m.Batch,
 		}
 	}
 
@@ -276,12 +617,19 @@ func copyInputObject(input *InputObject) *InputObject {
 	for name, field := range input.Fields {
 		copy.Fields[name] = field
 	}
+	for name, validate := range input.validators {
+		if copy.validators == nil {
+			copy.validators = make(map[string]func(value interface{}) error, len(input.validators))
+		}
+		copy.validators[name] = validate
+	}
 
 	return copy
 }
 
 func copyEnumMappings(mapping *EnumMapping) *EnumMapping {
 	enum := &EnumMapping{
+		Name:       mapping.Name,
 		Map:        make(map[string]interface{}, len(mapping.Map)),
 		ReverseMap: make(map[interface{}]string, len(mapping.ReverseMap)),
 	}
@@ -0,0 +1,48 @@
+package schemabuilder
+
+import (
+	"context"
+	"strings"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+type selectionSetKey struct{}
+
+// withSelectionSet returns a context holding the selection set of the field being resolved,
+// which is read by RequestedFields.
+func withSelectionSet(ctx context.Context, selectionSet *graphql.SelectionSet) context.Context {
+	return context.WithValue(ctx, selectionSetKey{}, selectionSet)
+}
+
+// RequestedFields returns the names of the fields selected on the value of the field being
+// resolved, given the context passed to its resolver, e.g. to only load the columns they need:
+//   query.FieldFunc("users", func(ctx context.Context) ([]*User, error) {
+//     return db.Users(ctx, schemabuilder.RequestedFields(ctx))
+//   })
+//
+// The names are those of the immediate child fields, including the fields of fragments, in the
+// order they were requested. Aliases are resolved to the names of the fields, and introspection
+// fields such as __typename are left out. A resolver needing the full selection set may accept
+// a trailing *graphql.SelectionSet argument instead.
+func RequestedFields(ctx context.Context) []string {
+	selectionSet, _ := ctx.Value(selectionSetKey{}).(*graphql.SelectionSet)
+	if selectionSet == nil {
+		return nil
+	}
+	selections, err := graphql.Flatten(selectionSet)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(selections))
+	names := make([]string, 0, len(selections))
+	for _, selection := range selections {
+		if strings.HasPrefix(selection.Name, "__") || seen[selection.Name] {
+			continue
+		}
+		seen[selection.Name] = true
+		names = append(names, selection.Name)
+	}
+	return names
+}
@@ -1,16 +1,19 @@
 package schemabuilder
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"go.appointy.com/jaal/graphql"
 )
 
 //Object - an Object represents a Go type and set of methods to be converted into an Object in a GraphQL schema.
@@ -20,7 +23,27 @@ type Object struct {
 	Type        interface{}
 	Methods     Methods // Deprecated, use FieldFunc instead.
 
-	key string
+	key        string
+	typename   func(source interface{}) string
+	implements []string
+
+	// duplicates are the names of the fields registered more than once, reported by Build.
+	duplicates []string
+}
+
+// ObjectOption configures an Object when it is registered on the schema.
+type ObjectOption func(*Object)
+
+// WithTypename overrides the value reported for __typename on an object. The function
+// receives the source value being resolved, so the reported name can vary per value.
+// For example:
+//   schema.Object("User", User{}, schemabuilder.WithTypename(func(source interface{}) string {
+//     return "UserV" + strconv.Itoa(source.(*User).Version)
+//   }))
+func WithTypename(f func(source interface{}) string) ObjectOption {
+	return func(o *Object) {
+		o.typename = f
+	}
 }
 
 // Key registers the key field on an object. The field should be specified by the name of the graphql field.
@@ -34,11 +57,40 @@ func (s *Object) Key(f string) {
 	s.key = f
 }
 
+// Implements declares that the object implements the interface registered with the name
+// interfaceName, of which it must be a member type. The fields registered on the interface with
+// FieldFunc are inherited by the object, unless it registers a field with the same name. The
+// resolvers of inherited fields receive the interface struct holding the object as their source.
+// For example:
+//   type Character struct {
+//     schemabuilder.Interface
+//     *Droid
+//     *Human
+//   }
+//
+//   character := schema.Object("Character", Character{})
+//   character.FieldFunc("name", func(c *Character) string {
+//     if c.Droid != nil {
+//       return c.Droid.Name
+//     }
+//     return c.Human.Name
+//   })
+//   schema.Object("Droid", Droid{}).Implements("Character")
+func (s *Object) Implements(interfaceName string) {
+	s.implements = append(s.implements, interfaceName)
+}
+
 // InputObject represents the input objects passed in queries,mutations and subscriptions
 type InputObject struct {
 	Name   string
 	Type   interface{}
 	Fields map[string]interface{}
+
+	// duplicates are the names of the fields registered more than once, reported by Build.
+	duplicates []string
+
+	// validators are the functions registered with WithValidator, by field name.
+	validators map[string]func(value interface{}) error
 }
 
 // A Methods map represents the set of methods exposed on a Object.
@@ -47,12 +99,179 @@ type Methods map[string]*method
 type method struct {
 	MarkedNonNullable bool
 	Fn                interface{}
+
+	Deprecated        bool
+	DeprecationReason string
+	ListNullPolicy    ListNullPolicy
+	Args              interface{}
+
+	// Batch is set for fields registered with BatchFieldFunc.
+	Batch bool
+
+	// Directives are the custom directives applied to the field with WithDirective.
+	Directives []*appliedDirective
+
+	// Validator is set with WithValidator, and only applies to the fields of input objects.
+	Validator func(value interface{}) error
+}
+
+// appliedDirective is a custom directive applied to a field, along with its unparsed args.
+type appliedDirective struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// FieldOption configures a field registered with FieldFunc.
+type FieldOption func(*method)
+
+// Deprecated marks a field as deprecated. The reason is exposed through introspection.
+func Deprecated(reason string) FieldOption {
+	return func(m *method) {
+		m.Deprecated = true
+		m.DeprecationReason = reason
+	}
+}
+
+// WithValidator validates the value of a field of an input object, e.g. the format of an email
+// or the range of an age, after it is coerced and before it is passed to the function of the
+// field. The value is of the type of the source argument of that function. An error returned by
+// f fails the parsing of the args, prefixed with the path of the field, wherever the input
+// object is used, be it as an arg or nested in another input object.
+//
+//	user.FieldFunc("age", func(target *User, source int64) { target.Age = source },
+//		schemabuilder.WithValidator(func(value interface{}) error {
+//			if value.(int64) < 0 {
+//				return errors.New("must not be negative")
+//			}
+//			return nil
+//		}))
+func WithValidator(f func(value interface{}) error) FieldOption {
+	return func(m *method) {
+		m.Validator = f
+	}
+}
+
+// NonNull marks the type of a field as non-null even though its resolver returns a pointer,
+// e.g. a *User which is never nil. Returning nil then fails the field. Otherwise, the type of
+// a field is non-null unless the resolver returns a pointer.
+func NonNull() FieldOption {
+	return func(m *method) {
+		m.MarkedNonNullable = true
+	}
+}
+
+// WithArgs declares the arguments of a field whose args parameter is a map[string]interface{},
+// using the fields of the struct spec as an args struct would. The arguments are validated
+// against spec, and the resolver receives them as they were sent.
+func WithArgs(spec interface{}) FieldOption {
+	return func(m *method) {
+		m.Args = spec
+	}
+}
+
+// WithDirective applies the custom directive registered with name to a field, e.g. to require
+// a role. args are parsed as the args of the directive would be in a query. For example:
+//   schema.Directive("auth", []graphql.DirectiveLocation{introspection.FIELD_DEFINITION}, handler,
+//     schemabuilder.WithDirectiveArgs(struct{ Requires Role }{}))
+//   query.FieldFunc("users", listUsers, schemabuilder.WithDirective("auth", map[string]interface{}{"requires": "ADMIN"}))
+//
+// The handler of the directive runs around the resolver whenever the field is resolved, and
+// denies the field by returning an error without calling next. The directive must be registered
+// with the FIELD_DEFINITION location.
+func WithDirective(name string, args map[string]interface{}) FieldOption {
+	return func(m *method) {
+		m.Directives = append(m.Directives, &appliedDirective{Name: name, Args: args})
+	}
+}
+
+// ListNullPolicy controls how nil elements of a list returned by a field are serialized.
+type ListNullPolicy int
+
+const (
+	// KeepNulls serializes nil elements as null, as required by the spec.
+	KeepNulls ListNullPolicy = iota
+	// DropNulls removes nil elements from the list.
+	DropNulls
+)
+
+// WithListNullPolicy sets how nil elements of the list returned by a field are serialized.
+// Fields keep null elements by default.
+func WithListNullPolicy(policy ListNullPolicy) FieldOption {
+	return func(m *method) {
+		m.ListNullPolicy = policy
+	}
+}
+
+// Directive is a custom directive registered on the schema with Schema.Directive.
+type Directive struct {
+	Name        string
+	Description string
+	Locations   []graphql.DirectiveLocation
+	Args        interface{}
+	Repeatable  bool
+	Handler     DirectiveHandler
+}
+
+// DirectiveHandler executes a custom directive applied to a field. args holds the arguments
+// of the directive, parsed into the struct given with WithDirectiveArgs, and next calls the
+// resolver of the field. A handler may transform the value returned by next, or return
+// without calling it to short-circuit the execution of the field.
+type DirectiveHandler func(ctx context.Context, args interface{}, next func(ctx context.Context) (interface{}, error)) (interface{}, error)
+
+// DirectiveOption configures a directive when it is registered on the schema.
+type DirectiveOption func(*Directive)
+
+// WithDirectiveArgs declares the arguments of a directive using the fields of the struct args,
+// which are parsed as the args struct of a field would be. For example:
+//   schema.Directive("auth", locations, handler, schemabuilder.WithDirectiveArgs(struct{ Role Role }{}))
+func WithDirectiveArgs(args interface{}) DirectiveOption {
+	return func(d *Directive) {
+		d.Args = args
+	}
+}
+
+// WithDirectiveDescription sets the description of a directive, exposed through introspection.
+func WithDirectiveDescription(description string) DirectiveOption {
+	return func(d *Directive) {
+		d.Description = description
+	}
+}
+
+// WithDirectiveRepeatable marks a directive as repeatable, exposed through introspection as
+// isRepeatable.
+func WithDirectiveRepeatable() DirectiveOption {
+	return func(d *Directive) {
+		d.Repeatable = true
+	}
 }
 
 // EnumMapping is a representation of an enum that includes both the mapping and reverse mapping.
 type EnumMapping struct {
+	Name       string // Optional, defaults to the name of the Go type.
 	Map        map[string]interface{}
 	ReverseMap map[interface{}]string
+
+	// duplicates are the names registered more than once, or mapping to the value of another
+	// name, reported by Build.
+	duplicates []string
+}
+
+// EnumOption configures an enum when it is registered on the schema.
+type EnumOption func(*EnumMapping)
+
+// WithName overrides the GraphQL name of an enum, which otherwise defaults to the name of its Go type.
+func WithName(name string) EnumOption {
+	return func(m *EnumMapping) {
+		m.Name = name
+	}
+}
+
+// name returns the GraphQL name of the enum represented by typ.
+func (m *EnumMapping) name(typ reflect.Type) string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return typ.Name()
 }
 
 // InterfaceObj is a representation of graphql interface
@@ -63,6 +282,10 @@ type InterfaceObj struct {
 
 // Interface is a special marker struct that can be embedded into to denote that a type should be
 // treated as a interface type by the schemabuilder
+//
+// Fields may also return a Go interface implemented by all the member types of a registered
+// interface struct, such as []Friend where *Droid and *Human implement Friend. Every value is
+// then resolved as the member of its dynamic type, without being wrapped in the interface struct.
 type Interface struct{}
 
 // Union is a special marker struct that can be embedded into to denote
@@ -100,15 +323,73 @@ var unionType = reflect.TypeOf(Union{})
 //        userID, err := db.AddUser(ctx, args.FirstName, args.LastName)
 //        return userID, err
 //    })
-func (s *Object) FieldFunc(name string, f interface{}) {
+//
+// A list field may return a slice of functions instead of a slice of values. The functions
+// are only called when the field is executed, and are called concurrently, at most
+// MaxConcurrency at once if the executor sets it:
+//    user.FieldFunc("friends", func(ctx context.Context, u *User) []func() (*User, error) {
+//        thunks := make([]func() (*User, error), 0, len(u.FriendIDs))
+//        for _, id := range u.FriendIDs {
+//            id := id
+//            thunks = append(thunks, func() (*User, error) { return db.GetUser(ctx, id) })
+//        }
+//        return thunks
+//    })
+//
+// A function may also return whether its result is present, as (Result, bool[, error]). The
+// field is then nullable, and resolves to null when the bool is false, even for a Result such
+// as a string which cannot be nil:
+//    user.FieldFunc("nickname", func(u *User) (string, bool) {
+//        return u.Nickname, u.Nickname != ""
+//    })
+//
+// Options such as Deprecated can be passed after the function.
+func (s *Object) FieldFunc(name string, f interface{}, opts ...FieldOption) {
 	if s.Methods == nil {
 		s.Methods = make(Methods)
 	}
 
 	m := &method{Fn: f}
+	for _, opt := range opts {
+		opt(m)
+	}
 
 	if _, ok := s.Methods[name]; ok {
-		panic("duplicate method")
+		s.duplicates = append(s.duplicates, name)
+		return
+	}
+	s.Methods[name] = m
+}
+
+// BatchFieldFunc exposes a field on an object, resolved once for all the objects of a list
+// selecting it instead of once per object, which avoids issuing a query per object. The function
+// receives the sources as a slice of the object type:
+//   user.BatchFieldFunc("posts", func(ctx context.Context, users []*User, args struct{ First int64 }) ([][]*Post, error) {
+//     return db.PostsByUsers(ctx, users, args.First)
+//   })
+//
+// The returned slice must hold exactly one result for every source, in the order of the sources:
+// the i-th result is the value of the field for the i-th source. Objects outside of a list are
+// resolved with a slice holding only their source.
+//
+// A result may also be a function, or a list of functions, which is only called once the
+// selected fields of every source are resolved, like the functions returned by FieldFunc:
+//   user.BatchFieldFunc("friends", func(ctx context.Context, users []*User) [][]func() (*User, error) {
+//     return db.LazyFriendsByUsers(ctx, users)
+//   })
+func (s *Object) BatchFieldFunc(name string, f interface{}, opts ...FieldOption) {
+	if s.Methods == nil {
+		s.Methods = make(Methods)
+	}
+
+	m := &method{Fn: f, Batch: true}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if _, ok := s.Methods[name]; ok {
+		s.duplicates = append(s.duplicates, name)
+		return
 	}
 	s.Methods[name] = m
 }
@@ -125,8 +406,9 @@ func (s *Object) FieldFunc(name string, f interface{}) {
 // inputObj.FieldFunc("firstName", func(target *ServiceProvider, source *string) {
 // 	target.FirstName = *source
 // })
-// The target variable of the function should be pointer
-func (io *InputObject) FieldFunc(name string, function interface{}) {
+// The target variable of the function should be pointer. Of the options, only WithValidator
+// applies to the fields of input objects.
+func (io *InputObject) FieldFunc(name string, function interface{}, opts ...FieldOption) {
 	funcTyp := reflect.TypeOf(function)
 
 	if funcTyp.NumIn() != 2 {
@@ -142,12 +424,63 @@ func (io *InputObject) FieldFunc(name string, function interface{}) {
 		panic(fmt.Errorf("can not register field %v on %v as number of output parameters should be less than 2", name, io.Name))
 	}
 
+	if _, ok := io.Fields[name]; ok {
+		io.duplicates = append(io.duplicates, name)
+		return
+	}
 	io.Fields[name] = function
+
+	m := &method{Fn: function}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.Validator != nil {
+		if io.validators == nil {
+			io.validators = make(map[string]func(value interface{}) error)
+		}
+		io.validators[name] = m.Validator
+	}
 }
 
 // UnmarshalFunc is used to unmarshal scalar value from JSON
 type UnmarshalFunc func(value interface{}, dest reflect.Value) error
 
+// SerializeFunc is used to convert a scalar value into the value written to the response
+type SerializeFunc func(value interface{}) (interface{}, error)
+
+// ScalarOption configures a scalar when it is registered with RegisterScalar.
+type ScalarOption func(*scalarOptions)
+
+type scalarOptions struct {
+	serialize      SerializeFunc
+	description    string
+	specifiedByURL string
+}
+
+// WithSerializer sets the function used to serialize the scalar in the response, instead of
+// relying on its json.Marshaler implementation. This decouples the output of a scalar from
+// the input accepted by its UnmarshalFunc.
+func WithSerializer(f SerializeFunc) ScalarOption {
+	return func(o *scalarOptions) {
+		o.serialize = f
+	}
+}
+
+// WithScalarDescription sets the description of the scalar, exposed through introspection.
+func WithScalarDescription(description string) ScalarOption {
+	return func(o *scalarOptions) {
+		o.description = description
+	}
+}
+
+// WithSpecifiedByURL sets the URL of the specification of the scalar, exposed through
+// introspection as its specifiedByURL.
+func WithSpecifiedByURL(url string) ScalarOption {
+	return func(o *scalarOptions) {
+		o.specifiedByURL = url
+	}
+}
+
 // RegisterScalar is used to register custom scalars.
 //
 // For example, to register a custom ID type,
@@ -175,11 +508,19 @@ type UnmarshalFunc func(value interface{}, dest reflect.Value) error
 //		panic(err)
 //	}
 //}
-func RegisterScalar(typ reflect.Type, name string, uf UnmarshalFunc) error {
+//
+// The output of a scalar can be customized independent of its input with WithSerializer, and
+// it can be documented with WithScalarDescription.
+func RegisterScalar(typ reflect.Type, name string, uf UnmarshalFunc, opts ...ScalarOption) error {
 	if typ.Kind() == reflect.Ptr {
 		return errors.New("type should not be of pointer type")
 	}
 
+	var o scalarOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	if uf == nil {
 		// Slow fail safe to avoid reflection code by package users
 		if !reflect.PtrTo(typ).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
@@ -221,10 +562,178 @@ func RegisterScalar(typ reflect.Type, name string, uf UnmarshalFunc) error {
 	scalarArgParsers[typ] = &argParser{
 		FromJSON: uf,
 	}
+	if o.serialize != nil {
+		scalarSerializers[typ] = o.serialize
+	} else {
+		delete(scalarSerializers, typ)
+	}
+	if o.description != "" {
+		scalarDescriptions[name] = o.description
+	} else {
+		delete(scalarDescriptions, name)
+	}
+	if o.specifiedByURL != "" {
+		scalarSpecifiedByURLs[name] = o.specifiedByURL
+	} else {
+		delete(scalarSpecifiedByURLs, name)
+	}
 
 	return nil
 }
 
+// TimeFormat is the representation of a time scalar registered with RegisterTimeScalar.
+type TimeFormat int
+
+const (
+	// RFC3339 represents times as RFC 3339 strings, such as "2006-01-02T15:04:05Z".
+	RFC3339 TimeFormat = iota
+	// UnixSeconds represents times as the number of seconds elapsed since the Unix epoch.
+	UnixSeconds
+	// UnixMillis represents times as the number of milliseconds elapsed since the Unix epoch.
+	UnixMillis
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// rfc3339URL is the specification of the DateTime scalar.
+const rfc3339URL = "https://datatracker.ietf.org/doc/html/rfc3339"
+
+// time.Time is registered by default as the DateTime scalar, represented as RFC 3339 strings.
+// It can be overridden by registering time.Time with RegisterScalar or RegisterTimeScalar.
+func init() {
+	if err := RegisterTimeScalar(timeType, "DateTime", RFC3339, WithSpecifiedByURL(rfc3339URL)); err != nil {
+		panic(err)
+	}
+	if err := RegisterBigIntScalar(reflect.TypeOf(Int64(0)), "BigInt", WithSpecifiedByURL(bigIntURL)); err != nil {
+		panic(err)
+	}
+}
+
+// bigIntURL is the specification of the BigInt scalar, describing the precision of JSON numbers.
+const bigIntURL = "https://datatracker.ietf.org/doc/html/rfc8259#section-6"
+
+// RegisterBigIntScalar registers typ as a scalar holding a 64-bit integer, which is written to
+// the response as a string to preserve its precision, and is parsed from both strings and
+// numbers. typ must be an int64 or a type defined on it. Int64 is registered by default as the
+// BigInt scalar, and resolvers can return int64 directly as a BigInt by registering it with
+//
+// schemabuilder.RegisterBigIntScalar(reflect.TypeOf(int64(0)), "BigInt")
+//
+// The opts are applied after the serializer, such that WithSerializer overrides it.
+func RegisterBigIntScalar(typ reflect.Type, name string, opts ...ScalarOption) error {
+	if typ.Kind() != reflect.Int64 {
+		return fmt.Errorf("type %v should be of kind int64", typ)
+	}
+
+	serialize := func(value interface{}) (interface{}, error) {
+		return strconv.FormatInt(reflect.ValueOf(value).Int(), 10), nil
+	}
+	description := WithScalarDescription("A 64-bit integer, represented as a string to preserve its precision.")
+
+	return RegisterScalar(typ, name, unmarshalBigInt, append([]ScalarOption{WithSerializer(serialize), description}, opts...)...)
+}
+
+// unmarshalBigInt parses a BigInt scalar from a string, or from a number without fractional part.
+func unmarshalBigInt(value interface{}, dest reflect.Value) error {
+	var i int64
+	switch v := value.(type) {
+	case string:
+		var err error
+		if i, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("invalid BigInt %q", v)
+		}
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return fmt.Errorf("invalid BigInt %v", v)
+		}
+		i = int64(v)
+	default:
+		return errors.New("invalid type expected string or number")
+	}
+
+	dest.SetInt(i)
+	return nil
+}
+
+// RegisterTimeScalar registers typ as a scalar holding a time in the given format. The
+// format is used both to parse arguments and to serialize the response. typ must be
+// time.Time or a type defined on it, such as
+//
+// type Time time.Time
+//
+// The opts are applied after the serializer of the format, such that WithSerializer overrides it.
+func RegisterTimeScalar(typ reflect.Type, name string, format TimeFormat, opts ...ScalarOption) error {
+	if typ.Kind() == reflect.Ptr {
+		return errors.New("type should not be of pointer type")
+	}
+	if !typ.ConvertibleTo(timeType) || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("type %v should be convertible to time.Time", typ)
+	}
+
+	var serialize SerializeFunc
+	var unmarshal UnmarshalFunc
+	switch format {
+	case RFC3339:
+		serialize = func(value interface{}) (interface{}, error) {
+			return toTime(value).Format(time.RFC3339), nil
+		}
+		unmarshal = func(value interface{}, dest reflect.Value) error {
+			v, ok := value.(string)
+			if !ok {
+				return errors.New("invalid type expected string")
+			}
+
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return err
+			}
+
+			dest.Set(reflect.ValueOf(t).Convert(dest.Type()))
+			return nil
+		}
+
+	case UnixSeconds, UnixMillis:
+		unit := time.Second
+		if format == UnixMillis {
+			unit = time.Millisecond
+		}
+
+		serialize = func(value interface{}) (interface{}, error) {
+			return toTime(value).UnixNano() / int64(unit), nil
+		}
+		unmarshal = func(value interface{}, dest reflect.Value) error {
+			v, ok := value.(float64)
+			if !ok {
+				return errors.New("invalid type expected number")
+			}
+
+			t := time.Unix(0, int64(v)*int64(unit)).UTC()
+			dest.Set(reflect.ValueOf(t).Convert(dest.Type()))
+			return nil
+		}
+
+	default:
+		return fmt.Errorf("unknown time format %d", format)
+	}
+
+	return RegisterScalar(typ, name, unmarshal, append([]ScalarOption{WithSerializer(serialize)}, opts...)...)
+}
+
+// toTime converts a value of a time scalar to a time.Time.
+func toTime(value interface{}) time.Time {
+	return reflect.ValueOf(value).Convert(timeType).Interface().(time.Time)
+}
+
+// Int64 is the BigInt scalar, holding a 64-bit integer which is written to the response as a
+// string, since JSON numbers lose precision above 2^53 in clients such as JavaScript. It is
+// parsed from both strings and numbers, see RegisterBigIntScalar.
+type Int64 int64
+
+// MarshalJSON implements JSON Marshalling used to generate the output
+func (i Int64) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, strconv.FormatInt(int64(i), 10)), nil
+}
+
 // ID is the graphql ID scalar
 type ID struct {
 	Value string
@@ -241,9 +750,11 @@ func isScalarType(t reflect.Type) bool {
 	return ok
 }
 
-// typesIdenticalOrScalarAliases checks whether a & b are same scalar
+// typesIdenticalOrScalarAliases checks whether a & b are same scalar. Types defined on a basic
+// type, such as type UserID int64, are aliases of the scalar of the basic type only, so that
+// they don't match scalars defined on the same kind, such as Int64.
 func typesIdenticalOrScalarAliases(a, b reflect.Type) bool {
-	return a == b || (a.Kind() == b.Kind() && (a.Kind() != reflect.Struct) && (a.Kind() != reflect.Map) && isScalarType(a))
+	return a == b || (a.Kind() == b.Kind() && a.PkgPath() == "" && (a.Kind() != reflect.Struct) && (a.Kind() != reflect.Map) && isScalarType(a))
 }
 
 //Timestamp handles the time
@@ -269,6 +780,18 @@ func (m Map) MarshalJSON() ([]byte, error) {
 	return d, nil
 }
 
+// JSON is a scalar holding an arbitrary JSON value, such as an object or a list. Unlike Map,
+// the value is written as is in the response, and input literals like {a: 1, b: [2, 3]} are
+// accepted as arguments.
+type JSON struct {
+	Value interface{}
+}
+
+// MarshalJSON implements JSON Marshalling used to generate the output
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
 //Duration handles the duration
 type Duration duration.Duration
 
@@ -0,0 +1,125 @@
+package jaal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.appointy.com/jaal/graphql"
+)
+
+// WithTracing adds the timings of the parsing, validation and resolvers of every operation
+// executed by the HTTPHandler to the extensions of its response, under "tracing", in the Apollo
+// Tracing format. The timing of a field includes its field middlewares, and covers the resolver
+// returning the function of a lazy field but not the function itself.
+func WithTracing() HandlerOption {
+	return func(h *handlerOptions) {
+		h.Tracing = true
+	}
+}
+
+// tracing records the timings of an operation.
+type tracing struct {
+	start      time.Time
+	parsing    tracingPhase
+	validation tracingPhase
+
+	mu        sync.Mutex
+	resolvers []tracingResolver
+}
+
+// tracingPhase is the timing of a phase of an operation, in nanoseconds from the start of the
+// operation.
+type tracingPhase struct {
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+type tracingResolver struct {
+	Path        []interface{} `json:"path"`
+	ParentType  string        `json:"parentType"`
+	FieldName   string        `json:"fieldName"`
+	ReturnType  string        `json:"returnType"`
+	StartOffset time.Duration `json:"startOffset"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// tracingExtension is the tracing extension of a response.
+type tracingExtension struct {
+	Version    int           `json:"version"`
+	StartTime  time.Time     `json:"startTime"`
+	EndTime    time.Time     `json:"endTime"`
+	Duration   time.Duration `json:"duration"`
+	Parsing    tracingPhase  `json:"parsing"`
+	Validation tracingPhase  `json:"validation"`
+	Execution  struct {
+		Resolvers []tracingResolver `json:"resolvers"`
+	} `json:"execution"`
+}
+
+func addTracing(ctx context.Context, t *tracing) context.Context {
+	return context.WithValue(ctx, tracingKey, t)
+}
+
+// phase returns the timing of the phase which started at start and ends now.
+func (t *tracing) phase(start time.Time) tracingPhase {
+	return tracingPhase{StartOffset: start.Sub(t.start), Duration: time.Since(start)}
+}
+
+// parsed records the end of the parsing, which started with the operation. It does nothing on a
+// nil tracing, i.e. when tracing is disabled.
+func (t *tracing) parsed() {
+	if t != nil {
+		t.parsing = t.phase(t.start)
+	}
+}
+
+// validated records the end of the validation, which started at the end of the parsing.
+func (t *tracing) validated() {
+	if t != nil {
+		t.validation = t.phase(t.start.Add(t.parsing.StartOffset + t.parsing.Duration))
+	}
+}
+
+// extension returns the tracing extension of the operation, which ends now.
+func (t *tracing) extension() *tracingExtension {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	end := time.Now()
+	extension := &tracingExtension{
+		Version:    1,
+		StartTime:  t.start.UTC(),
+		EndTime:    end.UTC(),
+		Duration:   end.Sub(t.start),
+		Parsing:    t.parsing,
+		Validation: t.validation,
+	}
+	extension.Execution.Resolvers = append([]tracingResolver{}, t.resolvers...)
+	return extension
+}
+
+// traceField is the field middleware recording the timing of every field of the operations
+// traced with WithTracing.
+func traceField(ctx context.Context, info graphql.FieldInfo, next func() (interface{}, error)) (interface{}, error) {
+	t, ok := ctx.Value(tracingKey).(*tracing)
+	if !ok {
+		return next()
+	}
+
+	start := time.Now()
+	value, err := next()
+	resolver := tracingResolver{
+		Path:        graphql.FieldPathFromContext(ctx),
+		ParentType:  info.ParentType,
+		FieldName:   info.FieldName,
+		ReturnType:  info.ReturnType.String(),
+		StartOffset: start.Sub(t.start),
+		Duration:    time.Since(start),
+	}
+
+	t.mu.Lock()
+	t.resolvers = append(t.resolvers, resolver)
+	t.mu.Unlock()
+	return value, err
+}
@@ -0,0 +1,433 @@
+package jaal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go.appointy.com/jaal/graphql"
+	"go.appointy.com/jaal/jerrors"
+)
+
+// transportWSProtocol is the websocket subprotocol implemented by WebSocketHandler.
+const transportWSProtocol = "graphql-transport-ws"
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	closeBadRequest      = 4400
+	closeUnauthorized    = 4401
+	closeInitTimeout     = 4408
+	closeSubscriberTaken = 4409
+	closeTooManyInits    = 4429
+)
+
+const (
+	defaultSubscriptionInterval  = time.Second
+	defaultConnectionInitTimeout = 3 * time.Second
+)
+
+// WithSubscriptionInterval sets how often WebSocketHandler calls the function returned by a
+// subscription resolver to produce the next value. It defaults to one second.
+func WithSubscriptionInterval(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.SubscriptionInterval = d
+	}
+}
+
+// WithKeepAlive makes WebSocketHandler send a ping message every d, closing the connection
+// if the client has not answered the previous ping with a pong. This keeps idle connections
+// open behind proxies and detects dead peers. Keep-alive is disabled by default.
+func WithKeepAlive(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.KeepAlive = d
+	}
+}
+
+// WithConnectionInitTimeout sets how long WebSocketHandler waits for the connection_init
+// message of a client before closing its connection. It defaults to three seconds.
+func WithConnectionInitTimeout(d time.Duration) HandlerOption {
+	return func(h *handlerOptions) {
+		h.ConnectionInitTimeout = d
+	}
+}
+
+// WebSocketHandler serves queries, mutations and subscriptions over websockets, implementing
+// the graphql-transport-ws protocol. Every operation on a connection is identified by the id
+// of its subscribe message, and is cancelled when the client completes it or the connection
+// is closed.
+//
+// A subscription resolver returns either a function, e.g. func() T, which is called on every
+// tick of WithSubscriptionInterval, or a channel, e.g. <-chan T. Every value the function
+// returns or the channel delivers is sent to the client in a next message, unless the function
+// returns graphql.ErrNoUpdate. The subscription is completed once the channel is closed.
+func WebSocketHandler(schema *graphql.Schema, opts ...HandlerOption) http.Handler {
+	o := handlerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	interval := o.SubscriptionInterval
+	if interval <= 0 {
+		interval = defaultSubscriptionInterval
+	}
+	initTimeout := o.ConnectionInitTimeout
+	if initTimeout <= 0 {
+		initTimeout = defaultConnectionInitTimeout
+	}
+
+	return &wsHandler{
+		schema:               schema,
+		upgrader:             &websocket.Upgrader{Subprotocols: []string{transportWSProtocol}},
+		interval:             interval,
+		keepAlive:            o.KeepAlive,
+		initTimeout:          initTimeout,
+		deprecationUsageHook: o.DeprecationUsageHook,
+		panicHandler:         o.PanicHandler,
+		fieldMiddleware:      o.FieldMiddlewares,
+		contextFunc:          o.ContextFunc,
+
+		introspectionDisabled: o.IntrospectionDisabled,
+	}
+}
+
+type wsHandler struct {
+	schema               *graphql.Schema
+	upgrader             *websocket.Upgrader
+	interval             time.Duration
+	keepAlive            time.Duration
+	initTimeout          time.Duration
+	deprecationUsageHook func(ctx context.Context, typeName, fieldName string)
+	panicHandler         func(ctx context.Context, recovered interface{}, stack []byte)
+	fieldMiddleware      []graphql.FieldMiddleware
+	contextFunc          func(r *http.Request) context.Context
+
+	introspectionDisabled bool
+}
+
+// wsConnection is a websocket connection speaking the graphql-transport-ws protocol.
+type wsConnection struct {
+	handler *wsHandler
+	conn    *websocket.Conn
+
+	writeMu sync.Mutex
+
+	mu           sync.Mutex
+	initialized  bool
+	awaitingPong bool
+	operations   map[string]context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := &wsConnection{
+		handler:    h,
+		conn:       conn,
+		operations: make(map[string]context.CancelFunc),
+	}
+
+	ctx := r.Context()
+	if h.contextFunc != nil {
+		ctx = h.contextFunc(r)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.wg.Wait()
+	}()
+
+	if conn.Subprotocol() != transportWSProtocol {
+		c.close(closeBadRequest, "unsupported subprotocol")
+		return
+	}
+
+	c.wg.Add(1)
+	go c.awaitInit(ctx)
+	if h.keepAlive > 0 {
+		c.wg.Add(1)
+		go c.ping(ctx)
+	}
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		if !c.handle(ctx, &msg) {
+			return
+		}
+	}
+}
+
+// handle processes a message received from the client, and reports whether the connection
+// should be kept open.
+func (c *wsConnection) handle(ctx context.Context, msg *wsMessage) bool {
+	switch msg.Type {
+	case "connection_init":
+		c.mu.Lock()
+		initialized := c.initialized
+		c.initialized = true
+		c.mu.Unlock()
+
+		if initialized {
+			c.close(closeTooManyInits, "Too many initialisation requests")
+			return false
+		}
+		return c.write(&wsMessage{Type: "connection_ack"}) == nil
+
+	case "ping":
+		return c.write(&wsMessage{Type: "pong"}) == nil
+
+	case "pong":
+		c.mu.Lock()
+		c.awaitingPong = false
+		c.mu.Unlock()
+		return true
+
+	case "subscribe":
+		c.mu.Lock()
+		initialized := c.initialized
+		_, taken := c.operations[msg.Id]
+		c.mu.Unlock()
+
+		if !initialized {
+			c.close(closeUnauthorized, "Unauthorized")
+			return false
+		}
+		if msg.Id == "" {
+			c.close(closeBadRequest, "Invalid message received")
+			return false
+		}
+		if taken {
+			c.close(closeSubscriberTaken, fmt.Sprintf("Subscriber for %s already exists", msg.Id))
+			return false
+		}
+
+		var payload gqlPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			c.close(closeBadRequest, "Invalid message received")
+			return false
+		}
+
+		c.subscribe(ctx, msg.Id, &payload)
+		return true
+
+	case "complete":
+		c.mu.Lock()
+		if cancel, ok := c.operations[msg.Id]; ok {
+			cancel()
+			delete(c.operations, msg.Id)
+		}
+		c.mu.Unlock()
+		return true
+
+	default:
+		c.close(closeBadRequest, "Invalid message received")
+		return false
+	}
+}
+
+// awaitInit closes the connection unless the client initializes it within the connection
+// init timeout.
+func (c *wsConnection) awaitInit(ctx context.Context) {
+	defer c.wg.Done()
+
+	timer := time.NewTimer(c.handler.initTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	c.mu.Lock()
+	initialized := c.initialized
+	c.mu.Unlock()
+
+	if !initialized {
+		c.close(closeInitTimeout, "Connection initialisation timeout")
+		_ = c.conn.Close()
+	}
+}
+
+// ping sends a ping message on every tick of the keep-alive interval until ctx is cancelled,
+// and closes the connection once a ping has not been answered by the next tick.
+func (c *wsConnection) ping(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.handler.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+		awaiting := c.awaitingPong
+		c.awaitingPong = true
+		c.mu.Unlock()
+
+		if awaiting {
+			_ = c.conn.Close()
+			return
+		}
+		if err := c.write(&wsMessage{Type: "ping"}); err != nil {
+			return
+		}
+	}
+}
+
+// subscribe starts executing an operation.
+func (c *wsConnection) subscribe(ctx context.Context, id string, payload *gqlPayload) {
+	query, err := graphql.ParseOperation(payload.Query, payload.OpName, payload.Variables)
+	if err != nil {
+		c.writeError(id, err)
+		return
+	}
+
+	var root graphql.Type
+	switch query.Kind {
+	case "mutation":
+		root = c.handler.schema.Mutation
+	case "subscription":
+		root = c.handler.schema.Subscription
+	default:
+		root = c.handler.schema.Query
+	}
+
+	validateCtx := ctx
+	if c.handler.introspectionDisabled {
+		validateCtx = graphql.WithIntrospectionDisabled(ctx)
+	}
+	if err := graphql.ValidateQuery(validateCtx, root, query.SelectionSet); err != nil {
+		c.writeError(id, err)
+		return
+	}
+	if err := graphql.ValidateDirectives(c.handler.schema.Directives, query.SelectionSet); err != nil {
+		c.writeError(id, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ctx = addVariables(ctx, query.Variables)
+
+	c.mu.Lock()
+	c.operations[id] = cancel
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		var err error
+		if query.Kind == "subscription" {
+			err = c.stream(ctx, id, root, query)
+		} else {
+			executor := c.handler.newExecutor()
+			var data interface{}
+			data, err = executor.Execute(ctx, root, nil, query)
+			if data != nil {
+				err = c.write(&wsMessage{Type: "next", Id: id, Payload: marshalPayload(newWSResponse(data, err))})
+			}
+		}
+
+		c.mu.Lock()
+		_, active := c.operations[id]
+		delete(c.operations, id)
+		c.mu.Unlock()
+
+		// The operation was completed by the client or the connection was closed.
+		if !active || ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			_ = c.write(&wsMessage{Type: "next", Id: id, Payload: marshalPayload(httpResponse{Errors: []*jerrors.Error{jerrors.ConvertError(err)}})})
+		}
+		_ = c.write(&wsMessage{Type: "complete", Id: id})
+	}()
+}
+
+// stream sends the responses of a subscription to the events of its source stream until ctx
+// is cancelled or the source stream ends.
+func (c *wsConnection) stream(ctx context.Context, id string, root graphql.Type, query *graphql.Query) error {
+	results, err := c.handler.newExecutor().Subscribe(ctx, root, query)
+	if err != nil {
+		return err
+	}
+
+	for result := range results {
+		if result.Data == nil {
+			return result.Err
+		}
+		if err := c.write(&wsMessage{Type: "next", Id: id, Payload: marshalPayload(newWSResponse(result.Data, result.Err))}); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (h *wsHandler) newExecutor() *graphql.Executor {
+	return &graphql.Executor{
+		DeprecationUsageHook: h.deprecationUsageHook,
+		Directives:           h.schema.Directives,
+		FieldMiddleware:      h.fieldMiddleware,
+		PanicHandler:         h.panicHandler,
+		SubscriptionInterval: h.interval,
+	}
+}
+
+// newWSResponse builds the payload of a next message, keeping the data of an operation which
+// failed partially alongside its errors.
+func newWSResponse(data interface{}, err error) httpResponse {
+	response := httpResponse{Data: data}
+	if multi := jerrors.ConvertMultiError(err); multi != nil {
+		response.Errors = multi.Errors
+	} else if err != nil {
+		response.Errors = []*jerrors.Error{jerrors.ConvertError(err)}
+	}
+	return response
+}
+
+// writeError sends the errors of an operation which could not be executed.
+func (c *wsConnection) writeError(id string, err error) {
+	_ = c.write(&wsMessage{Type: "error", Id: id, Payload: marshalPayload([]*jerrors.Error{jerrors.ConvertError(err)})})
+}
+
+func (c *wsConnection) write(msg *wsMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConnection) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
+
+func marshalPayload(v interface{}) json.RawMessage {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		payload, _ = json.Marshal([]*jerrors.Error{jerrors.ConvertError(err)})
+	}
+	return payload
+}
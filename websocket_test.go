@@ -0,0 +1,225 @@
+package jaal_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kylelemons/godebug/pretty"
+	"go.appointy.com/jaal"
+	"go.appointy.com/jaal/schemabuilder"
+)
+
+func TestWebSocketHandler(t *testing.T) {
+	var mu sync.Mutex
+	var subscriptions []context.Context
+
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func() string {
+		return "world"
+	})
+	schema.Subscription().FieldFunc("counter", func(ctx context.Context) func() int64 {
+		mu.Lock()
+		subscriptions = append(subscriptions, ctx)
+		mu.Unlock()
+
+		var count int64
+		return func() int64 {
+			count++
+			return count
+		}
+	})
+
+	server := httptest.NewServer(jaal.WebSocketHandler(schema.MustBuild(), jaal.WithSubscriptionInterval(5*time.Millisecond)))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() string {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(msg))
+	}
+
+	send(`{"type":"connection_init"}`)
+	if diff := pretty.Compare(read(), `{"type":"connection_ack"}`); diff != "" {
+		t.Fatalf("expected connection_ack, but received %s", diff)
+	}
+
+	send(`{"type":"subscribe","id":"1","payload":{"query":"subscription { counter }"}}`)
+	send(`{"type":"subscribe","id":"2","payload":{"query":"subscription { count: counter }"}}`)
+
+	// Both subscriptions are multiplexed on the connection, each with its own counter.
+	received := map[string][]string{}
+	for len(received["1"]) < 2 || len(received["2"]) < 2 {
+		msg := read()
+		switch {
+		case strings.Contains(msg, `"id":"1"`):
+			received["1"] = append(received["1"], msg)
+		case strings.Contains(msg, `"id":"2"`):
+			received["2"] = append(received["2"], msg)
+		default:
+			t.Fatalf("unexpected message %s", msg)
+		}
+	}
+	if diff := pretty.Compare(received["1"][:2], []string{
+		`{"type":"next","id":"1","payload":{"data":{"counter":1},"errors":null}}`,
+		`{"type":"next","id":"1","payload":{"data":{"counter":2},"errors":null}}`,
+	}); diff != "" {
+		t.Errorf("expected messages to match, but received %s", diff)
+	}
+	if diff := pretty.Compare(received["2"][:2], []string{
+		`{"type":"next","id":"2","payload":{"data":{"count":1},"errors":null}}`,
+		`{"type":"next","id":"2","payload":{"data":{"count":2},"errors":null}}`,
+	}); diff != "" {
+		t.Errorf("expected messages to match, but received %s", diff)
+	}
+
+	// Queries are executed once and completed.
+	send(`{"type":"complete","id":"1"}`)
+	send(`{"type":"subscribe","id":"3","payload":{"query":"{ hello }"}}`)
+
+	var query []string
+	for len(query) < 2 {
+		if msg := read(); strings.Contains(msg, `"id":"3"`) {
+			query = append(query, msg)
+		}
+	}
+	if diff := pretty.Compare(query, []string{
+		`{"type":"next","id":"3","payload":{"data":{"hello":"world"},"errors":null}}`,
+		`{"type":"complete","id":"3"}`,
+	}); diff != "" {
+		t.Errorf("expected messages to match, but received %s", diff)
+	}
+
+	// Closing the connection cancels the remaining subscription.
+	conn.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, ctx := range subscriptions {
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected subscription to be cancelled")
+		}
+	}
+}
+
+func TestWebSocketHandlerRequiresInit(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func() string {
+		return "world"
+	})
+
+	server := httptest.NewServer(jaal.WebSocketHandler(schema.MustBuild()))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscribe","id":"1","payload":{"query":"{ hello }"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4401) {
+		t.Errorf("expected close with 4401, but received %v", err)
+	}
+}
+
+func TestWebSocketHandlerInitTimeout(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func() string {
+		return "world"
+	})
+
+	server := httptest.NewServer(jaal.WebSocketHandler(schema.MustBuild(), jaal.WithConnectionInitTimeout(10*time.Millisecond)))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, 4408) {
+		t.Errorf("expected close with 4408, but received %v", err)
+	}
+}
+
+func TestWebSocketHandlerKeepAlive(t *testing.T) {
+	schema := schemabuilder.NewSchema()
+	schema.Query().FieldFunc("hello", func() string {
+		return "world"
+	})
+
+	server := httptest.NewServer(jaal.WebSocketHandler(schema.MustBuild(), jaal.WithKeepAlive(50*time.Millisecond)))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() (string, error) {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		return strings.TrimSpace(string(msg)), err
+	}
+
+	send(`{"type":"connection_init"}`)
+	if msg, err := read(); err != nil || msg != `{"type":"connection_ack"}` {
+		t.Fatalf("expected connection_ack, but received %s, %v", msg, err)
+	}
+
+	// The connection stays open while the client answers every ping.
+	for i := 0; i < 3; i++ {
+		if msg, err := read(); err != nil || msg != `{"type":"ping"}` {
+			t.Fatalf("expected ping, but received %s, %v", msg, err)
+		}
+		send(`{"type":"pong"}`)
+	}
+
+	// A ping left unanswered closes the connection.
+	for {
+		if _, err := read(); err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				t.Fatalf("expected connection to be closed, but received %v", err)
+			}
+			break
+		}
+	}
+}
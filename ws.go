@@ -164,7 +164,7 @@ loop:
 				fmt.Println(err)
 				return
 			}
-			query, err := graphql.Parse(gql.Query, gql.Variables)
+			query, err := graphql.ParseOperation(gql.Query, gql.OpName, gql.Variables)
 			if err != nil {
 				if er := writeResponse(conn, "error", data.Id, nil, err); er != nil {
 					fmt.Println(err)
@@ -306,7 +306,10 @@ func (h *httpSubHandler) serveHTTP(conn *webConn, data wsMessage, schema graphql
 			return nil
 		default:
 			if err := func() error {
-				res, err := h.executor.Execute(r.Context(), schema, &schemabuilder.Subscription{msg.payload}, query)
+				// Every execution uses its own copy of the executor, which holds the state of
+				// the execution, as the executor is shared by every session.
+				executor := *h.executor
+				res, err := executor.Execute(r.Context(), schema, &schemabuilder.Subscription{Payload: msg.payload}, query)
 				if err == graphql.ErrNoUpdate {
 					return nil
 				}